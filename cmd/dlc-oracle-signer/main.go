@@ -0,0 +1,115 @@
+// Command dlc-oracle-signer holds an oracle's HD master key
+// and signs requests from the oracle front end over a unix socket.
+//
+//	dlc-oracle-signer -seed <seed file> -socket <socket path> [-signed <file>] [-net regtest]
+//
+// The seed file contains a hex encoded seed.
+// Messages signed at each key path are kept in the signed file
+// (<seed file>.signed by default), so that the signer never signs
+// different messages at the same path even after restarting.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dgarage/dlc/internal/oracle"
+)
+
+func main() {
+	seedPath := flag.String("seed", "", "path to a hex encoded seed file")
+	socketPath := flag.String("socket", "", "path to a unix socket to listen on")
+	signedPath := flag.String("signed", "",
+		"path to a file keeping signed messages (default: <seed>.signed)")
+	network := flag.String("net", "regtest", "network (regtest, testnet3)")
+	flag.Parse()
+
+	if *seedPath == "" || *socketPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *signedPath == "" {
+		*signedPath = *seedPath + ".signed"
+	}
+
+	if err := run(*seedPath, *socketPath, *signedPath, *network); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(seedPath, socketPath, signedPath, network string) error {
+	params, err := netParams(network)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(seedPath)
+	if err != nil {
+		return err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("invalid seed: %v", err)
+	}
+
+	signer, err := oracle.NewHDSignerFromSeed(seed, params)
+	if err != nil {
+		return err
+	}
+	if err := signer.PersistSignedMsgs(signedPath); err != nil {
+		return err
+	}
+	pub, err := signer.Pubkey()
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	// only the owner can connect to the socket
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		l.Close()
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		l.Close()
+	}()
+
+	fmt.Printf("identity pubkey: %x\n", pub.SerializeCompressed())
+	fmt.Printf("listening on %s\n", socketPath)
+	err = oracle.ServeSigner(l, signer)
+	if opErr, ok := err.(*net.OpError); ok && opErr.Op == "accept" {
+		// closed by a signal
+		return nil
+	}
+	return err
+}
+
+func netParams(name string) (chaincfg.Params, error) {
+	for _, params := range []chaincfg.Params{
+		chaincfg.RegressionNetParams,
+		chaincfg.TestNet3Params,
+		chaincfg.SimNetParams,
+	} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+	return chaincfg.Params{}, fmt.Errorf("unsupported network. net: %s", name)
+}
@@ -0,0 +1,72 @@
+// Command dlc-oracle-verify replays an oracle's audit log and verifies
+// its hash chain, record signatures and all attestations.
+//
+//	dlc-oracle-verify -pubkey <oracle identity pubkey hex> <audit log>
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/pkg/oracle"
+)
+
+func main() {
+	pubkeyHex := flag.String("pubkey", "", "oracle's identity pubkey (hex)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -pubkey <hex> <audit log>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *pubkeyHex == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := verify(*pubkeyHex, flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "verification failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func verify(pubkeyHex, path string) error {
+	b, err := hex.DecodeString(pubkeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid pubkey: %v", err)
+	}
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return fmt.Errorf("invalid pubkey: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := oracle.ReadAuditLog(f)
+	if err != nil {
+		return err
+	}
+	if err := oracle.VerifyAuditLog(records, pub); err != nil {
+		return err
+	}
+
+	announcements, attestations := 0, 0
+	for _, r := range records {
+		switch r.Type {
+		case oracle.RecordAnnouncement:
+			announcements++
+		case oracle.RecordAttestation:
+			attestations++
+		}
+	}
+	fmt.Printf("OK: %d records (%d announcements, %d attestations)\n",
+		len(records), announcements, attestations)
+	return nil
+}
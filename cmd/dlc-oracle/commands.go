@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/internal/oracle"
+)
+
+func runInit(datadir string, args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	net := fs.String("net", "regtest", "network (regtest, testnet3, simnet)")
+	_ = fs.Parse(args)
+
+	params, err := netParams(*net)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(datadir, seedFile)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("seed already exists. path: %s", path)
+	}
+	if err := os.MkdirAll(datadir, 0700); err != nil {
+		return err
+	}
+
+	pass, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+	data, err := oracle.NewEncryptedSeed(pass, params)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	o, l, err := openOracleWithPassphrase(datadir, pass)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	if err := saveOracle(datadir, o); err != nil {
+		return err
+	}
+
+	pub, err := o.Pubkey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("identity pubkey: %s\n", hexPubkey(pub))
+	return nil
+}
+
+func runCreateEvent(datadir string, args []string) error {
+	fs := flag.NewFlagSet("create-event", flag.ExitOnError)
+	stream := fs.String("stream", "", "event stream (created if it doesn't exist)")
+	id := fs.String("id", "", "event id (defaults to the maturity)")
+	outcomes := fs.String("outcomes", "", "comma separated outcomes of an enum event")
+	digits := fs.Int("digits", 0, "number of digits of a numeric event")
+	maturity := fs.String("maturity", "", "maturity in UTC ("+oracle.TimeFormat+")")
+	_ = fs.Parse(args)
+
+	e := oracle.Event{ID: oracle.EventID{Stream: *stream, ID: *id}}
+	if *maturity != "" {
+		t, err := time.Parse(oracle.TimeFormat, *maturity)
+		if err != nil {
+			return fmt.Errorf("invalid maturity: %v", err)
+		}
+		e.Maturity = t
+		if e.ID.ID == "" {
+			e.ID = oracle.TimeEventID(*stream, t)
+		}
+	}
+	if e.ID.ID == "" {
+		return fmt.Errorf("specify id or maturity")
+	}
+
+	switch {
+	case *outcomes != "" && *digits != 0:
+		return fmt.Errorf("specify either outcomes or digits")
+	case *outcomes != "":
+		e.Outcomes = strings.Split(*outcomes, ",")
+		e.NRpoints = 1
+	default:
+		e.NRpoints = *digits
+	}
+
+	o, l, err := openOracle(datadir)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	err = updateOracle(datadir, o, l, func() error {
+		if !hasStream(o, *stream) {
+			if err := o.AddStream(*stream); err != nil {
+				return err
+			}
+		}
+		return o.AddEvent(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	return printAnnouncement(o, e.ID, os.Stdout)
+}
+
+func runShowAnnouncement(datadir string, args []string) error {
+	fs := flag.NewFlagSet("show-announcement", flag.ExitOnError)
+	id := eventIDFlags(fs)
+	_ = fs.Parse(args)
+
+	o, l, err := openOracle(datadir)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	return printAnnouncement(o, *id, os.Stdout)
+}
+
+func runAttest(datadir string, args []string) error {
+	fs := flag.NewFlagSet("attest", flag.ExitOnError)
+	id := eventIDFlags(fs)
+	outcome := fs.String("outcome", "", "outcome of the event")
+	cancel := fs.Bool("cancel", false, "attest that the event was cancelled")
+	_ = fs.Parse(args)
+
+	if (*outcome == "") == !*cancel {
+		return fmt.Errorf("specify either outcome or cancel")
+	}
+
+	o, l, err := openOracle(datadir)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	e, err := o.Event(*id)
+	if err != nil {
+		return err
+	}
+	err = updateOracle(datadir, o, l, func() error {
+		if *cancel {
+			return o.CancelEvent(e.ID)
+		}
+		msgs, err := e.EncodeOutcome(*outcome)
+		if err != nil {
+			return err
+		}
+		return o.FixMsgs(e.ID, msgs)
+	})
+	if err != nil {
+		return err
+	}
+
+	return printAttestation(o, e.ID, os.Stdout)
+}
+
+func runListEvents(datadir string, args []string) error {
+	fs := flag.NewFlagSet("list-events", flag.ExitOnError)
+	stream := fs.String("stream", "", "event stream (all streams if empty)")
+	_ = fs.Parse(args)
+
+	o, err := loadOracle(datadir)
+	if err != nil {
+		return err
+	}
+
+	streams := o.Streams()
+	if *stream != "" {
+		streams = []string{*stream}
+	}
+	for _, s := range streams {
+		for _, e := range o.Events(s) {
+			status := "announced"
+			if o.HasFixedMsgs(e.ID) {
+				status = "attested"
+			}
+			maturity := "-"
+			if !e.Maturity.IsZero() {
+				maturity = e.Maturity.UTC().Format(oracle.TimeFormat)
+			}
+			fmt.Printf("%s\t%s\t%d\t%s\n", e.ID, maturity, e.NRpoints, status)
+		}
+	}
+	return nil
+}
+
+func runExportAttestation(datadir string, args []string) error {
+	fs := flag.NewFlagSet("export-attestation", flag.ExitOnError)
+	id := eventIDFlags(fs)
+	out := fs.String("out", "", "output file (standard output if empty)")
+	_ = fs.Parse(args)
+
+	o, l, err := openOracle(datadir)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	if *out == "" {
+		return printAttestation(o, *id, os.Stdout)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return printAttestation(o, *id, f)
+}
+
+func runRotateKey(datadir string, args []string) error {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	socket := fs.String("socket", "", "unix socket of the remote signer holding the new key")
+	_ = fs.Parse(args)
+
+	if *socket == "" {
+		return fmt.Errorf("specify socket")
+	}
+
+	o, l, err := openOracle(datadir)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	var rotation oracle.KeyRotation
+	err = updateOracle(datadir, o, l, func() error {
+		var err error
+		rotation, err = o.RotateKey(oracle.NewRemoteSigner(*socket))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	r := newKeyRotation(rotation)
+	return printJSON(os.Stdout, &r)
+}
+
+// eventIDFlags defines flags of an event id
+func eventIDFlags(fs *flag.FlagSet) *oracle.EventID {
+	id := &oracle.EventID{}
+	fs.StringVar(&id.Stream, "stream", "", "event stream")
+	fs.StringVar(&id.ID, "id", "", "event id")
+	return id
+}
+
+func hasStream(o *oracle.Oracle, stream string) bool {
+	for _, s := range o.Streams() {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// announcement is a JSON representation of an announced event
+type announcement struct {
+	Stream    string        `json:"stream"`
+	ID        string        `json:"id"`
+	Maturity  string        `json:"maturity,omitempty"`
+	Outcomes  []string      `json:"outcomes,omitempty"`
+	Identity  string        `json:"identity"`
+	Epoch     int           `json:"epoch"`
+	Pubkey    string        `json:"pubkey"`
+	Rpoints   []string      `json:"rpoints"`
+	Sig       string        `json:"sig"`
+	Rotations []keyRotation `json:"rotations"` // key rotations up to the epoch
+}
+
+func printAnnouncement(
+	o *oracle.Oracle, id oracle.EventID, w io.Writer) error {
+	e, err := o.Event(id)
+	if err != nil {
+		return err
+	}
+	pubset, err := o.PubkeySet(id)
+	if err != nil {
+		return err
+	}
+	identity, err := o.Pubkey()
+	if err != nil {
+		return err
+	}
+
+	a := &announcement{
+		Stream:    id.Stream,
+		ID:        id.ID,
+		Outcomes:  e.Outcomes,
+		Identity:  hexPubkey(identity),
+		Epoch:     pubset.Epoch,
+		Pubkey:    hexPubkey(pubset.Pubkey),
+		Rpoints:   []string{},
+		Sig:       hex.EncodeToString(pubset.Sig),
+		Rotations: []keyRotation{},
+	}
+	if !e.Maturity.IsZero() {
+		a.Maturity = e.Maturity.UTC().Format(oracle.TimeFormat)
+	}
+	for _, R := range pubset.CommittedRpoints {
+		a.Rpoints = append(a.Rpoints, hexPubkey(R))
+	}
+	for _, r := range pubset.Rotations {
+		a.Rotations = append(a.Rotations, newKeyRotation(r))
+	}
+
+	return printJSON(w, a)
+}
+
+// attestation is a JSON representation of an attested event
+type attestation struct {
+	Stream    string   `json:"stream"`
+	ID        string   `json:"id"`
+	Cancelled bool     `json:"cancelled"`
+	Msgs      []string `json:"msgs"`
+	Signs     []string `json:"signs"`
+}
+
+func printAttestation(
+	o *oracle.Oracle, id oracle.EventID, w io.Writer) error {
+	signSet, err := o.SignSet(id)
+	if err != nil {
+		return err
+	}
+
+	a := &attestation{
+		Stream:    id.Stream,
+		ID:        id.ID,
+		Cancelled: signSet.IsCancelled(),
+		Msgs:      []string{},
+		Signs:     []string{},
+	}
+	for i := range signSet.Msgs {
+		a.Msgs = append(a.Msgs, hex.EncodeToString(signSet.Msgs[i]))
+		a.Signs = append(a.Signs, hex.EncodeToString(signSet.Signs[i]))
+	}
+
+	return printJSON(w, a)
+}
+
+// keyRotation is a JSON representation of a key rotation
+type keyRotation struct {
+	Epoch  int    `json:"epoch"`
+	Pubkey string `json:"pubkey"`
+	Sig    string `json:"sig"`
+}
+
+func newKeyRotation(r oracle.KeyRotation) keyRotation {
+	return keyRotation{
+		Epoch:  r.Epoch,
+		Pubkey: hexPubkey(r.Pubkey),
+		Sig:    hex.EncodeToString(r.Sig),
+	}
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func hexPubkey(pub *btcec.PublicKey) string {
+	return hex.EncodeToString(pub.SerializeCompressed())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dgarage/dlc/internal/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseHexPubkey(t *testing.T, s string) *btcec.PublicKey {
+	b, err := hex.DecodeString(s)
+	assert.NoError(t, err)
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	assert.NoError(t, err)
+	return pub
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	assert.NoError(t, err)
+	return b
+}
+
+// pubkeySet decodes the pubkey set of a printed announcement
+func (a *announcement) pubkeySet(t *testing.T) oracle.PubkeySet {
+	pubset := oracle.PubkeySet{
+		Pubkey: parseHexPubkey(t, a.Pubkey),
+		ID:     oracle.EventID{Stream: a.Stream, ID: a.ID},
+		Epoch:  a.Epoch,
+		Sig:    decodeHex(t, a.Sig),
+	}
+	for _, R := range a.Rpoints {
+		pubset.CommittedRpoints = append(
+			pubset.CommittedRpoints, parseHexPubkey(t, R))
+	}
+	for _, r := range a.Rotations {
+		pubset.Rotations = append(pubset.Rotations, oracle.KeyRotation{
+			Epoch:  r.Epoch,
+			Pubkey: parseHexPubkey(t, r.Pubkey),
+			Sig:    decodeHex(t, r.Sig),
+		})
+	}
+	return pubset
+}
+
+func TestPrintAnnouncementRotated(t *testing.T) {
+	assert := assert.New(t)
+
+	o := oracle.NewTestOracle()
+	seed := bytes.Repeat([]byte{1}, 32)
+	signer, _ := oracle.NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	_, err := o.RotateKey(signer)
+	assert.NoError(err)
+
+	id := oracle.TimeEventID(oracle.TestStream, time.Now())
+	err = o.AddEvent(oracle.Event{ID: id, NRpoints: oracle.TestNRpoints})
+	assert.NoError(err)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(printAnnouncement(o, id, buf))
+	a := &announcement{}
+	assert.NoError(json.Unmarshal(buf.Bytes(), a))
+	assert.Equal(1, a.Epoch)
+	assert.Len(a.Rotations, 1)
+
+	// the announcement is verified from the printed identity
+	identity := parseHexPubkey(t, a.Identity)
+	assert.NoError(a.pubkeySet(t).Verify(identity))
+}
+
+func TestCreateEventEmptyID(t *testing.T) {
+	err := runCreateEvent("", []string{"-stream", "test", "-digits", "1"})
+	assert.Error(t, err)
+}
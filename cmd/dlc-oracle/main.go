@@ -0,0 +1,207 @@
+// Command dlc-oracle runs an oracle from the command line.
+//
+//	dlc-oracle [-datadir <dir>] <command> [flags]
+//
+// Commands:
+//
+//	init                create an encrypted seed
+//	create-event        announce an event with outcomes or digits
+//	show-announcement   show the pubkey set of an event
+//	attest              fix the outcome of an event and sign it
+//	list-events         list events and their status
+//	export-attestation  export the sign set of an attested event
+//	rotate-key          start a new key epoch held by a remote signer
+//
+// The passphrase of the seed is read from DLC_ORACLE_PASSPHRASE,
+// or from the standard input if it isn't set.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/oracle"
+)
+
+// Files in the data directory
+const (
+	seedFile  = "seed.json"
+	dbFile    = "oracle.json"
+	auditFile = "audit.log"
+)
+
+const passphraseEnv = "DLC_ORACLE_PASSPHRASE"
+
+type command struct {
+	name  string
+	usage string
+	run   func(datadir string, args []string) error
+}
+
+var commands = []command{
+	{"init", "create an encrypted seed", runInit},
+	{"create-event", "announce an event with outcomes or digits", runCreateEvent},
+	{"show-announcement", "show the pubkey set of an event", runShowAnnouncement},
+	{"attest", "fix the outcome of an event and sign it", runAttest},
+	{"list-events", "list events and their status", runListEvents},
+	{"export-attestation", "export the sign set of an attested event", runExportAttestation},
+	{"rotate-key", "start a new key epoch held by a remote signer", runRotateKey},
+}
+
+func main() {
+	datadir := flag.String("datadir", defaultDataDir(), "data directory")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(*datadir, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-datadir <dir>] <command> [flags]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	flag.PrintDefaults()
+}
+
+func defaultDataDir() string {
+	return btcutil.AppDataDir("dlc-oracle", false)
+}
+
+// readPassphrase reads the passphrase of the seed
+func readPassphrase() ([]byte, error) {
+	if pass := os.Getenv(passphraseEnv); pass != "" {
+		return []byte(pass), nil
+	}
+
+	fmt.Fprint(os.Stderr, "passphrase: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("failed to read passphrase: %v", err)
+	}
+	pass := strings.TrimRight(line, "\r\n")
+	if pass == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	return []byte(pass), nil
+}
+
+// openOracle decrypts the seed and loads the oracle's DB and audit log
+func openOracle(datadir string) (*oracle.Oracle, *oracle.AuditLog, error) {
+	pass, err := readPassphrase()
+	if err != nil {
+		return nil, nil, err
+	}
+	return openOracleWithPassphrase(datadir, pass)
+}
+
+func openOracleWithPassphrase(
+	datadir string, pass []byte) (*oracle.Oracle, *oracle.AuditLog, error) {
+	data, err := ioutil.ReadFile(filepath.Join(datadir, seedFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read seed. run init first: %v", err)
+	}
+	seed, net, err := oracle.DecryptSeed(data, pass)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, err := netParams(net)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, err := oracle.NewHDSignerFromSeed(seed, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o := oracle.NewWithSigner(filepath.Base(datadir), signer)
+	if err := o.LoadDB(filepath.Join(datadir, dbFile)); err != nil {
+		return nil, nil, err
+	}
+
+	l, err := oracle.OpenAuditLog(filepath.Join(datadir, auditFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	o.SetAuditLog(l)
+
+	return o, l, nil
+}
+
+// loadOracle loads the oracle's DB without decrypting the seed
+func loadOracle(datadir string) (*oracle.Oracle, error) {
+	o := oracle.NewWithSigner(filepath.Base(datadir), nil)
+	if err := o.LoadDB(filepath.Join(datadir, dbFile)); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// saveOracle saves the oracle's DB
+func saveOracle(datadir string, o *oracle.Oracle) error {
+	return o.SaveDB(filepath.Join(datadir, dbFile))
+}
+
+// updateOracle applies a change to the oracle and saves its DB.
+// Audit records of the change are rolled back if it fails or the DB isn't saved,
+// so that the log never records what the DB doesn't have.
+func updateOracle(datadir string,
+	o *oracle.Oracle, l *oracle.AuditLog, change func() error) error {
+	mark, err := l.Mark()
+	if err != nil {
+		return err
+	}
+
+	err = change()
+	if err == nil {
+		err = saveOracle(datadir, o)
+	}
+	if err != nil {
+		if rerr := l.Rollback(mark); rerr != nil {
+			return fmt.Errorf("%v (%v)", err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func netParams(name string) (chaincfg.Params, error) {
+	for _, params := range []chaincfg.Params{
+		chaincfg.RegressionNetParams,
+		chaincfg.TestNet3Params,
+		chaincfg.SimNetParams,
+	} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+	return chaincfg.Params{}, fmt.Errorf("unsupported network. net: %s", name)
+}
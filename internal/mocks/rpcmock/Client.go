@@ -59,6 +59,29 @@ func (_m *Client) GetBlockCount() (int64, error) {
 	return r0, r1
 }
 
+// GetTxOut provides a mock function with given fields: txHash, index, mempool
+func (_m *Client) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error) {
+	ret := _m.Called(txHash, index, mempool)
+
+	var r0 *btcjson.GetTxOutResult
+	if rf, ok := ret.Get(0).(func(*chainhash.Hash, uint32, bool) *btcjson.GetTxOutResult); ok {
+		r0 = rf(txHash, index, mempool)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*btcjson.GetTxOutResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*chainhash.Hash, uint32, bool) error); ok {
+		r1 = rf(txHash, index, mempool)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ImportAddress provides a mock function with given fields: address
 func (_m *Client) ImportAddress(address string) error {
 	ret := _m.Called(address)
@@ -8,6 +8,7 @@ import btcutil "github.com/btcsuite/btcutil"
 import chainhash "github.com/btcsuite/btcd/chaincfg/chainhash"
 import mock "github.com/stretchr/testify/mock"
 import rpc "github.com/dgarage/dlc/internal/rpc"
+import time "time"
 import wallet "github.com/dgarage/dlc/pkg/wallet"
 import wire "github.com/btcsuite/btcd/wire"
 
@@ -30,6 +31,29 @@ func (_m *Wallet) Close() error {
 	return r0
 }
 
+// GetTxOut provides a mock function with given fields: op
+func (_m *Wallet) GetTxOut(op wire.OutPoint) (*btcjson.GetTxOutResult, error) {
+	ret := _m.Called(op)
+
+	var r0 *btcjson.GetTxOutResult
+	if rf, ok := ret.Get(0).(func(wire.OutPoint) *btcjson.GetTxOutResult); ok {
+		r0 = rf(op)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*btcjson.GetTxOutResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(wire.OutPoint) error); ok {
+		r1 = rf(op)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListUnspent provides a mock function with given fields:
 func (_m *Wallet) ListUnspent() ([]btcjson.ListUnspentResult, error) {
 	ret := _m.Called()
@@ -53,6 +77,20 @@ func (_m *Wallet) ListUnspent() ([]btcjson.ListUnspentResult, error) {
 	return r0, r1
 }
 
+// LockUnspent provides a mock function with given fields: ops, expiry
+func (_m *Wallet) LockUnspent(ops []wire.OutPoint, expiry time.Time) error {
+	ret := _m.Called(ops, expiry)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]wire.OutPoint, time.Time) error); ok {
+		r0 = rf(ops, expiry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewAddress provides a mock function with given fields:
 func (_m *Wallet) NewAddress() (btcutil.Address, error) {
 	ret := _m.Called()
@@ -76,6 +114,52 @@ func (_m *Wallet) NewAddress() (btcutil.Address, error) {
 	return r0, r1
 }
 
+// NewChangeAddress provides a mock function with given fields:
+func (_m *Wallet) NewChangeAddress() (btcutil.Address, error) {
+	ret := _m.Called()
+
+	var r0 btcutil.Address
+	if rf, ok := ret.Get(0).(func() btcutil.Address); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(btcutil.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewChangePubkey provides a mock function with given fields:
+func (_m *Wallet) NewChangePubkey() (*btcec.PublicKey, error) {
+	ret := _m.Called()
+
+	var r0 *btcec.PublicKey
+	if rf, ok := ret.Get(0).(func() *btcec.PublicKey); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*btcec.PublicKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewPubkey provides a mock function with given fields:
 func (_m *Wallet) NewPubkey() (*btcec.PublicKey, error) {
 	ret := _m.Called()
@@ -99,13 +183,13 @@ func (_m *Wallet) NewPubkey() (*btcec.PublicKey, error) {
 	return r0, r1
 }
 
-// SelectUnspent provides a mock function with given fields: amt, feePerTxIn, feePerTxOut
-func (_m *Wallet) SelectUnspent(amt btcutil.Amount, feePerTxIn btcutil.Amount, feePerTxOut btcutil.Amount) ([]btcjson.ListUnspentResult, btcutil.Amount, error) {
-	ret := _m.Called(amt, feePerTxIn, feePerTxOut)
+// SelectUnspent provides a mock function with given fields: selector, amt, feePerTxIn, feePerTxOut
+func (_m *Wallet) SelectUnspent(selector wallet.CoinSelector, amt btcutil.Amount, feePerTxIn btcutil.Amount, feePerTxOut btcutil.Amount) ([]btcjson.ListUnspentResult, btcutil.Amount, error) {
+	ret := _m.Called(selector, amt, feePerTxIn, feePerTxOut)
 
 	var r0 []btcjson.ListUnspentResult
-	if rf, ok := ret.Get(0).(func(btcutil.Amount, btcutil.Amount, btcutil.Amount) []btcjson.ListUnspentResult); ok {
-		r0 = rf(amt, feePerTxIn, feePerTxOut)
+	if rf, ok := ret.Get(0).(func(wallet.CoinSelector, btcutil.Amount, btcutil.Amount, btcutil.Amount) []btcjson.ListUnspentResult); ok {
+		r0 = rf(selector, amt, feePerTxIn, feePerTxOut)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]btcjson.ListUnspentResult)
@@ -113,15 +197,15 @@ func (_m *Wallet) SelectUnspent(amt btcutil.Amount, feePerTxIn btcutil.Amount, f
 	}
 
 	var r1 btcutil.Amount
-	if rf, ok := ret.Get(1).(func(btcutil.Amount, btcutil.Amount, btcutil.Amount) btcutil.Amount); ok {
-		r1 = rf(amt, feePerTxIn, feePerTxOut)
+	if rf, ok := ret.Get(1).(func(wallet.CoinSelector, btcutil.Amount, btcutil.Amount, btcutil.Amount) btcutil.Amount); ok {
+		r1 = rf(selector, amt, feePerTxIn, feePerTxOut)
 	} else {
 		r1 = ret.Get(1).(btcutil.Amount)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(btcutil.Amount, btcutil.Amount, btcutil.Amount) error); ok {
-		r2 = rf(amt, feePerTxIn, feePerTxOut)
+	if rf, ok := ret.Get(2).(func(wallet.CoinSelector, btcutil.Amount, btcutil.Amount, btcutil.Amount) error); ok {
+		r2 = rf(selector, amt, feePerTxIn, feePerTxOut)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -171,6 +255,20 @@ func (_m *Wallet) Unlock(privPass []byte) error {
 	return r0
 }
 
+// UnlockUnspent provides a mock function with given fields: ops
+func (_m *Wallet) UnlockUnspent(ops []wire.OutPoint) error {
+	ret := _m.Called(ops)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]wire.OutPoint) error); ok {
+		r0 = rf(ops)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WitnessSignTxByIdxs provides a mock function with given fields: tx, idxs
 func (_m *Wallet) WitnessSignTxByIdxs(tx *wire.MsgTx, idxs []int) ([]wire.TxWitness, error) {
 	ret := _m.Called(tx, idxs)
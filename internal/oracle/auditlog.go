@@ -0,0 +1,135 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+)
+
+// AuditRecord is an alias of oracle.AuditRecord
+type AuditRecord = oracle.AuditRecord
+
+// AuditLog is an append-only log of oracle's announcements and attestations
+type AuditLog struct {
+	w    io.Writer
+	last *AuditRecord // last record to chain a next record to
+}
+
+// NewAuditLog creates an empty audit log writing records to w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// OpenAuditLog opens an audit log file to append records,
+// continuing the hash chain from its last record
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := oracle.ReadAuditLog(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &AuditLog{w: f}
+	if len(records) > 0 {
+		l.last = records[len(records)-1]
+	}
+	return l, nil
+}
+
+// Close closes the underlying writer if it's closable
+func (l *AuditLog) Close() error {
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// AuditMark is a position of an audit log to roll back to
+type AuditMark struct {
+	size int64
+	last *AuditRecord
+}
+
+// Mark returns the current end of the log.
+// Only a log of a file opened by OpenAuditLog can be marked.
+func (l *AuditLog) Mark() (AuditMark, error) {
+	f, ok := l.w.(*os.File)
+	if !ok {
+		return AuditMark{}, fmt.Errorf("audit log isn't a file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return AuditMark{}, err
+	}
+	return AuditMark{size: info.Size(), last: l.last}, nil
+}
+
+// Rollback removes records appended after a mark,
+// e.g. when the DB recording the same changes fails to be saved
+func (l *AuditLog) Rollback(m AuditMark) error {
+	f, ok := l.w.(*os.File)
+	if !ok {
+		return fmt.Errorf("audit log isn't a file")
+	}
+	if err := f.Truncate(m.size); err != nil {
+		return fmt.Errorf("failed to roll back audit log: %v", err)
+	}
+	l.last = m.last
+	return nil
+}
+
+// append chains a record to the last one, signs and writes it.
+// The signer rebuilds the record from the keys at a key path by itself.
+func (l *AuditLog) append(r *AuditRecord, path KeyPath, s Signer) error {
+	r.Time = time.Now().UTC()
+	sign := func([]byte) ([]byte, error) { return s.SignRecord(path, r) }
+	if err := r.Chain(l.last, sign); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %v", err)
+	}
+
+	l.last = r
+	return nil
+}
+
+// SetAuditLog sets a log to record all announcements and attestations
+func (o *Oracle) SetAuditLog(l *AuditLog) {
+	o.auditLog = l
+}
+
+// audit appends a record to the audit log if it's set
+func (o *Oracle) audit(r *AuditRecord) error {
+	if o.auditLog == nil {
+		return nil
+	}
+
+	// a record is signed by the key of its epoch,
+	// and a rotation record by the key it rotates from
+	epoch, path := r.Epoch, KeyPath{}
+	if r.Type == oracle.RecordRotation {
+		epoch--
+	} else {
+		path = o.keyPathForEvent(r.ID())
+	}
+	signer, err := o.signerAt(epoch)
+	if err != nil {
+		return err
+	}
+	return o.auditLog.append(r, path, signer)
+}
@@ -0,0 +1,108 @@
+package oracle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "oracle")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	o := NewTestOracle()
+	l, err := OpenAuditLog(path)
+	assert.NoError(err)
+	o.SetAuditLog(l)
+
+	ftime := time.Now()
+	id := addTestEvent(o, ftime)
+	assert.NoError(o.FixMsgs(id, randomMsgs(TestNRpoints)))
+	assert.NoError(l.Close())
+
+	// continue the chain after reopening
+	l, err = OpenAuditLog(path)
+	assert.NoError(err)
+	o.SetAuditLog(l)
+	_ = addTestEvent(o, ftime.Add(1*time.Hour))
+	assert.NoError(l.Close())
+
+	f, err := os.Open(path)
+	assert.NoError(err)
+	defer f.Close()
+	records, err := oracle.ReadAuditLog(f)
+	assert.NoError(err)
+	assert.Len(records, 3)
+
+	pub, err := o.Pubkey()
+	assert.NoError(err)
+	assert.NoError(oracle.VerifyAuditLog(records, pub))
+
+	// recorded announcement matches the pubkey set
+	pubset, err := o.PubkeySet(id)
+	assert.NoError(err)
+	recorded, err := records[0].PubkeySet()
+	assert.NoError(err)
+	assert.Equal(pubset.Pubkey, recorded.Pubkey)
+	assert.Equal(pubset.CommittedRpoints, recorded.CommittedRpoints)
+	assert.Equal(pubset.ID, recorded.ID)
+
+	// recorded attestation matches the sign set
+	signSet, err := o.SignSet(id)
+	assert.NoError(err)
+	attested, err := records[1].SignSet()
+	assert.NoError(err)
+	assert.Equal(signSet, attested)
+}
+
+func TestAuditLogRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "oracle")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	o := NewTestOracle()
+	l, err := OpenAuditLog(path)
+	assert.NoError(err)
+	o.SetAuditLog(l)
+
+	ftime := time.Now()
+	_ = addTestEvent(o, ftime)
+	mark, err := l.Mark()
+	assert.NoError(err)
+
+	// roll back the announcement of an event that isn't saved
+	_ = addTestEvent(o, ftime.Add(1*time.Hour))
+	assert.NoError(l.Rollback(mark))
+
+	// the chain continues from the mark
+	id := addTestEvent(o, ftime.Add(2*time.Hour))
+	assert.NoError(l.Close())
+
+	f, err := os.Open(path)
+	assert.NoError(err)
+	defer f.Close()
+	records, err := oracle.ReadAuditLog(f)
+	assert.NoError(err)
+	assert.Len(records, 2)
+	assert.Equal(id, records[1].ID())
+
+	pub, err := o.Pubkey()
+	assert.NoError(err)
+	assert.NoError(oracle.VerifyAuditLog(records, pub))
+
+	// a log not in a file can't be rolled back
+	_, err = NewAuditLog(ioutil.Discard).Mark()
+	assert.Error(err)
+}
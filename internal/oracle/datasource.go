@@ -0,0 +1,94 @@
+package oracle
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// DataSource provides outcomes of oracle's events
+type DataSource interface {
+	// Outcome fetches the outcome of a given event.
+	// It returns ErrEventCancelled if the event didn't occur.
+	Outcome(e Event) (string, error)
+}
+
+// ErrEventCancelled is returned by data sources for cancelled events
+var ErrEventCancelled = errors.New("event cancelled")
+
+// CancelledOutcome is an outcome in a CSV file for cancelled events.
+// It's reserved and no event can have an outcome of the same name.
+const CancelledOutcome = "cancelled"
+
+// CSVSource is a data source that reads outcomes from a CSV file.
+//
+// Each record has 3 fields: stream, event id and outcome.
+// An outcome "cancelled" marks a cancelled event.
+//
+//	btcusd,2018-11-11 12:00:00,6410
+//	weather/tokyo,2018-11-11 12:00:00,fine
+//
+// The file is read every time an outcome is requested,
+// so that outcomes can be appended while the oracle is running.
+type CSVSource struct {
+	path string
+}
+
+// NewCSVSource creates a data source reading a given CSV file
+func NewCSVSource(path string) *CSVSource {
+	return &CSVSource{path: path}
+}
+
+// Outcome is an implementation of DataSource.Outcome
+func (s *CSVSource) Outcome(e Event) (string, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if record[0] == e.ID.Stream && record[1] == e.ID.ID {
+			outcome := strings.TrimSpace(record[2])
+			if outcome == CancelledOutcome {
+				return "", ErrEventCancelled
+			}
+			return outcome, nil
+		}
+	}
+
+	return "", fmt.Errorf("outcome not found. id: %s", e.ID)
+}
+
+// FakeSource is a deterministic data source for testing.
+// It derives an outcome from a hash of the event id.
+type FakeSource struct{}
+
+// Outcome is an implementation of DataSource.Outcome
+func (s *FakeSource) Outcome(e Event) (string, error) {
+	h := sha256.Sum256([]byte(e.ID.String()))
+	n := new(big.Int).SetBytes(h[:])
+
+	if e.IsEnum() {
+		i := new(big.Int).Mod(n, big.NewInt(int64(len(e.Outcomes))))
+		return e.Outcomes[i.Int64()], nil
+	}
+
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(e.NRpoints)), nil)
+	return new(big.Int).Mod(n, max).String(), nil
+}
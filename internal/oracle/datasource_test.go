@@ -0,0 +1,56 @@
+package oracle
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVSource(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := ioutil.TempFile("", "outcomes")
+	assert.NoError(err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(
+		"btcusd,2018-11-11 12:00:00,6410\n" +
+			"weather/tokyo, 2018-11-11 12:00:00, fine\n" +
+			"btcusd,2018-11-12 12:00:00,cancelled\n")
+	assert.NoError(err)
+	f.Close()
+
+	src := NewCSVSource(f.Name())
+
+	outcome, err := src.Outcome(
+		Event{ID: EventID{Stream: "weather/tokyo", ID: "2018-11-11 12:00:00"}})
+	assert.NoError(err)
+	assert.Equal("fine", outcome)
+
+	_, err = src.Outcome(
+		Event{ID: EventID{Stream: "btcusd", ID: "2018-11-12 12:00:00"}})
+	assert.Equal(ErrEventCancelled, err)
+
+	_, err = src.Outcome(
+		Event{ID: EventID{Stream: "btcusd", ID: "2018-11-13 12:00:00"}})
+	assert.Error(err)
+}
+
+func TestFakeSource(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &FakeSource{}
+	numeric := Event{ID: EventID{Stream: TestStream, ID: "1"}, NRpoints: 2}
+	enum := Event{ID: numeric.ID, NRpoints: 1, Outcomes: []string{"a", "b"}}
+
+	for _, e := range []Event{numeric, enum} {
+		outcome1, err := src.Outcome(e)
+		assert.NoError(err)
+		outcome2, _ := src.Outcome(e)
+		assert.Equal(outcome1, outcome2)
+
+		_, err = e.EncodeOutcome(outcome1)
+		assert.NoError(err)
+	}
+}
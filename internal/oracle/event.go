@@ -0,0 +1,157 @@
+package oracle
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+)
+
+// Event is a descriptor of an event announced by the oracle
+type Event struct {
+	ID       EventID
+	NRpoints int       // number of committed R-points
+	Epoch    int       // key epoch of the event (set by the oracle)
+	Maturity time.Time // time when the outcome is fixed (optional)
+	Outcomes []string  // possible outcomes of an enum event (optional)
+}
+
+// IsEnum checks if the event has enumerated outcomes.
+// Otherwise the outcome is a decimal number with a digit per R-point.
+func (e Event) IsEnum() bool {
+	return len(e.Outcomes) > 0
+}
+
+// OutcomeMsgs encodes outcome strings into canonical messages
+func OutcomeMsgs(outcomes ...string) [][]byte {
+	return oracle.OutcomeMsgs(outcomes...)
+}
+
+// EncodeOutcome encodes an outcome into canonical messages to fix
+//
+// An enum outcome is encoded into a single message.
+// A numeric outcome is encoded into a message per digit,
+// from the most significant digit, padded with zeros.
+// e.g. "123" -> OutcomeMsgs("0", "1", "2", "3") for 4 R-points
+func (e Event) EncodeOutcome(outcome string) ([][]byte, error) {
+	if e.IsEnum() {
+		for _, o := range e.Outcomes {
+			if o == outcome {
+				return oracle.OutcomeMsgs(outcome), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown outcome. outcome: %s", outcome)
+	}
+
+	n, ok := new(big.Int).SetString(outcome, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid numeric outcome. outcome: %s", outcome)
+	}
+	digits := n.String()
+	if len(digits) > e.NRpoints {
+		return nil, fmt.Errorf(
+			"outcome has too many digits. max %d, but got %s", e.NRpoints, outcome)
+	}
+
+	padded := strings.Repeat("0", e.NRpoints-len(digits)) + digits
+	return oracle.OutcomeMsgs(strings.Split(padded, "")...), nil
+}
+
+// AddEvent adds an event to one of the oracle's streams
+func (o *Oracle) AddEvent(e Event) error {
+	return o.addEvent(e, nil)
+}
+
+// addEvent adds an event, whose keys are derived along a legacy HD path if given,
+// and records its announcement
+func (o *Oracle) addEvent(e Event, legacyPath []int) error {
+	if err := o.checkStream(e.ID); err != nil {
+		return err
+	}
+
+	if e.NRpoints <= 0 {
+		return fmt.Errorf("invalid number of R-points. got %d", e.NRpoints)
+	}
+	if e.IsEnum() && e.NRpoints != 1 {
+		return fmt.Errorf(
+			"enum event must have 1 R-point, but got %d", e.NRpoints)
+	}
+	for _, outcome := range e.Outcomes {
+		if !utf8.ValidString(outcome) {
+			return fmt.Errorf("outcome must be UTF-8. outcome: %q", outcome)
+		}
+		if outcome == CancelledOutcome {
+			return fmt.Errorf(
+				"outcome is reserved for cancelled events. outcome: %q", outcome)
+		}
+	}
+
+	key := e.ID.String()
+	if _, ok := o.db.events[key]; ok {
+		return fmt.Errorf("event already exists. id: %s", key)
+	}
+
+	// events are announced with the current key,
+	// except legacy events announced before key rotation
+	e.Epoch = o.Epoch()
+	if legacyPath != nil {
+		e.Epoch = 0
+		o.db.legacy[key] = legacyPath
+	}
+	err := o.announce(e)
+	if err != nil {
+		delete(o.db.legacy, key)
+		return err
+	}
+	o.db.events[key] = &e
+
+	return nil
+}
+
+// announce records the announcement of an event
+func (o *Oracle) announce(e Event) error {
+	if o.auditLog == nil {
+		return nil
+	}
+
+	pubset, err := o.pubkeySet(e)
+	if err != nil {
+		return err
+	}
+	return o.audit(oracle.NewAnnouncementRecord(e.ID, pubset))
+}
+
+// Event returns a descriptor of a given event
+func (o *Oracle) Event(id EventID) (Event, error) {
+	if err := o.checkStream(id); err != nil {
+		return Event{}, err
+	}
+
+	e, ok := o.db.events[id.String()]
+	if !ok {
+		return Event{}, fmt.Errorf("event not found. id: %s", id)
+	}
+	return *e, nil
+}
+
+// Events returns descriptors of all events in a stream
+func (o *Oracle) Events(stream string) []Event {
+	events := []Event{}
+	if !o.dbReady() {
+		return events
+	}
+
+	for _, e := range o.db.events {
+		if e.ID.Stream == stream {
+			events = append(events, *e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID.ID < events[j].ID.ID
+	})
+	return events
+}
@@ -0,0 +1,81 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	e := Event{ID: EventID{Stream: TestStream, ID: "1"}, NRpoints: 2}
+
+	err := o.AddEvent(e)
+	assert.NoError(err)
+
+	got, err := o.Event(e.ID)
+	assert.NoError(err)
+	assert.Equal(e, got)
+
+	// fail with duplicated event
+	assert.Error(o.AddEvent(e))
+
+	// fail with invalid descriptors
+	assert.Error(o.AddEvent(Event{ID: EventID{Stream: TestStream, ID: "2"}}))
+	assert.Error(o.AddEvent(Event{ID: EventID{Stream: "unknown", ID: "1"}, NRpoints: 1}))
+	assert.Error(o.AddEvent(Event{
+		ID: EventID{Stream: TestStream, ID: "3"}, NRpoints: 1,
+		Outcomes: []string{"\xff"}}))
+
+	// fail with an outcome reserved for cancelled events
+	assert.Error(o.AddEvent(Event{
+		ID: EventID{Stream: TestStream, ID: "4"}, NRpoints: 1,
+		Outcomes: []string{"a", CancelledOutcome}}))
+}
+
+func TestEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	_ = o.AddStream("other")
+	ids := []EventID{
+		{Stream: TestStream, ID: "b"},
+		{Stream: TestStream, ID: "a"},
+		{Stream: "other", ID: "c"},
+	}
+	for _, id := range ids {
+		_ = o.AddEvent(Event{ID: id, NRpoints: 1})
+	}
+
+	events := o.Events(TestStream)
+	assert.Len(events, 2)
+	assert.Equal(ids[1], events[0].ID)
+	assert.Equal(ids[0], events[1].ID)
+}
+
+func TestEncodeOutcome(t *testing.T) {
+	assert := assert.New(t)
+
+	numeric := Event{NRpoints: 4}
+	msgs, err := numeric.EncodeOutcome("123")
+	assert.NoError(err)
+	assert.Equal(oracle.OutcomeMsgs("0", "1", "2", "3"), msgs)
+
+	_, err = numeric.EncodeOutcome("12345")
+	assert.Error(err)
+	_, err = numeric.EncodeOutcome("-1")
+	assert.Error(err)
+	_, err = numeric.EncodeOutcome("fine")
+	assert.Error(err)
+
+	enum := Event{NRpoints: 1, Outcomes: []string{"fine", "rain"}}
+	msgs, err = enum.EncodeOutcome("rain")
+	assert.NoError(err)
+	assert.Equal(oracle.OutcomeMsgs("rain"), msgs)
+
+	_, err = enum.EncodeOutcome("snow")
+	assert.Error(err)
+}
@@ -0,0 +1,158 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// dbFile is a JSON representation of oracle's DB
+type dbFile struct {
+	Streams []string            `json:"streams"`
+	Events  []Event             `json:"events"`
+	Msgs    map[string][][]byte `json:"msgs"`
+	Legacy  map[string][]int    `json:"legacy,omitempty"`
+
+	Rotations []rotationFile `json:"rotations,omitempty"`
+}
+
+// rotationFile is a JSON representation of a key rotation
+// and the remote signer holding the key of its epoch
+type rotationFile struct {
+	Epoch  int    `json:"epoch"`
+	Pubkey []byte `json:"pubkey"` // compressed
+	Sig    []byte `json:"sig"`
+	Socket string `json:"socket"` // unix socket of the remote signer
+}
+
+// LoadDB loads oracle's DB from a file saved by SaveDB.
+// It initializes an empty DB if the file doesn't exist.
+//
+// Key rotations are restored with remote signers of the rotated epochs,
+// so the oracle continues from the epoch it was saved at.
+func (o *Oracle) LoadDB(path string) error {
+	o.InitDB()
+	o.signers = o.signers[:1]
+	o.rotations = nil
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f := &dbFile{}
+	if err := json.Unmarshal(b, f); err != nil {
+		return err
+	}
+
+	for _, name := range f.Streams {
+		o.db.streams[name] = true
+	}
+	for i := range f.Events {
+		e := f.Events[i]
+		o.db.events[e.ID.String()] = &e
+	}
+	for key, msgs := range f.Msgs {
+		o.db.msgs[key] = msgs
+	}
+	for key, hdpath := range f.Legacy {
+		o.db.legacy[key] = hdpath
+	}
+
+	for i, r := range f.Rotations {
+		if r.Epoch != i+1 {
+			return fmt.Errorf("invalid key rotation. epoch: %d", r.Epoch)
+		}
+		pub, err := btcec.ParsePubKey(r.Pubkey, btcec.S256())
+		if err != nil {
+			return err
+		}
+		o.signers = append(o.signers, NewRemoteSigner(r.Socket))
+		o.rotations = append(o.rotations,
+			KeyRotation{Epoch: r.Epoch, Pubkey: pub, Sig: r.Sig})
+	}
+
+	return nil
+}
+
+// SaveDB saves oracle's DB to a file.
+// The file is replaced atomically, so a failure never corrupts the saved DB.
+//
+// Signers of rotated epochs must be remote signers,
+// since the file holds only their sockets and no private keys.
+func (o *Oracle) SaveDB(path string) error {
+	if !o.dbReady() {
+		return fmt.Errorf("DB isn't ready")
+	}
+
+	rotations, err := o.rotationFiles()
+	if err != nil {
+		return err
+	}
+
+	f := &dbFile{
+		Streams: o.Streams(),
+		Events:  []Event{},
+		Msgs:    o.db.msgs,
+		Legacy:  o.db.legacy,
+
+		Rotations: rotations,
+	}
+	for _, stream := range f.Streams {
+		f.Events = append(f.Events, o.Events(stream)...)
+	}
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// rotationFiles returns key rotations with sockets of their signers
+func (o *Oracle) rotationFiles() ([]rotationFile, error) {
+	var files []rotationFile
+	for _, r := range o.rotations {
+		s, ok := o.signers[r.Epoch].(*RemoteSigner)
+		if !ok {
+			return nil, fmt.Errorf(
+				"signer of epoch %d isn't a remote signer", r.Epoch)
+		}
+		files = append(files, rotationFile{
+			Epoch:  r.Epoch,
+			Pubkey: r.Pubkey.SerializeCompressed(),
+			Sig:    r.Sig,
+			Socket: s.address,
+		})
+	}
+	return files, nil
+}
+
+// writeFileAtomic replaces a file with data atomically
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
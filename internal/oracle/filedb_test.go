@@ -0,0 +1,107 @@
+package oracle
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveDB(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "oracle")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "oracle.json")
+
+	o := NewTestOracle()
+	ftime := time.Now()
+	id1 := addTestEvent(o, ftime)
+	id2 := addTestEvent(o, ftime.Add(1*time.Hour))
+	enum := Event{
+		ID:       EventID{Stream: TestStream, ID: "enum"},
+		NRpoints: 1,
+		Maturity: ftime.UTC().Truncate(time.Second),
+		Outcomes: []string{"a", "b"},
+	}
+	_ = o.AddEvent(enum)
+	_ = o.FixMsgs(id1, randomMsgs(TestNRpoints))
+	_ = o.MigrateLegacyEvents(TestStream, TestNRpoints, ftime.Add(-1*time.Hour))
+	assert.NoError(o.SaveDB(path))
+
+	// load the DB into the oracle with the same key
+	loaded := NewWithSigner("loaded", o.signers[0])
+	assert.NoError(loaded.LoadDB(path))
+
+	assert.Equal(o.Streams(), loaded.Streams())
+	assert.Equal(len(o.Events(TestStream)), len(loaded.Events(TestStream)))
+	e, err := loaded.Event(enum.ID)
+	assert.NoError(err)
+	assert.Equal(enum, e)
+
+	assert.True(loaded.HasFixedMsgs(id1))
+	assert.False(loaded.HasFixedMsgs(id2))
+	signSet, _ := o.SignSet(id1)
+	loadedSignSet, err := loaded.SignSet(id1)
+	assert.NoError(err)
+	assert.Equal(signSet, loadedSignSet)
+
+	for _, e := range o.Events(TestStream) {
+		pubset, _ := o.PubkeySet(e.ID)
+		loadedPubset, err := loaded.PubkeySet(e.ID)
+		assert.NoError(err)
+		assert.Equal(pubset, loadedPubset)
+	}
+}
+
+func TestSaveDBRotations(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "oracle")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "oracle.json")
+	socketPath := filepath.Join(dir, "signer.sock")
+
+	l, err := net.Listen("unix", socketPath)
+	assert.NoError(err)
+	defer l.Close()
+	go func() { _ = ServeSigner(l, newTestSigner(1)) }()
+
+	o := NewTestOracle()
+	rotation, err := o.RotateKey(NewRemoteSigner(socketPath))
+	assert.NoError(err)
+	id := addTestEvent(o, time.Now())
+	assert.NoError(o.SaveDB(path))
+
+	// the oracle continues from the rotated epoch after loading
+	loaded := NewWithSigner("loaded", o.signers[0])
+	assert.NoError(loaded.LoadDB(path))
+	assert.Equal(1, loaded.Epoch())
+	assert.Equal([]KeyRotation{rotation}, loaded.Rotations())
+
+	pubset, _ := o.PubkeySet(id)
+	loadedPubset, err := loaded.PubkeySet(id)
+	assert.NoError(err)
+	assert.Equal(pubset, loadedPubset)
+
+	// signers of rotated epochs must be remote to be saved
+	o = NewTestOracle()
+	_, err = o.RotateKey(newTestSigner(2))
+	assert.NoError(err)
+	assert.Error(o.SaveDB(path))
+}
+
+func TestLoadDBNotExist(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	err := o.LoadDB(filepath.Join(os.TempDir(), "not-exist", "oracle.json"))
+	assert.NoError(err)
+	assert.Empty(o.Streams())
+}
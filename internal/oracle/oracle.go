@@ -3,6 +3,7 @@ package oracle
 import (
 	"fmt"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcutil/hdkeychain"
@@ -10,14 +11,15 @@ import (
 
 // Oracle is a struct
 type Oracle struct {
-	name      string                  // display name
-	nRpoints  int                     // number of committed R-points
-	masterKey *hdkeychain.ExtendedKey // master HD extended key (private)
-	db        *memdb                  // memory db for testing
+	name      string        // display name
+	signers   []Signer      // signers holding private keys of each key epoch
+	rotations []KeyRotation // key rotations to the later epochs
+	db        *memdb        // memory db for testing
+	auditLog  *AuditLog     // log of announcements and attestations
 }
 
 // New creates a oracle
-func New(name string, params chaincfg.Params, nRpoints int) (*Oracle, error) {
+func New(name string, params chaincfg.Params) (*Oracle, error) {
 	if isMainNet(params) {
 		return nil, fmt.Errorf("mainnet isn't supported yet")
 	}
@@ -27,8 +29,18 @@ func New(name string, params chaincfg.Params, nRpoints int) (*Oracle, error) {
 		return nil, err
 	}
 
-	oracle := &Oracle{name: name, nRpoints: nRpoints, masterKey: mKey}
-	return oracle, nil
+	return NewWithSigner(name, NewHDSigner(mKey)), nil
+}
+
+// NewWithSigner creates a oracle using a given signer
+func NewWithSigner(name string, signer Signer) *Oracle {
+	return &Oracle{name: name, signers: []Signer{signer}}
+}
+
+// Pubkey returns the oracle's identity pubkey, the long-term pubkey
+// of the first key epoch, that contractors and auditors trust
+func (o *Oracle) Pubkey() (*btcec.PublicKey, error) {
+	return o.signers[0].Pubkey()
 }
 
 func isMainNet(params chaincfg.Params) bool {
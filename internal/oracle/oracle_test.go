@@ -12,8 +12,7 @@ func TestNew(t *testing.T) {
 
 	name := "test"
 	params := chaincfg.RegressionNetParams
-	nRpoints := 1
 
-	_, err := New(name, params, nRpoints)
+	_, err := New(name, params)
 	assert.Nil(err)
 }
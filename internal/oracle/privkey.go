@@ -1,7 +1,9 @@
 package oracle
 
 import (
-	"time"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/hdkeychain"
@@ -9,11 +11,8 @@ import (
 
 // Extended key wrapper
 type privExtKey struct {
-	key *hdkeychain.ExtendedKey
-}
-
-func (oracle *Oracle) baseKey() privExtKey {
-	return privExtKey{oracle.masterKey}
+	key    *hdkeychain.ExtendedKey
+	legacy bool // derives non-hardened children (deprecated)
 }
 
 func (key *privExtKey) ECPubKey() (*btcec.PublicKey, error) {
@@ -24,25 +23,78 @@ func (key *privExtKey) ECPrivKey() (*btcec.PrivateKey, error) {
 	return key.key.ECPrivKey()
 }
 
-// deriveKeys derives child key following HD path
+// deriveKeys derives child key following HD path.
+//
+// Children are hardened so that neither a leaked event key nor R-point
+// nonces reveal the master key together with the master public key.
 func (key privExtKey) derive(path ...int) (*privExtKey, error) {
 	for _, i := range path {
-		extKey, err := key.key.Child(uint32(i))
+		extKey, err := key.key.Child(key.childIndex(i))
 		if err != nil {
 			return nil, err
 		}
-		key = privExtKey{extKey}
+		key = privExtKey{key: extKey, legacy: key.legacy}
 	}
 
 	return &key, nil
 }
 
-func (oracle *Oracle) extKeyForFixingTime(ftime time.Time) (*privExtKey, error) {
-	hdpath := timeToHDpath(ftime)
-	baseKey := oracle.baseKey()
-	return baseKey.derive(hdpath...)
+func (key privExtKey) childIndex(i int) uint32 {
+	if key.legacy {
+		return uint32(i)
+	}
+	return hdkeychain.HardenedKeyStart + uint32(i)
+}
+
+// KeyPath locates an event key in oracle's HD key tree
+type KeyPath struct {
+	Indices []int // child indices from the master key
+	Legacy  bool  // derives non-hardened children (deprecated)
+}
+
+// String returns the path in the form of "m/1/2/3",
+// which is prefixed with "legacy:" if it's legacy
+func (p KeyPath) String() string {
+	s := "m"
+	for _, i := range p.Indices {
+		s += fmt.Sprintf("/%d", i)
+	}
+	if p.Legacy {
+		return "legacy:" + s
+	}
+	return s
+}
+
+// identityKeyIndex is a hardened child index of the identity key.
+// Event keys are derived deeper, so they never collide with it.
+const identityKeyIndex = 0
+
+// keyPathForEvent returns the path of the event key
+// following the stream branch and then the event branch
+func (oracle *Oracle) keyPathForEvent(id EventID) KeyPath {
+	if hdpath, ok := oracle.legacyHDpath(id); ok {
+		return KeyPath{Indices: hdpath, Legacy: true}
+	}
+
+	return eventKeyPath(id)
 }
 
-func timeToHDpath(t time.Time) []int {
-	return []int{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+// eventKeyPath returns the path of the key of a non-legacy event
+func eventKeyPath(id EventID) KeyPath {
+	hdpath := append(nameToHDpath(id.Stream), nameToHDpath(id.ID)...)
+	return KeyPath{Indices: hdpath}
+}
+
+// hdpathDepth is a number of HD path indices for a stream name or an event id
+const hdpathDepth = 4
+
+// nameToHDpath maps a name to HD path indices using its hash
+func nameToHDpath(name string) []int {
+	h := sha256.Sum256([]byte(name))
+	path := make([]int, hdpathDepth)
+	for i := range path {
+		n := binary.BigEndian.Uint32(h[i*4:])
+		path[i] = int(n &^ hdkeychain.HardenedKeyStart)
+	}
+	return path
 }
@@ -1,55 +1,41 @@
 package oracle
 
 import (
-	"time"
-
-	"github.com/btcsuite/btcd/btcec"
 	"github.com/dgarage/dlc/pkg/oracle"
 )
 
 // PubkeySet is an alias of oracle.PubkeySet
 type PubkeySet = oracle.PubkeySet
 
-// PubkeySet returns a key set for given fixing time
-func (o *Oracle) PubkeySet(ftime time.Time) (PubkeySet, error) {
-	extKey, err := o.extKeyForFixingTime(ftime)
-	// derive oracle's pubkey for the given time
+// PubkeySet returns a key set for given event
+func (o *Oracle) PubkeySet(id EventID) (PubkeySet, error) {
+	event, err := o.Event(id)
 	if err != nil {
 		return PubkeySet{}, err
 	}
-	pubkey, err := extKey.ECPubKey()
+	return o.pubkeySet(event)
+}
+
+func (o *Oracle) pubkeySet(event Event) (PubkeySet, error) {
+	signer, err := o.signerAt(event.Epoch)
 	if err != nil {
 		return PubkeySet{}, err
 	}
 
-	// derive pubkeys for all committed R-points at the given time
-	rpoints, err := committedRpoints(extKey, o.nRpoints)
+	path := o.keyPathForEvent(event.ID)
+	pubset, err := signer.PubkeySet(path, event.NRpoints)
 	if err != nil {
 		return PubkeySet{}, err
 	}
 
-	keyset := PubkeySet{
-		Pubkey:           pubkey,
-		CommittedRpoints: rpoints,
-	}
-
-	return keyset, nil
-}
-
-func committedRpoints(
-	extKey *privExtKey, nRpoints int) ([]*btcec.PublicKey, error) {
-	pubs := []*btcec.PublicKey{}
-	for i := 0; i < nRpoints; i++ {
-		k, err := extKey.derive(i)
-		if err != nil {
-			return nil, err
-		}
-		pub, err := k.ECPubKey()
-		if err != nil {
-			return nil, err
-		}
-		pubs = append(pubs, pub)
+	// sign the announcement with the epoch's long-term key
+	pubset.ID = event.ID
+	pubset.Epoch = event.Epoch
+	pubset.Rotations = o.rotations[:event.Epoch]
+	pubset.Sig, err = signer.SignAnnouncement(path, pubset)
+	if err != nil {
+		return PubkeySet{}, err
 	}
 
-	return pubs, nil
+	return pubset, nil
 }
@@ -14,29 +14,133 @@ func TestPubkeySet(t *testing.T) {
 
 	// Get KeySet
 	ftime := time.Now()
-	keyset, err := o.PubkeySet(ftime)
+	keyset, err := o.PubkeySet(addTestEvent(o, ftime))
 	assert.Nil(err)
 	assert.IsType(PubkeySet{}, keyset)
 
 	// Compare with other keysets
-	keysetSame, _ := o.PubkeySet(ftime) // same time
+	keysetSame, _ := o.PubkeySet(addTestEvent(o, ftime)) // same time
 	assert.Equal(keyset, keysetSame)
 
-	keysetNextYear, _ := o.PubkeySet(ftime.AddDate(1, 0, 0)) // next year
+	keysetNextYear, _ := o.PubkeySet(addTestEvent(o, ftime.AddDate(1, 0, 0))) // next year
 	assert.NotEqual(keyset, keysetNextYear)
 
-	keysetNextMonth, _ := o.PubkeySet(ftime.AddDate(0, 1, 0)) // next month
+	keysetNextMonth, _ := o.PubkeySet(addTestEvent(o, ftime.AddDate(0, 1, 0))) // next month
 	assert.NotEqual(keyset, keysetNextMonth)
 
-	keysetTomorrow, _ := o.PubkeySet(ftime.AddDate(0, 0, 1)) // tomorrow
+	keysetTomorrow, _ := o.PubkeySet(addTestEvent(o, ftime.AddDate(0, 0, 1))) // tomorrow
 	assert.NotEqual(keyset, keysetTomorrow)
 
-	keysetHourLater, _ := o.PubkeySet(ftime.Add(1 * time.Hour)) // an hour later
+	keysetHourLater, _ := o.PubkeySet(addTestEvent(o, ftime.Add(1*time.Hour))) // an hour later
 	assert.NotEqual(keyset, keysetHourLater)
 
-	keysetMiniteLater, _ := o.PubkeySet(ftime.Add(1 * time.Minute)) // a minute later
+	keysetMiniteLater, _ := o.PubkeySet(addTestEvent(o, ftime.Add(1*time.Minute))) // a minute later
 	assert.NotEqual(keyset, keysetMiniteLater)
 
-	keysetSecondLater, _ := o.PubkeySet(ftime.Add(1 * time.Second)) // a second later
+	keysetSecondLater, _ := o.PubkeySet(addTestEvent(o, ftime.Add(1*time.Second))) // a second later
 	assert.NotEqual(keyset, keysetSecondLater)
 }
+
+func TestPubkeySetEventStreams(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	_ = o.AddStream("weather/tokyo")
+
+	// any event ids in a stream
+	id1 := EventID{Stream: "weather/tokyo", ID: "2018-11-11 morning"}
+	id2 := EventID{Stream: "weather/tokyo", ID: "2018-11-11 evening"}
+	idOther := EventID{Stream: TestStream, ID: id1.ID}
+	for _, id := range []EventID{id1, id2, idOther} {
+		_ = o.AddEvent(Event{ID: id, NRpoints: 1})
+	}
+	keyset1, err := o.PubkeySet(id1)
+	assert.NoError(err)
+	keyset2, err := o.PubkeySet(id2)
+	assert.NoError(err)
+	assert.NotEqual(keyset1, keyset2)
+
+	// the same event id in another stream
+	keysetOther, err := o.PubkeySet(idOther)
+	assert.NoError(err)
+	assert.NotEqual(keyset1, keysetOther)
+
+	// unknown stream
+	_, err = o.PubkeySet(EventID{Stream: "unknown", ID: id1.ID})
+	assert.Error(err)
+}
+
+func TestPubkeySetHardened(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	id := addTestEvent(o, time.Now())
+	keyset, _ := o.PubkeySet(id)
+
+	// R-points can't be derived from the oracle's public keys
+	extKey, _ := o.signers[0].(*HDSigner).extKeyAt(o.keyPathForEvent(id))
+	pubExtKey, _ := extKey.key.Neuter()
+	for i := range keyset.CommittedRpoints {
+		child, _ := pubExtKey.Child(uint32(i))
+		R, _ := child.ECPubKey()
+		assert.False(R.IsEqual(keyset.CommittedRpoints[i]))
+	}
+}
+
+func TestPubkeySetNRpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	ftime := time.Now()
+
+	// events with different numbers of R-points
+	idEnum := EventID{Stream: TestStream, ID: "enum"}
+	idDigits := EventID{Stream: TestStream, ID: "digits"}
+	_ = o.AddEvent(Event{ID: idEnum, NRpoints: 1})
+	_ = o.AddEvent(Event{ID: idDigits, NRpoints: 20})
+
+	keysetEnum, err := o.PubkeySet(idEnum)
+	assert.NoError(err)
+	assert.Len(keysetEnum.CommittedRpoints, 1)
+
+	keysetDigits, err := o.PubkeySet(idDigits)
+	assert.NoError(err)
+	assert.Len(keysetDigits.CommittedRpoints, 20)
+
+	// fail without an event
+	_, err = o.PubkeySet(TimeEventID(TestStream, ftime))
+	assert.Error(err)
+}
+
+func TestPubkeySetLegacyEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	ftime := time.Now()
+	o := NewTestOracle()
+	keyset, _ := o.PubkeySet(addTestEvent(o, ftime))
+
+	oLegacy := NewTestOracle()
+	err := oLegacy.MigrateLegacyEvents(TestStream, TestNRpoints, ftime)
+	assert.NoError(err)
+
+	// legacy event keeps the non-hardened keys
+	keysetLegacy, err := oLegacy.PubkeySet(TimeEventID(TestStream, ftime))
+	assert.NoError(err)
+	assert.NotEqual(keyset, keysetLegacy)
+
+	extKey, _ := oLegacy.signers[0].(*HDSigner).masterKey.Child(uint32(ftime.Year()))
+	for _, i := range timeToHDpath(ftime)[1:] {
+		extKey, _ = extKey.Child(uint32(i))
+	}
+	pub, _ := extKey.ECPubKey()
+	assert.True(pub.IsEqual(keysetLegacy.Pubkey))
+	R, _ := extKey.Child(0)
+	Rpub, _ := R.ECPubKey()
+	assert.True(Rpub.IsEqual(keysetLegacy.CommittedRpoints[0]))
+
+	// other events aren't affected
+	nextTime := ftime.Add(1 * time.Second)
+	keysetNext, _ := o.PubkeySet(addTestEvent(o, nextTime))
+	keysetNextLegacy, _ := oLegacy.PubkeySet(addTestEvent(oLegacy, nextTime))
+	assert.Equal(keysetNext, keysetNextLegacy)
+}
@@ -0,0 +1,304 @@
+package oracle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// Methods of the signer protocol
+const (
+	signerMethodPubkey           = "pubkey"
+	signerMethodSignAnnouncement = "sign_announcement"
+	signerMethodSignRotation     = "sign_rotation"
+	signerMethodSignRecord       = "sign_record"
+	signerMethodPubkeySet        = "pubkey_set"
+	signerMethodSignMsgs         = "sign_msgs"
+)
+
+// signerRequest is a request of the signer protocol.
+//
+// The protocol exchanges a JSON request and a JSON response per line
+// over a stream connection. Byte slices are base64 encoded.
+type signerRequest struct {
+	Method   string       `json:"method"`
+	Path     []int        `json:"path,omitempty"`
+	Legacy   bool         `json:"legacy,omitempty"`
+	NRpoints int          `json:"n_rpoints,omitempty"`
+	Msgs     [][]byte     `json:"msgs,omitempty"`
+	Stream   string       `json:"stream,omitempty"`
+	EventID  string       `json:"event_id,omitempty"`
+	Epoch    int          `json:"epoch,omitempty"`
+	Pubkey   []byte       `json:"pubkey,omitempty"` // compressed pubkey
+	Record   *AuditRecord `json:"record,omitempty"`
+}
+
+// signerResponse is a response of the signer protocol
+type signerResponse struct {
+	Error   string   `json:"error,omitempty"`
+	Pubkey  []byte   `json:"pubkey,omitempty"`  // compressed pubkey
+	Rpoints [][]byte `json:"rpoints,omitempty"` // compressed R-points
+	Sig     []byte   `json:"sig,omitempty"`     // DER signature of digest
+	Signs   [][]byte `json:"signs,omitempty"`   // Schnorr signs of messages
+}
+
+// RemoteSigner is a signer running in a separate process
+// reached over a local socket
+type RemoteSigner struct {
+	network string
+	address string
+	Timeout time.Duration // timeout of each request
+}
+
+// DefaultSignerTimeout is a default timeout of requests to a remote signer
+const DefaultSignerTimeout = 10 * time.Second
+
+// NewRemoteSigner creates a client of a signer listening on a unix socket
+func NewRemoteSigner(socketPath string) *RemoteSigner {
+	return &RemoteSigner{
+		network: "unix",
+		address: socketPath,
+		Timeout: DefaultSignerTimeout,
+	}
+}
+
+func (s *RemoteSigner) call(req *signerRequest) (*signerResponse, error) {
+	conn, err := net.DialTimeout(s.network, s.address, s.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.Timeout)); err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	res := &signerResponse{}
+	if err := json.NewDecoder(conn).Decode(res); err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("signer error: %s", res.Error)
+	}
+	return res, nil
+}
+
+// Pubkey is an implementation of Signer.Pubkey
+func (s *RemoteSigner) Pubkey() (*btcec.PublicKey, error) {
+	res, err := s.call(&signerRequest{Method: signerMethodPubkey})
+	if err != nil {
+		return nil, err
+	}
+	return btcec.ParsePubKey(res.Pubkey, btcec.S256())
+}
+
+// SignAnnouncement is an implementation of Signer.SignAnnouncement
+func (s *RemoteSigner) SignAnnouncement(
+	path KeyPath, pubset PubkeySet) ([]byte, error) {
+	res, err := s.call(&signerRequest{
+		Method:   signerMethodSignAnnouncement,
+		Path:     path.Indices,
+		Legacy:   path.Legacy,
+		NRpoints: len(pubset.CommittedRpoints),
+		Stream:   pubset.ID.Stream,
+		EventID:  pubset.ID.ID,
+		Epoch:    pubset.Epoch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Sig, nil
+}
+
+// SignRotation is an implementation of Signer.SignRotation
+func (s *RemoteSigner) SignRotation(
+	epoch int, pub *btcec.PublicKey) ([]byte, error) {
+	res, err := s.call(&signerRequest{
+		Method: signerMethodSignRotation,
+		Epoch:  epoch,
+		Pubkey: pub.SerializeCompressed(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Sig, nil
+}
+
+// SignRecord is an implementation of Signer.SignRecord
+func (s *RemoteSigner) SignRecord(path KeyPath, r *AuditRecord) ([]byte, error) {
+	res, err := s.call(&signerRequest{
+		Method: signerMethodSignRecord,
+		Path:   path.Indices,
+		Legacy: path.Legacy,
+		Record: r,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Sig, nil
+}
+
+// PubkeySet is an implementation of Signer.PubkeySet
+func (s *RemoteSigner) PubkeySet(path KeyPath, nRpoints int) (PubkeySet, error) {
+	res, err := s.call(&signerRequest{
+		Method:   signerMethodPubkeySet,
+		Path:     path.Indices,
+		Legacy:   path.Legacy,
+		NRpoints: nRpoints,
+	})
+	if err != nil {
+		return PubkeySet{}, err
+	}
+	if len(res.Rpoints) != nRpoints {
+		return PubkeySet{}, fmt.Errorf(
+			"invalid number of R-points. expected %d, but got %d",
+			nRpoints, len(res.Rpoints))
+	}
+
+	pubkey, err := btcec.ParsePubKey(res.Pubkey, btcec.S256())
+	if err != nil {
+		return PubkeySet{}, err
+	}
+	rpoints := []*btcec.PublicKey{}
+	for _, b := range res.Rpoints {
+		R, err := btcec.ParsePubKey(b, btcec.S256())
+		if err != nil {
+			return PubkeySet{}, err
+		}
+		rpoints = append(rpoints, R)
+	}
+
+	return PubkeySet{Pubkey: pubkey, CommittedRpoints: rpoints}, nil
+}
+
+// SignMsgs is an implementation of Signer.SignMsgs
+func (s *RemoteSigner) SignMsgs(path KeyPath, msgs [][]byte) ([][]byte, error) {
+	res, err := s.call(&signerRequest{
+		Method: signerMethodSignMsgs,
+		Path:   path.Indices,
+		Legacy: path.Legacy,
+		Msgs:   msgs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Signs) != len(msgs) {
+		return nil, fmt.Errorf(
+			"invalid number of signs. expected %d, but got %d",
+			len(msgs), len(res.Signs))
+	}
+	return res.Signs, nil
+}
+
+// ServeSigner serves requests to a signer on a listener
+// until the listener is closed.
+//
+// Requests only carry fields of the data to sign,
+// and the signer rebuilds the data by itself,
+// so the front end can't make it sign an arbitrary digest.
+func ServeSigner(l net.Listener, s Signer) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveSignerConn(conn, s)
+	}
+}
+
+func serveSignerConn(conn net.Conn, s Signer) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		req := &signerRequest{}
+		if err := dec.Decode(req); err != nil {
+			if err != io.EOF {
+				_ = enc.Encode(&signerResponse{Error: err.Error()})
+			}
+			return
+		}
+
+		res, err := handleSignerRequest(req, s)
+		if err != nil {
+			res = &signerResponse{Error: err.Error()}
+		}
+		if err := enc.Encode(res); err != nil {
+			return
+		}
+	}
+}
+
+func handleSignerRequest(
+	req *signerRequest, s Signer) (*signerResponse, error) {
+	path := KeyPath{Indices: req.Path, Legacy: req.Legacy}
+
+	switch req.Method {
+	case signerMethodPubkey:
+		pub, err := s.Pubkey()
+		if err != nil {
+			return nil, err
+		}
+		return &signerResponse{Pubkey: pub.SerializeCompressed()}, nil
+	case signerMethodSignAnnouncement:
+		pubset := PubkeySet{
+			ID:               EventID{Stream: req.Stream, ID: req.EventID},
+			Epoch:            req.Epoch,
+			CommittedRpoints: make([]*btcec.PublicKey, req.NRpoints),
+		}
+		sig, err := s.SignAnnouncement(path, pubset)
+		if err != nil {
+			return nil, err
+		}
+		return &signerResponse{Sig: sig}, nil
+	case signerMethodSignRotation:
+		pub, err := btcec.ParsePubKey(req.Pubkey, btcec.S256())
+		if err != nil {
+			return nil, err
+		}
+		sig, err := s.SignRotation(req.Epoch, pub)
+		if err != nil {
+			return nil, err
+		}
+		return &signerResponse{Sig: sig}, nil
+	case signerMethodSignRecord:
+		if req.Record == nil {
+			return nil, errors.New("empty record")
+		}
+		sig, err := s.SignRecord(path, req.Record)
+		if err != nil {
+			return nil, err
+		}
+		return &signerResponse{Sig: sig}, nil
+	case signerMethodPubkeySet:
+		pubset, err := s.PubkeySet(path, req.NRpoints)
+		if err != nil {
+			return nil, err
+		}
+		rpoints := [][]byte{}
+		for _, R := range pubset.CommittedRpoints {
+			rpoints = append(rpoints, R.SerializeCompressed())
+		}
+		return &signerResponse{
+			Pubkey:  pubset.Pubkey.SerializeCompressed(),
+			Rpoints: rpoints,
+		}, nil
+	case signerMethodSignMsgs:
+		signs, err := s.SignMsgs(path, req.Msgs)
+		if err != nil {
+			return nil, err
+		}
+		return &signerResponse{Signs: signs}, nil
+	default:
+		return nil, fmt.Errorf("unknown method. method: %s", req.Method)
+	}
+}
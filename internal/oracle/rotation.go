@@ -0,0 +1,61 @@
+package oracle
+
+import (
+	"fmt"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+)
+
+// KeyRotation is an alias of oracle.KeyRotation
+type KeyRotation = oracle.KeyRotation
+
+// Epoch returns the current key epoch
+func (o *Oracle) Epoch() int {
+	return len(o.signers) - 1
+}
+
+// Rotations returns all key rotations from the identity key
+func (o *Oracle) Rotations() []KeyRotation {
+	return append([]KeyRotation{}, o.rotations...)
+}
+
+// RotateKey starts a new key epoch with a new long-term key held by a signer.
+//
+// The new pubkey is signed by the current key, so that contractors
+// trusting the identity pubkey can verify events of the new epoch.
+// Events announced before keep using the keys of their epochs.
+func (o *Oracle) RotateKey(signer Signer) (KeyRotation, error) {
+	pub, err := signer.Pubkey()
+	if err != nil {
+		return KeyRotation{}, err
+	}
+
+	epoch := o.Epoch() + 1
+	sig, err := o.signer().SignRotation(epoch, pub)
+	if err != nil {
+		return KeyRotation{}, err
+	}
+	rotation := KeyRotation{Epoch: epoch, Pubkey: pub, Sig: sig}
+
+	// the rotation record is signed by the current key
+	if err := o.audit(oracle.NewRotationRecord(rotation)); err != nil {
+		return KeyRotation{}, err
+	}
+
+	o.signers = append(o.signers, signer)
+	o.rotations = append(o.rotations, rotation)
+	return rotation, nil
+}
+
+// signer returns the signer of the current epoch
+func (o *Oracle) signer() Signer {
+	return o.signers[o.Epoch()]
+}
+
+// signerAt returns the signer of a given epoch
+func (o *Oracle) signerAt(epoch int) (Signer, error) {
+	if epoch < 0 || epoch >= len(o.signers) {
+		return nil, fmt.Errorf("unknown key epoch. epoch: %d", epoch)
+	}
+	return o.signers[epoch], nil
+}
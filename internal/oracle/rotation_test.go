@@ -0,0 +1,86 @@
+package oracle
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/dgarage/dlc/pkg/schnorr"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSigner(seed byte) Signer {
+	s, _ := NewHDSignerFromSeed(
+		bytes.Repeat([]byte{seed}, 32), chaincfg.RegressionNetParams)
+	return s
+}
+
+func TestRotateKey(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	identity, _ := o.Pubkey()
+	ftime := time.Now()
+	id0 := addTestEvent(o, ftime)
+
+	rotation, err := o.RotateKey(newTestSigner(1))
+	assert.NoError(err)
+	assert.Equal(1, o.Epoch())
+	assert.Equal(1, rotation.Epoch)
+	assert.NoError(rotation.Verify(identity))
+
+	// identity doesn't change
+	pub, _ := o.Pubkey()
+	assert.True(identity.IsEqual(pub))
+
+	id1 := addTestEvent(o, ftime.Add(1*time.Hour))
+	event0, _ := o.Event(id0)
+	event1, _ := o.Event(id1)
+	assert.Equal(0, event0.Epoch)
+	assert.Equal(1, event1.Epoch)
+
+	// both events are verified from the identity
+	pubset0, err := o.PubkeySet(id0)
+	assert.NoError(err)
+	assert.NoError(pubset0.Verify(identity))
+	pubset1, err := o.PubkeySet(id1)
+	assert.NoError(err)
+	assert.Len(pubset1.Rotations, 1)
+	assert.NoError(pubset1.Verify(identity))
+
+	// the event of the old epoch is still signed with its keys
+	_ = o.FixMsgs(id0, randomMsgs(TestNRpoints))
+	signSet, err := o.SignSet(id0)
+	assert.NoError(err)
+	for i, R := range pubset0.CommittedRpoints {
+		P := schnorr.Commit(pubset0.Pubkey, R, signSet.Msgs[i])
+		assert.True(schnorr.Verify(P, signSet.Signs[i]))
+	}
+
+	// fail to verify from another identity
+	other, _ := newTestSigner(2).Pubkey()
+	assert.Error(pubset1.Verify(other))
+}
+
+func TestRotateKeyAuditLog(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	o := NewTestOracle()
+	o.SetAuditLog(NewAuditLog(buf))
+	identity, _ := o.Pubkey()
+
+	ftime := time.Now()
+	id := addTestEvent(o, ftime)
+	_, err := o.RotateKey(newTestSigner(1))
+	assert.NoError(err)
+	_ = addTestEvent(o, ftime.Add(1*time.Hour))
+	assert.NoError(o.FixMsgs(id, randomMsgs(TestNRpoints)))
+
+	records, err := oracle.ReadAuditLog(buf)
+	assert.NoError(err)
+	assert.Len(records, 4)
+	assert.NoError(oracle.VerifyAuditLog(records, identity))
+}
@@ -0,0 +1,217 @@
+package oracle
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default settings of Scheduler
+const (
+	DefaultMaxRetries    = 5
+	DefaultRetryInterval = 1 * time.Minute
+	DefaultPollInterval  = 1 * time.Minute
+)
+
+// Scheduler fixes and signs messages of oracle's events at their maturity
+// using outcomes fetched from data sources.
+//
+// Oracle isn't safe for concurrent use,
+// so events shouldn't be added by other goroutines while Run is running.
+type Scheduler struct {
+	oracle  *Oracle
+	sources map[string]DataSource // data source for each stream
+	retries map[string]*retryState
+
+	MaxRetries    int           // number of retries after the first failure
+	RetryInterval time.Duration // interval between retries
+	PollInterval  time.Duration // max interval to look for new events
+
+	// OnAttest is called when an event has been attested
+	OnAttest func(e Event, signSet SignSet)
+	// OnAlert is called every time attestation fails
+	OnAlert func(err *AttestationError)
+}
+
+type retryState struct {
+	attempts int       // number of failed attempts
+	next     time.Time // time of the next attempt
+}
+
+// AttestationError is an error raised when the scheduler fails to attest an event
+type AttestationError struct {
+	error
+	Event    Event
+	Attempts int  // number of failed attempts
+	Final    bool // true if the scheduler gave up the event
+}
+
+func newAttestationError(
+	e Event, attempts int, final bool, err error) *AttestationError {
+	msg := fmt.Sprintf(
+		"failed to attest event. id: %s, attempts: %d, final: %t, err: %v",
+		e.ID, attempts, final, err)
+	return &AttestationError{
+		error:    fmt.Errorf("%s", msg),
+		Event:    e,
+		Attempts: attempts,
+		Final:    final,
+	}
+}
+
+// NewScheduler creates a scheduler for a given oracle
+func NewScheduler(o *Oracle) *Scheduler {
+	return &Scheduler{
+		oracle:        o,
+		sources:       make(map[string]DataSource),
+		retries:       make(map[string]*retryState),
+		MaxRetries:    DefaultMaxRetries,
+		RetryInterval: DefaultRetryInterval,
+		PollInterval:  DefaultPollInterval,
+	}
+}
+
+// SetDataSource sets a data source for events in a given stream
+func (s *Scheduler) SetDataSource(stream string, src DataSource) {
+	s.sources[stream] = src
+}
+
+// Run attests events at their maturity until stop is closed
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	for {
+		now := time.Now()
+		next := s.AttestDue(now)
+
+		wait := s.PollInterval
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// AttestDue attests all events that are due at a given time,
+// and returns the time when the next event or retry is due
+func (s *Scheduler) AttestDue(now time.Time) (next time.Time) {
+	for _, stream := range s.oracle.Streams() {
+		for _, e := range s.oracle.Events(stream) {
+			due, ok := s.dueTime(e)
+			if !ok {
+				continue
+			}
+
+			if due.After(now) {
+				next = earlier(next, due)
+				continue
+			}
+
+			err := s.attest(e)
+			if err == nil {
+				continue
+			}
+			retryAt, ok := s.fail(e, now, err)
+			if ok {
+				next = earlier(next, retryAt)
+			}
+		}
+	}
+	return next
+}
+
+// dueTime returns the time of the next attempt to attest a given event.
+// An event whose messages have been fixed is due only if signing them failed.
+func (s *Scheduler) dueTime(e Event) (time.Time, bool) {
+	if e.Maturity.IsZero() {
+		return time.Time{}, false
+	}
+
+	st, ok := s.retries[e.ID.String()]
+	if !ok {
+		if s.oracle.HasFixedMsgs(e.ID) {
+			return time.Time{}, false
+		}
+		return e.Maturity, true
+	}
+	if st.attempts > s.MaxRetries {
+		return time.Time{}, false
+	}
+	return st.next, true
+}
+
+// attest fetches the outcome of a given event, fixes and signs it.
+// A cancelled event is attested with the cancellation messages.
+// Messages fixed at a previous attempt are only signed again.
+func (s *Scheduler) attest(e Event) error {
+	if !s.oracle.HasFixedMsgs(e.ID) {
+		if err := s.fix(e); err != nil {
+			return err
+		}
+	}
+
+	signSet, err := s.oracle.SignSet(e.ID)
+	if err != nil {
+		return err
+	}
+
+	delete(s.retries, e.ID.String())
+	if s.OnAttest != nil {
+		s.OnAttest(e, signSet)
+	}
+	return nil
+}
+
+// fix fetches the outcome of a given event and fixes its messages
+func (s *Scheduler) fix(e Event) error {
+	src, ok := s.sources[e.ID.Stream]
+	if !ok {
+		return fmt.Errorf("data source isn't set. stream: %s", e.ID.Stream)
+	}
+
+	outcome, err := src.Outcome(e)
+	switch {
+	case err == ErrEventCancelled:
+		return s.oracle.CancelEvent(e.ID)
+	case err != nil:
+		return err
+	}
+	return s.fixOutcome(e, outcome)
+}
+
+func (s *Scheduler) fixOutcome(e Event, outcome string) error {
+	msgs, err := e.EncodeOutcome(outcome)
+	if err != nil {
+		return err
+	}
+	return s.oracle.FixMsgs(e.ID, msgs)
+}
+
+// fail records a failed attempt, alerts it,
+// and returns the time of the next attempt if it will be retried
+func (s *Scheduler) fail(e Event, now time.Time, err error) (time.Time, bool) {
+	key := e.ID.String()
+	st, ok := s.retries[key]
+	if !ok {
+		st = &retryState{}
+		s.retries[key] = st
+	}
+	st.attempts++
+	st.next = now.Add(s.RetryInterval)
+
+	final := st.attempts > s.MaxRetries
+	if s.OnAlert != nil {
+		s.OnAlert(newAttestationError(e, st.attempts, final, err))
+	}
+
+	return st.next, !final
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,190 @@
+package oracle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+type flakySource struct {
+	failures int
+	outcome  string
+}
+
+func (s *flakySource) Outcome(e Event) (string, error) {
+	if s.failures > 0 {
+		s.failures--
+		return "", errors.New("source unavailable")
+	}
+	return s.outcome, nil
+}
+
+func addMaturedEvent(o *Oracle, id string, maturity time.Time) Event {
+	e := Event{
+		ID:       EventID{Stream: TestStream, ID: id},
+		NRpoints: TestNRpoints,
+		Maturity: maturity,
+	}
+	_ = o.AddEvent(e)
+	return e
+}
+
+func TestSchedulerAttestDue(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	now := time.Now()
+	e1 := addMaturedEvent(o, "1", now.Add(-1*time.Minute))
+	e2 := addMaturedEvent(o, "2", now.Add(1*time.Hour))
+	e3 := addMaturedEvent(o, "3", time.Time{})
+
+	s := NewScheduler(o)
+	s.SetDataSource(TestStream, &FakeSource{})
+	attested := []Event{}
+	s.OnAttest = func(e Event, _ SignSet) { attested = append(attested, e) }
+
+	next := s.AttestDue(now)
+	assert.Equal([]Event{e1}, attested)
+	assert.Equal(e2.Maturity, next)
+	assert.True(o.HasFixedMsgs(e1.ID))
+	assert.False(o.HasFixedMsgs(e2.ID))
+	assert.False(o.HasFixedMsgs(e3.ID))
+
+	// attested events aren't attested again
+	next = s.AttestDue(e2.Maturity)
+	assert.Equal([]Event{e1, e2}, attested)
+	assert.True(next.IsZero())
+	assert.False(o.HasFixedMsgs(e3.ID))
+}
+
+func TestSchedulerRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	now := time.Now()
+	e := addMaturedEvent(o, "1", now)
+
+	s := NewScheduler(o)
+	s.MaxRetries = 2
+	s.SetDataSource(TestStream, &flakySource{failures: 2, outcome: "123"})
+	alerts := []*AttestationError{}
+	s.OnAlert = func(err *AttestationError) { alerts = append(alerts, err) }
+
+	next := s.AttestDue(now)
+	assert.Len(alerts, 1)
+	assert.Equal(now.Add(s.RetryInterval), next)
+
+	// not retried before the interval
+	s.AttestDue(now)
+	assert.Len(alerts, 1)
+
+	next = s.AttestDue(next)
+	assert.Len(alerts, 2)
+	assert.False(alerts[1].Final)
+
+	s.AttestDue(next)
+	assert.Len(alerts, 2)
+	assert.True(o.HasFixedMsgs(e.ID))
+
+	signSet, err := o.SignSet(e.ID)
+	assert.NoError(err)
+	assert.Equal(oracle.OutcomeMsgs("1", "2", "3"), signSet.Msgs)
+}
+
+type flakySigner struct {
+	Signer
+	failures int
+}
+
+func (s *flakySigner) SignMsgs(path KeyPath, msgs [][]byte) ([][]byte, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("signer unavailable")
+	}
+	return s.Signer.SignMsgs(path, msgs)
+}
+
+// an event whose messages are fixed should be signed again
+// if signing them failed
+func TestSchedulerRetrySigning(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	o.signers[0] = &flakySigner{Signer: o.signers[0], failures: 1}
+	now := time.Now()
+	e := addMaturedEvent(o, "1", now)
+
+	s := NewScheduler(o)
+	s.SetDataSource(TestStream, &flakySource{outcome: "123"})
+	alerts := []*AttestationError{}
+	s.OnAlert = func(err *AttestationError) { alerts = append(alerts, err) }
+	attested := []Event{}
+	s.OnAttest = func(e Event, _ SignSet) { attested = append(attested, e) }
+
+	next := s.AttestDue(now)
+	assert.Len(alerts, 1)
+	assert.Empty(attested)
+	assert.True(o.HasFixedMsgs(e.ID))
+	assert.Equal(now.Add(s.RetryInterval), next)
+
+	next = s.AttestDue(next)
+	assert.Len(alerts, 1)
+	assert.Equal([]Event{e}, attested)
+	assert.True(next.IsZero())
+
+	// not attested again
+	s.AttestDue(now.Add(time.Hour))
+	assert.Len(attested, 1)
+}
+
+func TestSchedulerGiveUp(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	now := time.Now()
+	e := addMaturedEvent(o, "1", now)
+
+	s := NewScheduler(o)
+	s.MaxRetries = 1
+	s.SetDataSource(TestStream, &flakySource{failures: 5})
+	alerts := []*AttestationError{}
+	s.OnAlert = func(err *AttestationError) { alerts = append(alerts, err) }
+
+	next := s.AttestDue(now)
+	next = s.AttestDue(next)
+	assert.True(next.IsZero())
+	assert.Len(alerts, 2)
+	assert.True(alerts[1].Final)
+	assert.Equal(e, alerts[1].Event)
+
+	// no more attempts
+	s.AttestDue(now.Add(time.Hour))
+	assert.Len(alerts, 2)
+	assert.False(o.HasFixedMsgs(e.ID))
+}
+
+type cancelledSource struct{}
+
+func (s *cancelledSource) Outcome(e Event) (string, error) {
+	return "", ErrEventCancelled
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	now := time.Now()
+	e := addMaturedEvent(o, "1", now)
+
+	s := NewScheduler(o)
+	s.SetDataSource(TestStream, &cancelledSource{})
+	var attested SignSet
+	s.OnAttest = func(_ Event, signSet SignSet) { attested = signSet }
+
+	s.AttestDue(now)
+	assert.True(o.HasFixedMsgs(e.ID))
+	assert.True(attested.IsCancelled())
+}
@@ -0,0 +1,76 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcwallet/snacl"
+)
+
+// encryptedSeed is a seed encrypted with a key derived from a passphrase
+type encryptedSeed struct {
+	Net  string `json:"net"`  // network name
+	Key  []byte `json:"key"`  // marshaled parameters of the secret key
+	Seed []byte `json:"seed"` // encrypted seed
+}
+
+// NewEncryptedSeed generates a random seed
+// and encrypts it with a passphrase
+func NewEncryptedSeed(pass []byte, params chaincfg.Params) ([]byte, error) {
+	if isMainNet(params) {
+		return nil, fmt.Errorf("mainnet isn't supported yet")
+	}
+
+	seed, err := hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptSeed(seed, pass, params)
+}
+
+// EncryptSeed encrypts a seed with a passphrase
+func EncryptSeed(seed, pass []byte, params chaincfg.Params) ([]byte, error) {
+	key, err := snacl.NewSecretKey(
+		&pass, snacl.DefaultN, snacl.DefaultR, snacl.DefaultP)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	encrypted, err := key.Encrypt(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&encryptedSeed{
+		Net:  params.Name,
+		Key:  key.Marshal(),
+		Seed: encrypted,
+	})
+}
+
+// DecryptSeed decrypts an encrypted seed with a passphrase
+// and returns the seed and the network name
+func DecryptSeed(data, pass []byte) ([]byte, string, error) {
+	es := &encryptedSeed{}
+	if err := json.Unmarshal(data, es); err != nil {
+		return nil, "", err
+	}
+
+	key := &snacl.SecretKey{}
+	if err := key.Unmarshal(es.Key); err != nil {
+		return nil, "", err
+	}
+	if err := key.DeriveKey(&pass); err != nil {
+		return nil, "", err
+	}
+	defer key.Zero()
+
+	seed, err := key.Decrypt(es.Seed)
+	if err != nil {
+		return nil, "", err
+	}
+	return seed, es.Net, nil
+}
@@ -0,0 +1,41 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	pass := []byte("passphrase")
+	params := chaincfg.RegressionNetParams
+
+	data, err := EncryptSeed(seed, pass, params)
+	assert.NoError(err)
+	assert.NotContains(string(data), string(seed))
+
+	decrypted, net, err := DecryptSeed(data, pass)
+	assert.NoError(err)
+	assert.Equal(seed, decrypted)
+	assert.Equal(params.Name, net)
+
+	// fail with a wrong passphrase
+	_, _, err = DecryptSeed(data, []byte("wrong"))
+	assert.Error(err)
+}
+
+func TestNewEncryptedSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	pass := []byte("passphrase")
+	_, err := NewEncryptedSeed(pass, chaincfg.RegressionNetParams)
+	assert.NoError(err)
+
+	// mainnet isn't supported
+	_, err = NewEncryptedSeed(pass, chaincfg.MainNetParams)
+	assert.Error(err)
+}
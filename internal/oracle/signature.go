@@ -1,58 +1,44 @@
 package oracle
 
 import (
-	"time"
+	"fmt"
 
 	"github.com/dgarage/dlc/pkg/oracle"
-	"github.com/dgarage/dlc/pkg/schnorr"
 )
 
 // SignSet is an alias of oracle.SignSet
 type SignSet = oracle.SignSet
 
-// SignSet returns SignSet for given fixing time
-func (oracle *Oracle) SignSet(ftime time.Time) (SignSet, error) {
-	msgs, err := oracle.msgsAt(ftime)
+// SignSet returns SignSet for given event
+func (oracle *Oracle) SignSet(id EventID) (SignSet, error) {
+	event, err := oracle.Event(id)
 	if err != nil {
 		return SignSet{}, err
 	}
 
-	extKey, err := oracle.extKeyForFixingTime(ftime)
+	msgs, err := oracle.msgsAt(id)
 	if err != nil {
 		return SignSet{}, err
 	}
+	return oracle.signSet(event, msgs)
+}
 
-	signs, err := signMsgs(msgs, extKey)
-	if err != nil {
-		return SignSet{}, err
+func (oracle *Oracle) signSet(event Event, msgs [][]byte) (SignSet, error) {
+	if len(msgs) != event.NRpoints {
+		return SignSet{}, fmt.Errorf(
+			"invalid messages size. expected %d, but got %d", event.NRpoints, len(msgs))
 	}
 
-	return SignSet{Msgs: msgs, Signs: signs}, nil
-}
-
-func signMsgs(msgs [][]byte, extKey *privExtKey) ([][]byte, error) {
-	opriv, err := extKey.ECPrivKey()
+	signer, err := oracle.signerAt(event.Epoch)
 	if err != nil {
-		return [][]byte{}, err
+		return SignSet{}, err
 	}
 
-	signs := [][]byte{}
-
-	for i, m := range msgs {
-		k, err := extKey.derive(i)
-		if err != nil {
-			return [][]byte{}, err
-		}
-		rpriv, err := k.ECPrivKey()
-		if err != nil {
-			return [][]byte{}, err
-		}
-
-		// Schnorr signature
-		sign := schnorr.Sign(opriv, rpriv, m)
-
-		signs = append(signs, sign)
+	path := oracle.keyPathForEvent(event.ID)
+	signs, err := signer.SignMsgs(path, msgs)
+	if err != nil {
+		return SignSet{}, err
 	}
 
-	return signs, nil
+	return SignSet{Msgs: msgs, Signs: signs}, nil
 }
@@ -1,11 +1,12 @@
 package oracle
 
 import (
-	"math/big"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/dgarage/dlc/pkg/oracle"
 	"github.com/dgarage/dlc/pkg/schnorr"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,8 +16,9 @@ func TestSignSetNoMsgsFailure(t *testing.T) {
 
 	o := NewTestOracle()
 	ftime := time.Now()
+	id := addTestEvent(o, ftime)
 
-	_, err := o.SignSet(ftime)
+	_, err := o.SignSet(id)
 	assert.NotNil(err)
 }
 
@@ -25,15 +27,16 @@ func TestSignSet(t *testing.T) {
 
 	o := NewTestOracle()
 	ftime := time.Now()
-	pub, _ := o.PubkeySet(ftime)
+	id := addTestEvent(o, ftime)
+	pub, _ := o.PubkeySet(id)
 
 	// Fix msgs
-	msgs := randomMsgs(o.nRpoints)
-	err := o.FixMsgs(ftime, msgs)
+	msgs := randomMsgs(TestNRpoints)
+	err := o.FixMsgs(id, msgs)
 	assert.Nil(err)
 
 	// Get signetures
-	signSet, err := o.SignSet(ftime)
+	signSet, err := o.SignSet(id)
 	assert.Nil(err)
 	assert.Equal(len(signSet.Msgs), len(signSet.Signs))
 	assert.Equal(len(signSet.Msgs), len(pub.CommittedRpoints))
@@ -58,8 +61,27 @@ func TestSignSet(t *testing.T) {
 func randomMsgs(n int) [][]byte {
 	var msgs [][]byte
 	for i := 0; i < n; i++ {
-		m := big.NewInt(rand.Int63n(9)).Bytes()
+		m := oracle.OutcomeMsg(strconv.Itoa(rand.Intn(10)))
 		msgs = append(msgs, m)
 	}
 	return msgs
 }
+
+func TestSignSetLegacyEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	ftime := time.Now()
+	id := TimeEventID(TestStream, ftime)
+	_ = o.MigrateLegacyEvents(TestStream, TestNRpoints, ftime)
+	pub, _ := o.PubkeySet(id)
+
+	msgs := randomMsgs(TestNRpoints)
+	_ = o.FixMsgs(id, msgs)
+	signSet, err := o.SignSet(id)
+	assert.NoError(err)
+
+	// signs still verify with the legacy keys
+	P := schnorr.CommitMulti(pub.Pubkey, pub.CommittedRpoints, signSet.Msgs)
+	assert.True(schnorr.Verify(P, schnorr.SumSigns(signSet.Signs)))
+}
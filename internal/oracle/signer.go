@@ -0,0 +1,354 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/dgarage/dlc/pkg/schnorr"
+)
+
+// Signer holds oracle's private keys and signs on behalf of the oracle.
+//
+// The oracle only asks a signer for public keys and signatures,
+// so private keys can be kept in a separate process.
+// A signer only signs data it rebuilds from the request by itself,
+// and never signs different messages at the same key path.
+type Signer interface {
+	// Pubkey returns the oracle's identity pubkey
+	Pubkey() (*btcec.PublicKey, error)
+	// SignAnnouncement signs the announcement of the event keys at a key path
+	// with the identity key and returns a DER signature.
+	// The pubkey and R-points of the pubkey set are derived by the signer.
+	SignAnnouncement(path KeyPath, pubset PubkeySet) ([]byte, error)
+	// SignRotation signs a key rotation with the identity key
+	// and returns a DER signature
+	SignRotation(epoch int, pub *btcec.PublicKey) ([]byte, error)
+	// SignRecord signs an audit record with the identity key
+	// and returns a DER signature.
+	// The signer rebuilds the record from the keys at a key path,
+	// messages signed there or rotations it signed,
+	// and refuses a record that doesn't match.
+	SignRecord(path KeyPath, r *AuditRecord) ([]byte, error)
+	// PubkeySet returns the event pubkey and committed R-points at a key path
+	PubkeySet(path KeyPath, nRpoints int) (PubkeySet, error)
+	// SignMsgs signs messages with the event key and R-points at a key path
+	SignMsgs(path KeyPath, msgs [][]byte) ([][]byte, error)
+}
+
+// HDSigner is an in-process signer deriving keys from an HD master key.
+//
+// It remembers messages signed at each key path and refuses to sign
+// different messages there, since it would reveal the event key.
+// The messages can be kept in a file with PersistSignedMsgs.
+type HDSigner struct {
+	masterKey *hdkeychain.ExtendedKey // master HD extended key (private)
+
+	mu              sync.Mutex
+	signedMsgs      map[string][][]byte      // messages signed at each key path
+	signedPath      string                   // file keeping signed messages (option)
+	signedRotations map[int]*btcec.PublicKey // pubkeys signed at each epoch
+}
+
+// NewHDSigner creates a signer with a master private key
+func NewHDSigner(masterKey *hdkeychain.ExtendedKey) *HDSigner {
+	return &HDSigner{
+		masterKey:       masterKey,
+		signedMsgs:      make(map[string][][]byte),
+		signedRotations: make(map[int]*btcec.PublicKey),
+	}
+}
+
+// PersistSignedMsgs loads messages signed before from a file
+// and saves messages to it every time new ones are signed.
+// The file is created if it doesn't exist.
+func (s *HDSigner) PersistSignedMsgs(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		signed := make(map[string][][]byte)
+		if err := json.Unmarshal(b, &signed); err != nil {
+			return err
+		}
+		for key, msgs := range signed {
+			s.signedMsgs[key] = msgs
+		}
+	}
+
+	s.signedPath = path
+	return s.saveSignedMsgs()
+}
+
+func (s *HDSigner) saveSignedMsgs() error {
+	if s.signedPath == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.signedMsgs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.signedPath, b)
+}
+
+// recordSignedMsgs records messages to sign at a key path.
+// It fails if different messages have been signed at the path.
+func (s *HDSigner) recordSignedMsgs(path KeyPath, msgs [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := path.String()
+	if signed, ok := s.signedMsgs[key]; ok {
+		if !equalMsgs(signed, msgs) {
+			return fmt.Errorf(
+				"different messages have already been signed at %s", key)
+		}
+		return nil
+	}
+
+	s.signedMsgs[key] = msgs
+	if err := s.saveSignedMsgs(); err != nil {
+		delete(s.signedMsgs, key)
+		return err
+	}
+	return nil
+}
+
+func equalMsgs(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewHDSignerFromSeed creates a signer with a master key generated from a seed
+func NewHDSignerFromSeed(seed []byte, params chaincfg.Params) (*HDSigner, error) {
+	if isMainNet(params) {
+		return nil, fmt.Errorf("mainnet isn't supported yet")
+	}
+
+	mKey, err := hdkeychain.NewMaster(seed, &params)
+	if err != nil {
+		return nil, err
+	}
+	return NewHDSigner(mKey), nil
+}
+
+func (s *HDSigner) baseKey() privExtKey {
+	return privExtKey{key: s.masterKey}
+}
+
+func (s *HDSigner) identityKey() (*privExtKey, error) {
+	return s.baseKey().derive(identityKeyIndex)
+}
+
+func (s *HDSigner) extKeyAt(path KeyPath) (*privExtKey, error) {
+	baseKey := s.baseKey()
+	baseKey.legacy = path.Legacy
+	return baseKey.derive(path.Indices...)
+}
+
+// Pubkey is an implementation of Signer.Pubkey
+func (s *HDSigner) Pubkey() (*btcec.PublicKey, error) {
+	key, err := s.identityKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPubKey()
+}
+
+// SignAnnouncement is an implementation of Signer.SignAnnouncement
+func (s *HDSigner) SignAnnouncement(
+	path KeyPath, pubset PubkeySet) ([]byte, error) {
+	derived, err := s.PubkeySet(path, len(pubset.CommittedRpoints))
+	if err != nil {
+		return nil, err
+	}
+	derived.ID = pubset.ID
+	derived.Epoch = pubset.Epoch
+	return s.signDigest(derived.Digest())
+}
+
+// SignRotation is an implementation of Signer.SignRotation
+func (s *HDSigner) SignRotation(
+	epoch int, pub *btcec.PublicKey) ([]byte, error) {
+	s.mu.Lock()
+	if signed, ok := s.signedRotations[epoch]; ok && !signed.IsEqual(pub) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf(
+			"a different pubkey has already been signed at epoch %d", epoch)
+	}
+	s.signedRotations[epoch] = pub
+	s.mu.Unlock()
+
+	return s.signDigest(oracle.RotationDigest(epoch, pub))
+}
+
+// SignRecord is an implementation of Signer.SignRecord
+func (s *HDSigner) SignRecord(path KeyPath, r *AuditRecord) ([]byte, error) {
+	rebuilt, err := s.rebuildRecord(path, r)
+	if err != nil {
+		return nil, err
+	}
+	rebuilt.Seq = r.Seq
+	rebuilt.Time = r.Time
+	rebuilt.PrevHash = r.PrevHash
+
+	digest, err := rebuilt.Digest()
+	if err != nil {
+		return nil, err
+	}
+	given, err := r.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(digest, given) {
+		return nil, fmt.Errorf("%s record doesn't match the signer's data", r.Type)
+	}
+	return s.signDigest(digest)
+}
+
+// rebuildRecord rebuilds an unchained record from the signer's own data
+func (s *HDSigner) rebuildRecord(
+	path KeyPath, r *AuditRecord) (*AuditRecord, error) {
+	if r.Type != oracle.RecordRotation && !path.Legacy &&
+		path.String() != eventKeyPath(r.ID()).String() {
+		return nil, fmt.Errorf("key path doesn't match the event. id: %s", r.ID())
+	}
+
+	switch r.Type {
+	case oracle.RecordAnnouncement:
+		pubset, err := s.PubkeySet(path, len(r.Rpoints))
+		if err != nil {
+			return nil, err
+		}
+		pubset.Epoch = r.Epoch
+		return oracle.NewAnnouncementRecord(r.ID(), pubset), nil
+	case oracle.RecordAttestation:
+		s.mu.Lock()
+		msgs, ok := s.signedMsgs[path.String()]
+		s.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no messages have been signed at %s", path)
+		}
+		signs, err := s.signMsgs(path, msgs)
+		if err != nil {
+			return nil, err
+		}
+		signSet := SignSet{Msgs: msgs, Signs: signs}
+		return oracle.NewAttestationRecord(r.ID(), r.Epoch, signSet), nil
+	case oracle.RecordRotation:
+		s.mu.Lock()
+		pub, ok := s.signedRotations[r.Epoch]
+		s.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no rotation has been signed at epoch %d", r.Epoch)
+		}
+		rotation := KeyRotation{Epoch: r.Epoch, Pubkey: pub}
+		return oracle.NewRotationRecord(rotation), nil
+	default:
+		return nil, fmt.Errorf("unknown record type. type: %s", r.Type)
+	}
+}
+
+// signDigest signs a digest with the identity key
+func (s *HDSigner) signDigest(digest []byte) ([]byte, error) {
+	key, err := s.identityKey()
+	if err != nil {
+		return nil, err
+	}
+	priv, err := key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := priv.Sign(digest)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+// PubkeySet is an implementation of Signer.PubkeySet
+func (s *HDSigner) PubkeySet(path KeyPath, nRpoints int) (PubkeySet, error) {
+	// derive oracle's pubkey for the event
+	extKey, err := s.extKeyAt(path)
+	if err != nil {
+		return PubkeySet{}, err
+	}
+	pubkey, err := extKey.ECPubKey()
+	if err != nil {
+		return PubkeySet{}, err
+	}
+
+	// derive pubkeys for all committed R-points of the event
+	rpoints := []*btcec.PublicKey{}
+	for i := 0; i < nRpoints; i++ {
+		k, err := extKey.derive(i)
+		if err != nil {
+			return PubkeySet{}, err
+		}
+		pub, err := k.ECPubKey()
+		if err != nil {
+			return PubkeySet{}, err
+		}
+		rpoints = append(rpoints, pub)
+	}
+
+	return PubkeySet{Pubkey: pubkey, CommittedRpoints: rpoints}, nil
+}
+
+// SignMsgs is an implementation of Signer.SignMsgs
+func (s *HDSigner) SignMsgs(path KeyPath, msgs [][]byte) ([][]byte, error) {
+	if err := s.recordSignedMsgs(path, msgs); err != nil {
+		return nil, err
+	}
+	return s.signMsgs(path, msgs)
+}
+
+// signMsgs signs messages at a key path without recording them
+func (s *HDSigner) signMsgs(path KeyPath, msgs [][]byte) ([][]byte, error) {
+	extKey, err := s.extKeyAt(path)
+	if err != nil {
+		return nil, err
+	}
+	opriv, err := extKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	signs := [][]byte{}
+	for i, m := range msgs {
+		k, err := extKey.derive(i)
+		if err != nil {
+			return nil, err
+		}
+		rpriv, err := k.ECPrivKey()
+		if err != nil {
+			return nil, err
+		}
+
+		// Schnorr signature
+		sign := schnorr.Sign(opriv, rpriv, m)
+
+		signs = append(signs, sign)
+	}
+
+	return signs, nil
+}
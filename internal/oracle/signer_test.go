@@ -0,0 +1,214 @@
+package oracle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dgarage/dlc/pkg/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHDSignerFromSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := make([]byte, 32)
+	_, err := NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	assert.NoError(err)
+
+	// mainnet isn't supported
+	_, err = NewHDSignerFromSeed(seed, chaincfg.MainNetParams)
+	assert.Error(err)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "signer.sock")
+
+	// in-process oracle and its signer served over a socket
+	o := NewTestOracle()
+	l, err := net.Listen("unix", socketPath)
+	assert.NoError(err)
+	defer l.Close()
+	go func() { _ = ServeSigner(l, o.signer()) }()
+
+	remote := NewWithSigner("remote", NewRemoteSigner(socketPath))
+	remote.InitDB()
+	_ = remote.AddStream(TestStream)
+
+	pub, err := o.Pubkey()
+	assert.NoError(err)
+	remotePub, err := remote.Pubkey()
+	assert.NoError(err)
+	assert.True(pub.IsEqual(remotePub))
+
+	// the same keys and signs without holding private keys
+	ftime := time.Now()
+	id := addTestEvent(o, ftime)
+	_ = addTestEvent(remote, ftime)
+
+	keyset, err := o.PubkeySet(id)
+	assert.NoError(err)
+	remoteKeyset, err := remote.PubkeySet(id)
+	assert.NoError(err)
+	assert.Equal(keyset, remoteKeyset)
+
+	msgs := randomMsgs(TestNRpoints)
+	_ = o.FixMsgs(id, msgs)
+	_ = remote.FixMsgs(id, msgs)
+
+	signSet, err := o.SignSet(id)
+	assert.NoError(err)
+	remoteSignSet, err := remote.SignSet(id)
+	assert.NoError(err)
+	assert.Equal(signSet, remoteSignSet)
+
+	// the signer refuses different messages at the same path
+	path := o.keyPathForEvent(id)
+	_, err = remote.signer().SignMsgs(path, oracle.OutcomeMsgs("x", "y", "z"))
+	assert.Error(err)
+
+	// audit records are signed without sending digests
+	var buf bytes.Buffer
+	remote.SetAuditLog(NewAuditLog(&buf))
+	_ = addTestEvent(remote, ftime.Add(time.Hour))
+	records, err := oracle.ReadAuditLog(&buf)
+	assert.NoError(err)
+	assert.Len(records, 1)
+	assert.NoError(oracle.VerifyAuditLog(records, pub))
+
+	// fail with an unknown method
+	_, err = NewRemoteSigner(socketPath).call(&signerRequest{Method: "unknown"})
+	assert.Error(err)
+	_, err = NewRemoteSigner(socketPath).call(
+		&signerRequest{Method: "sign_digest"})
+	assert.Error(err)
+
+	// fail without a signer process
+	_, err = NewRemoteSigner(filepath.Join(dir, "none.sock")).Pubkey()
+	assert.Error(err)
+}
+
+// a signer should never sign different messages at the same path
+// even after restarting
+func TestHDSignerSignedMsgs(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	signedPath := filepath.Join(dir, "signed.json")
+
+	seed := make([]byte, 32)
+	s, _ := NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	assert.NoError(s.PersistSignedMsgs(signedPath))
+
+	path := KeyPath{Indices: []int{1, 2}}
+	msgs := oracle.OutcomeMsgs("1", "2")
+	signs, err := s.SignMsgs(path, msgs)
+	assert.NoError(err)
+
+	// the same messages can be signed again
+	signs2, err := s.SignMsgs(path, msgs)
+	assert.NoError(err)
+	assert.Equal(signs, signs2)
+
+	_, err = s.SignMsgs(path, oracle.OutcomeMsgs("1", "3"))
+	assert.Error(err)
+
+	// other paths aren't affected
+	_, err = s.SignMsgs(KeyPath{Indices: []int{1, 2}, Legacy: true},
+		oracle.OutcomeMsgs("1", "3"))
+	assert.NoError(err)
+
+	// restart
+	s, _ = NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	assert.NoError(s.PersistSignedMsgs(signedPath))
+	_, err = s.SignMsgs(path, oracle.OutcomeMsgs("1", "3"))
+	assert.Error(err)
+	_, err = s.SignMsgs(path, msgs)
+	assert.NoError(err)
+}
+
+func TestHDSignerSignRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := make([]byte, 32)
+	s, _ := NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	id := EventID{Stream: TestStream, ID: "1"}
+	path := eventKeyPath(id)
+
+	// announcement
+	pubset, _ := s.PubkeySet(path, TestNRpoints)
+	_, err := s.SignRecord(path, oracle.NewAnnouncementRecord(id, pubset))
+	assert.NoError(err)
+
+	// attestation
+	msgs := oracle.OutcomeMsgs("1", "2", "3")
+	signs, _ := s.SignMsgs(path, msgs)
+	signSet := SignSet{Msgs: msgs, Signs: signs}
+	_, err = s.SignRecord(path, oracle.NewAttestationRecord(id, 0, signSet))
+	assert.NoError(err)
+
+	// rotation
+	pub, _ := newTestSigner(1).Pubkey()
+	_, _ = s.SignRotation(1, pub)
+	rotation := KeyRotation{Epoch: 1, Pubkey: pub}
+	_, err = s.SignRecord(KeyPath{}, oracle.NewRotationRecord(rotation))
+	assert.NoError(err)
+
+	_, err = s.SignRecord(KeyPath{}, &AuditRecord{Type: "digest"})
+	assert.Error(err)
+}
+
+func TestHDSignerSignRecordTampered(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := make([]byte, 32)
+	s, _ := NewHDSignerFromSeed(seed, chaincfg.RegressionNetParams)
+	id := EventID{Stream: TestStream, ID: "1"}
+	path := eventKeyPath(id)
+
+	// announcement of keys the signer doesn't hold
+	other, _ := newTestSigner(1).PubkeySet(path, TestNRpoints)
+	_, err := s.SignRecord(path, oracle.NewAnnouncementRecord(id, other))
+	assert.Error(err)
+
+	// announcement at a key path of another event
+	pubset, _ := s.PubkeySet(path, TestNRpoints)
+	id2 := EventID{Stream: TestStream, ID: "2"}
+	_, err = s.SignRecord(path, oracle.NewAnnouncementRecord(id2, pubset))
+	assert.Error(err)
+
+	// attestation of messages not signed
+	msgs := oracle.OutcomeMsgs("1", "2", "3")
+	signs, _ := s.SignMsgs(path, msgs)
+	tampered := oracle.OutcomeMsgs("1", "2", "4")
+	signSet := SignSet{Msgs: tampered, Signs: signs}
+	_, err = s.SignRecord(path, oracle.NewAttestationRecord(id, 0, signSet))
+	assert.Error(err)
+
+	// attestation with tampered signs
+	signSet = SignSet{Msgs: msgs, Signs: [][]byte{signs[1], signs[0], signs[2]}}
+	_, err = s.SignRecord(path, oracle.NewAttestationRecord(id, 0, signSet))
+	assert.Error(err)
+
+	// rotation not signed, or to another pubkey
+	pub, _ := newTestSigner(1).Pubkey()
+	rotation := KeyRotation{Epoch: 1, Pubkey: pub}
+	_, err = s.SignRecord(KeyPath{}, oracle.NewRotationRecord(rotation))
+	assert.Error(err)
+	_, _ = s.SignRotation(1, pub)
+	rotation.Pubkey, _ = newTestSigner(2).Pubkey()
+	_, err = s.SignRecord(KeyPath{}, oracle.NewRotationRecord(rotation))
+	assert.Error(err)
+}
@@ -2,32 +2,92 @@ package oracle
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/dgarage/dlc/pkg/oracle"
 )
 
+// EventID is an alias of oracle.EventID
+type EventID = oracle.EventID
+
 // TimeFormat is a format of settlement time
-const TimeFormat = "2006-01-02 15:04:05"
+const TimeFormat = oracle.TimeFormat
+
+// TimeEventID returns an id of an event fixed at a given time in a stream
+func TimeEventID(stream string, ftime time.Time) EventID {
+	return oracle.TimeEventID(stream, ftime)
+}
 
 type memdb struct {
-	msgs map[string][][]byte
+	streams map[string]bool
+	events  map[string]*Event
+	msgs    map[string][][]byte
+	legacy  map[string][]int // HD paths of events announced with non-hardened keys
 }
 
 // InitDB initialized oracle's DB
 func (o *Oracle) InitDB() {
+	streams := make(map[string]bool)
+	events := make(map[string]*Event)
 	msgs := make(map[string][][]byte)
-	o.db = &memdb{msgs: msgs}
+	legacy := make(map[string][]int)
+	o.db = &memdb{streams: streams, events: events, msgs: msgs, legacy: legacy}
 }
 
 func (o *Oracle) dbReady() bool {
 	return (o.db != nil) && (o.db.msgs != nil)
 }
 
-func (o *Oracle) msgsAt(ftime time.Time) ([][]byte, error) {
+// AddStream adds a named event stream
+func (o *Oracle) AddStream(name string) error {
+	if !o.dbReady() {
+		return fmt.Errorf("DB isn't ready")
+	}
+
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid stream name. name: %q", name)
+	}
+	if o.db.streams[name] {
+		return fmt.Errorf("stream already exists. name: %s", name)
+	}
+	o.db.streams[name] = true
+
+	return nil
+}
+
+// Streams returns names of all event streams
+func (o *Oracle) Streams() []string {
 	if !o.dbReady() {
-		return [][]byte{}, fmt.Errorf("DB isn't ready")
+		return []string{}
 	}
 
-	key := ftime.Format(TimeFormat)
+	names := []string{}
+	for name := range o.db.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o *Oracle) checkStream(id EventID) error {
+	if !o.dbReady() {
+		return fmt.Errorf("DB isn't ready")
+	}
+
+	if !o.db.streams[id.Stream] {
+		return fmt.Errorf("unknown stream. name: %s", id.Stream)
+	}
+	return nil
+}
+
+func (o *Oracle) msgsAt(id EventID) ([][]byte, error) {
+	if _, err := o.Event(id); err != nil {
+		return [][]byte{}, err
+	}
+
+	key := id.String()
 	vals, ok := o.db.msgs[key]
 	if !ok {
 		return [][]byte{}, fmt.Errorf("not found messages at %s", key)
@@ -35,18 +95,99 @@ func (o *Oracle) msgsAt(ftime time.Time) ([][]byte, error) {
 	return vals, nil
 }
 
-// FixMsgs fixes messsages at a specified time
-func (o *Oracle) FixMsgs(ftime time.Time, msgs [][]byte) error {
-	if !o.dbReady() {
-		return fmt.Errorf("DB isn't ready")
+// FixMsgs fixes canonical messsages of a specified event.
+// Legacy events migrated by MigrateLegacyEvents accept raw messages.
+// Messages can be fixed only once, since signing different messages
+// with the same R-points reveals the oracle's private key.
+func (o *Oracle) FixMsgs(id EventID, msgs [][]byte) error {
+	event, err := o.Event(id)
+	if err != nil {
+		return err
 	}
 
-	size := o.nRpoints
+	size := event.NRpoints
 	if len(msgs) != size {
 		return fmt.Errorf("invalid messages size. expected %d, but got %d", size, len(msgs))
 	}
-	key := ftime.Format(TimeFormat)
+	key := id.String()
+	// legacy events keep attesting raw messages of contracts made on them
+	if _, legacy := o.db.legacy[key]; !legacy {
+		for i, m := range msgs {
+			if !oracle.IsCanonicalMsg(m) {
+				return fmt.Errorf("message isn't canonical at %d. size: %d", i, len(m))
+			}
+		}
+	}
+	if _, ok := o.db.msgs[key]; ok {
+		return fmt.Errorf("messages have already been fixed at %s", key)
+	}
+
+	if err := o.attest(event, msgs); err != nil {
+		return err
+	}
 	o.db.msgs[key] = msgs
 
 	return nil
 }
+
+// CancelEvent attests that an event didn't occur
+// by fixing the reserved cancellation message on all its R-points
+func (o *Oracle) CancelEvent(id EventID) error {
+	event, err := o.Event(id)
+	if err != nil {
+		return err
+	}
+	return o.FixMsgs(id, oracle.CancelMsgs(event.NRpoints))
+}
+
+// attest records the attestation of fixed messages
+func (o *Oracle) attest(event Event, msgs [][]byte) error {
+	if o.auditLog == nil {
+		return nil
+	}
+
+	signSet, err := o.signSet(event, msgs)
+	if err != nil {
+		return err
+	}
+	return o.audit(oracle.NewAttestationRecord(event.ID, event.Epoch, signSet))
+}
+
+// HasFixedMsgs checks if messages of a given event have been fixed
+func (o *Oracle) HasFixedMsgs(id EventID) bool {
+	if !o.dbReady() {
+		return false
+	}
+	_, ok := o.db.msgs[id.String()]
+	return ok
+}
+
+// MigrateLegacyEvents registers events at fixing times whose pubkey sets were
+// published with the deprecated non-hardened time derivation.
+// The oracle keeps deriving keys for those events the old way,
+// so that contracts already made on them can still be settled.
+func (o *Oracle) MigrateLegacyEvents(
+	stream string, nRpoints int, ftimes ...time.Time) error {
+	for _, ftime := range ftimes {
+		id := TimeEventID(stream, ftime)
+		e := Event{ID: id, NRpoints: nRpoints}
+		err := o.addEvent(e, timeToHDpath(ftime))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *Oracle) legacyHDpath(id EventID) ([]int, bool) {
+	if !o.dbReady() {
+		return nil, false
+	}
+	hdpath, ok := o.db.legacy[id.String()]
+	return hdpath, ok
+}
+
+func timeToHDpath(t time.Time) []int {
+	return []int{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+}
@@ -0,0 +1,108 @@
+package oracle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddStream(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+
+	err := o.AddStream("btcusd")
+	assert.NoError(err)
+	err = o.AddStream("weather/tokyo")
+	assert.NoError(err)
+	assert.Equal([]string{"btcusd", TestStream, "weather/tokyo"}, o.Streams())
+
+	// fail with duplicated or invalid names
+	assert.Error(o.AddStream("btcusd"))
+	assert.Error(o.AddStream(""))
+	assert.Error(o.AddStream("btc:usd"))
+}
+
+func TestFixMsgsUnknownStream(t *testing.T) {
+	o := NewTestOracle()
+
+	id := TimeEventID("unknown", time.Now())
+	err := o.FixMsgs(id, randomMsgs(TestNRpoints))
+	assert.Error(t, err)
+}
+
+func TestFixMsgsInvalidSize(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	id := EventID{Stream: TestStream, ID: "enum"}
+	_ = o.AddEvent(Event{ID: id, NRpoints: 1})
+
+	err := o.FixMsgs(id, randomMsgs(2))
+	assert.Error(err)
+
+	// non-canonical message
+	err = o.FixMsgs(id, [][]byte{{1}})
+	assert.Error(err)
+
+	err = o.FixMsgs(id, randomMsgs(1))
+	assert.NoError(err)
+}
+
+func TestFixMsgsTwice(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	id := addTestEvent(o, time.Now())
+
+	msgs := randomMsgs(TestNRpoints)
+	err := o.FixMsgs(id, msgs)
+	assert.NoError(err)
+
+	// refuse to fix messages of the attested event again
+	err = o.FixMsgs(id, randomMsgs(TestNRpoints))
+	assert.Error(err)
+
+	fixed, _ := o.msgsAt(id)
+	assert.Equal(msgs, fixed)
+}
+
+func TestFixMsgsLegacyEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	ftime := time.Now()
+	assert.NoError(o.MigrateLegacyEvents(TestStream, 1, ftime))
+	id := TimeEventID(TestStream, ftime)
+
+	// legacy events attest raw messages
+	msgs := [][]byte{{1}}
+	assert.NoError(o.FixMsgs(id, msgs))
+	signSet, err := o.SignSet(id)
+	assert.NoError(err)
+	assert.Equal(msgs, signSet.Msgs)
+}
+
+func TestCancelEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewTestOracle()
+	id := addTestEvent(o, time.Now())
+
+	err := o.CancelEvent(id)
+	assert.NoError(err)
+
+	signSet, err := o.SignSet(id)
+	assert.NoError(err)
+	assert.True(signSet.IsCancelled())
+	assert.Len(signSet.Signs, TestNRpoints)
+
+	// fail to fix messages of the cancelled event
+	assert.Error(o.FixMsgs(id, randomMsgs(TestNRpoints)))
+
+	// fail to cancel an attested event
+	id2 := addTestEvent(o, time.Now().Add(1*time.Hour))
+	_ = o.FixMsgs(id2, randomMsgs(TestNRpoints))
+	assert.Error(o.CancelEvent(id2))
+}
@@ -1,15 +1,32 @@
 package oracle
 
-import "github.com/btcsuite/btcd/chaincfg"
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// TestStream is a name of event stream of the test oracle
+const TestStream = "test"
+
+// TestNRpoints is a number of R-points of test events
+const TestNRpoints = 3
 
 // NewTestOracle creates a oracle for test
 func NewTestOracle() *Oracle {
 	name := "test"
 	params := chaincfg.RegressionNetParams
-	nRpoints := 3
 
-	o, _ := New(name, params, nRpoints)
+	o, _ := New(name, params)
 	o.InitDB()
+	_ = o.AddStream(TestStream)
 
 	return o
 }
+
+// addTestEvent adds an event at a given time to the test stream
+func addTestEvent(o *Oracle, ftime time.Time) EventID {
+	id := TimeEventID(TestStream, ftime)
+	_ = o.AddEvent(Event{ID: id, NRpoints: TestNRpoints})
+	return id
+}
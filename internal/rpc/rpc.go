@@ -27,6 +27,7 @@ type Client interface {
 	SendToAddress(address btcutil.Address, amount btcutil.Amount) (*chainhash.Hash, error)
 	Generate(numBlocks uint32) ([]*chainhash.Hash, error)
 	GetBlockCount() (int64, error)
+	GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error)
 	RawRequest(method string, params []json.RawMessage) (json.RawMessage, error)
 	// TODO: add Shutdown func
 }
@@ -29,9 +29,39 @@ func (w *Wallet) NewAddress() (btcutil.Address, error) {
 	return maddr.Address(), nil
 }
 
+// NewChangePubkey returns a new public key of the internal branch
+func (w *Wallet) NewChangePubkey() (*btcec.PublicKey, error) {
+	mAddr, err := w.newChangeAddress()
+	if err != nil {
+		return nil, err
+	}
+	return (mAddr.(waddrmgr.ManagedPubKeyAddress)).PubKey(), nil
+}
+
+// NewChangeAddress creates a new address of the internal branch
+func (w *Wallet) NewChangeAddress() (btcutil.Address, error) {
+	maddr, err := w.newChangeAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	return maddr.Address(), nil
+}
+
 // newAddress returns a new ManagedAddress
 // NOTE: this function calls NextExternalAddresses to generate a ManagadAdddress.
 func (w *Wallet) newAddress() (waddrmgr.ManagedAddress, error) {
+	return w.nextAddress(false)
+}
+
+// newChangeAddress returns a new ManagedAddress for change
+// NOTE: this function calls NextInternalAddresses to generate a ManagadAdddress.
+func (w *Wallet) newChangeAddress() (waddrmgr.ManagedAddress, error) {
+	return w.nextAddress(true)
+}
+
+// nextAddress returns a new ManagedAddress of the internal or external branch
+func (w *Wallet) nextAddress(internal bool) (waddrmgr.ManagedAddress, error) {
 	scopedMgr, err := w.manager.FetchScopedKeyManager(waddrmgrKeyScope)
 	if err != nil {
 		return nil, err
@@ -42,7 +72,13 @@ func (w *Wallet) newAddress() (waddrmgr.ManagedAddress, error) {
 	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
 		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
 		var e error
-		addrs, e = scopedMgr.NextExternalAddresses(ns, w.account, numAddresses)
+		if internal {
+			addrs, e = scopedMgr.NextInternalAddresses(
+				ns, w.account, numAddresses)
+		} else {
+			addrs, e = scopedMgr.NextExternalAddresses(
+				ns, w.account, numAddresses)
+		}
 		return e
 	})
 	if err != nil {
@@ -51,7 +87,8 @@ func (w *Wallet) newAddress() (waddrmgr.ManagedAddress, error) {
 
 	addr := addrs[0]
 
-	// register address to bitcoind
+	// register address to bitcoind.
+	// change addresses are also needed to list utxos of the wallet.
 	err = w.rpc.ImportAddress(addr.Address().EncodeAddress())
 	if err != nil {
 		return nil, err
@@ -23,6 +23,28 @@ func TestNewPubkey(t *testing.T) {
 	assert.NotNil(t, pub)
 }
 
+// TestNewChangePubkey tests generating a new public key of the internal branch
+func TestNewChangePubkey(t *testing.T) {
+	assert := assert.New(t)
+	w, tearDownFunc := setupWallet(t)
+	defer tearDownFunc()
+
+	rpcc := &rpcmock.Client{}
+	rpcc = mockImportAddress(rpcc, nil)
+	w.SetRPCClient(rpcc)
+
+	pub, err := w.NewChangePubkey()
+	assert.NoError(err)
+
+	mpaddr, err := w.managedPubKeyAddressFromPubkey(pub)
+	assert.NoError(err)
+	assert.True(mpaddr.Internal())
+
+	addr, err := w.NewChangeAddress()
+	assert.NoError(err)
+	assert.NotEqual(mpaddr.Address().EncodeAddress(), addr.EncodeAddress())
+}
+
 func mockImportAddress(c *rpcmock.Client, err error) *rpcmock.Client {
 	c.On("ImportAddress",
 		mock.AnythingOfType("string"),
@@ -0,0 +1,128 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/dgarage/dlc/pkg/wallet"
+)
+
+// lockedUtxosNamespaceKey is a bucket key of utxos reserved for contracts.
+// Each entry maps an outpoint to the unix time when the lock expires.
+var lockedUtxosNamespaceKey = []byte("lockedutxos")
+
+// createLockedUtxosBucket creates the bucket of locked utxos if not exists
+func createLockedUtxosBucket(db walletdb.DB) error {
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		if tx.ReadWriteBucket(lockedUtxosNamespaceKey) != nil {
+			return nil
+		}
+		_, err := tx.CreateTopLevelBucket(lockedUtxosNamespaceKey)
+		return err
+	})
+}
+
+// outPointKey serializes an outpoint as txid || vout
+func outPointKey(op wire.OutPoint) []byte {
+	k := make([]byte, chainhash.HashSize+4)
+	copy(k, op.Hash[:])
+	binary.BigEndian.PutUint32(k[chainhash.HashSize:], op.Index)
+	return k
+}
+
+// LockUnspent is an implementation of Wallet.LockUnspent.
+// It fails without locking any utxos if one of them is already locked,
+// so that two contracts selecting the same utxo concurrently
+// can't both reserve it.
+func (w *Wallet) LockUnspent(ops []wire.OutPoint, expiry time.Time) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(expiry.Unix()))
+	now := time.Now()
+
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		b := tx.ReadWriteBucket(lockedUtxosNamespaceKey)
+		for _, op := range ops {
+			k := outPointKey(op)
+			if old := b.Get(k); old != nil && now.Before(lockExpiry(old)) {
+				return fmt.Errorf("utxo is already locked. outpoint: %v", op)
+			}
+			if err := b.Put(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockExpiry decodes the expiry of a lock
+func lockExpiry(v []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(v)), 0)
+}
+
+// UnlockUnspent is an implementation of Wallet.UnlockUnspent
+func (w *Wallet) UnlockUnspent(ops []wire.OutPoint) error {
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		b := tx.ReadWriteBucket(lockedUtxosNamespaceKey)
+		for _, op := range ops {
+			if err := b.Delete(outPointKey(op)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockedOutPoints returns keys of outpoints locked at a given time.
+// Expired locks are removed.
+func (w *Wallet) lockedOutPoints(now time.Time) (map[string]bool, error) {
+	locked := make(map[string]bool)
+	err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		b := tx.ReadWriteBucket(lockedUtxosNamespaceKey)
+
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if now.Before(lockExpiry(v)) {
+				locked[string(k)] = true
+			} else {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return locked, err
+}
+
+// filterLocked removes locked utxos
+func (w *Wallet) filterLocked(utxos []wallet.Utxo) ([]wallet.Utxo, error) {
+	locked, err := w.lockedOutPoints(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var unlocked []wallet.Utxo
+	for _, utxo := range utxos {
+		txid, err := chainhash.NewHashFromStr(utxo.TxID)
+		if err != nil {
+			return nil, err
+		}
+		k := outPointKey(*wire.NewOutPoint(txid, utxo.Vout))
+		if !locked[string(k)] {
+			unlocked = append(unlocked, utxo)
+		}
+	}
+	return unlocked, nil
+}
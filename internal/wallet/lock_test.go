@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dgarage/dlc/pkg/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockUnspent(t *testing.T) {
+	assert := assert.New(t)
+	db, tearDownFunc := setupDB(t)
+	defer tearDownFunc()
+
+	err := createLockedUtxosBucket(db)
+	assert.NoError(err)
+	w := &Wallet{db: db}
+
+	txid := "5ac5e5ed25e50eb0a3c0b8d6f1e8d2c51a7c3c9ad17c2e6b4f8e1d9c0b7a6f51"
+	hash, _ := chainhash.NewHashFromStr(txid)
+	utxos := []wallet.Utxo{
+		{TxID: txid, Vout: 0},
+		{TxID: txid, Vout: 1},
+		{TxID: txid, Vout: 2},
+	}
+	op0 := *wire.NewOutPoint(hash, 0)
+	op1 := *wire.NewOutPoint(hash, 1)
+
+	// lock
+	err = w.LockUnspent([]wire.OutPoint{op0}, time.Now().Add(time.Hour))
+	assert.NoError(err)
+	err = w.LockUnspent([]wire.OutPoint{op1}, time.Now().Add(-time.Second))
+	assert.NoError(err)
+
+	unlocked, err := w.filterLocked(utxos)
+	assert.NoError(err)
+	assert.Equal([]wallet.Utxo{utxos[1], utxos[2]}, unlocked,
+		"expired lock shouldn't reserve a utxo")
+
+	// unlock
+	err = w.UnlockUnspent([]wire.OutPoint{op0})
+	assert.NoError(err)
+
+	unlocked, err = w.filterLocked(utxos)
+	assert.NoError(err)
+	assert.Equal(utxos, unlocked)
+}
+
+func TestLockUnspentLocked(t *testing.T) {
+	assert := assert.New(t)
+	db, tearDownFunc := setupDB(t)
+	defer tearDownFunc()
+
+	err := createLockedUtxosBucket(db)
+	assert.NoError(err)
+	w := &Wallet{db: db}
+
+	txid := "5ac5e5ed25e50eb0a3c0b8d6f1e8d2c51a7c3c9ad17c2e6b4f8e1d9c0b7a6f51"
+	hash, _ := chainhash.NewHashFromStr(txid)
+	utxos := []wallet.Utxo{{TxID: txid, Vout: 0}, {TxID: txid, Vout: 1}}
+	op0 := *wire.NewOutPoint(hash, 0)
+	op1 := *wire.NewOutPoint(hash, 1)
+
+	expiry := time.Now().Add(time.Hour)
+	err = w.LockUnspent([]wire.OutPoint{op0}, expiry)
+	assert.NoError(err)
+
+	// another contract can't lock the same utxo
+	err = w.LockUnspent([]wire.OutPoint{op1, op0}, expiry)
+	assert.Error(err)
+
+	// nothing is locked by the failed call
+	unlocked, err := w.filterLocked(utxos)
+	assert.NoError(err)
+	assert.Equal([]wallet.Utxo{utxos[1]}, unlocked)
+
+	// an expired lock can be taken over
+	err = w.LockUnspent([]wire.OutPoint{op1}, time.Now().Add(-time.Second))
+	assert.NoError(err)
+	err = w.LockUnspent([]wire.OutPoint{op1}, expiry)
+	assert.NoError(err)
+}
@@ -2,7 +2,6 @@ package wallet
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -41,7 +40,7 @@ func (w *Wallet) ListUnspent() (utxos []wallet.Utxo, err error) {
 
 // SelectUnspent is an implementation of Wallet.SelectUnspent
 func (w *Wallet) SelectUnspent(
-	amt, feePerTxIn, feePerTxOut btcutil.Amount,
+	selector wallet.CoinSelector, amt, feePerTxIn, feePerTxOut btcutil.Amount,
 ) (utxos []wallet.Utxo, change btcutil.Amount, err error) {
 	var utxosAll []wallet.Utxo
 	utxosAll, err = w.ListUnspent()
@@ -49,30 +48,18 @@ func (w *Wallet) SelectUnspent(
 		return
 	}
 
-	var total btcutil.Amount
-	var fee btcutil.Amount
-	var utxoAmt btcutil.Amount
-	for _, utxo := range utxosAll {
-		utxoAmt, err = btcutil.NewAmount(utxo.Amount)
-		if err != nil {
-			return
-		}
-		total += utxoAmt
-		fee += feePerTxIn
-		utxos = append(utxos, utxo)
-		if amt+fee == total {
-			return
-		} else if amt+fee < total {
-			change = total - (amt + fee)
-			fee += feePerTxOut
-			if amt+fee <= total {
-				return
-			}
-		}
+	// skip utxos reserved for other contracts
+	utxosAll, err = w.filterLocked(utxosAll)
+	if err != nil {
+		return
 	}
 
-	err = fmt.Errorf("Not enough utxos")
-	return utxos, change, err
+	s, err := wallet.SelectCoins(
+		selector, utxosAll, amt, feePerTxIn, feePerTxOut)
+	if err != nil {
+		return
+	}
+	return s.Utxos, s.Change, nil
 }
 
 // UtxoByTxIn finds utxo by txin
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -192,6 +193,11 @@ func open(
 		return nil, err
 	}
 
+	err = createLockedUtxosBucket(db)
+	if err != nil {
+		return nil, err
+	}
+
 	w := &Wallet{
 		params:           params,
 		publicPassphrase: pubPass,
@@ -214,6 +220,11 @@ func (w *Wallet) SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
 	return w.rpc.SendRawTransaction(tx, false)
 }
 
+// GetTxOut delegates to RPC client including the mempool
+func (w *Wallet) GetTxOut(op wire.OutPoint) (*btcjson.GetTxOutResult, error) {
+	return w.rpc.GetTxOut(&op.Hash, op.Index, true)
+}
+
 // Unlock unlocks address manager with a given private pass phrase
 func (w *Wallet) Unlock(privPass []byte) error {
 	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
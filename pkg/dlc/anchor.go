@@ -0,0 +1,123 @@
+package dlc
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// anchorTxOutSize is size of an anchor txout (p2wpkh)
+const anchorTxOutSize = int64(31)
+
+// hasAnchors checks if redeem txs have anchor txouts
+func (d *DLC) hasAnchors() bool {
+	return d.Conds.AnchorAmt > 0
+}
+
+// anchorCost returns the amount that each party funds for its anchor txout
+// including the redeem tx fee for the txout
+func (d *DLC) anchorCost() btcutil.Amount {
+	if !d.hasAnchors() {
+		return 0
+	}
+	return d.Conds.AnchorAmt + d.redeemTxFee(anchorTxOutSize)
+}
+
+// AnchorTxOut returns an anchor txout owned by a given party,
+// which the party spends to bump the fee of a redeem tx.
+// It's sent to the fund pubkey so that the party's wallet can spend it.
+func (d *DLC) AnchorTxOut(p Contractor) (*wire.TxOut, error) {
+	return d.fundPubkeyTxOut(p, d.Conds.AnchorAmt)
+}
+
+// addAnchorTxOuts adds anchor txouts of both parties to a redeem tx.
+// They're always the last txouts in the order of first party and second party.
+func (d *DLC) addAnchorTxOuts(tx *wire.MsgTx) error {
+	if !d.hasAnchors() {
+		return nil
+	}
+	for _, p := range []Contractor{FirstParty, SecondParty} {
+		txout, err := d.AnchorTxOut(p)
+		if err != nil {
+			return err
+		}
+		tx.AddTxOut(txout)
+	}
+	return nil
+}
+
+// anchorTxOutAt returns the index of the party's anchor txout in a redeem tx
+func (d *DLC) anchorTxOutAt(p Contractor, tx *wire.MsgTx) (int, error) {
+	if !d.hasAnchors() {
+		return 0, errors.New("redeem txs don't have anchor txouts")
+	}
+
+	idx := len(tx.TxOut) - 2 + int(p)
+	anchor, err := d.AnchorTxOut(p)
+	if err != nil {
+		return 0, err
+	}
+	if idx < 0 ||
+		tx.TxOut[idx].Value != anchor.Value ||
+		string(tx.TxOut[idx].PkScript) != string(anchor.PkScript) {
+		return 0, errors.New("anchor txout isn't found")
+	}
+	return idx, nil
+}
+
+// redeemTxFeePaid returns the fee that a redeem tx pays
+func (d *DLC) redeemTxFeePaid(tx *wire.MsgTx) (btcutil.Amount, error) {
+	fundtx, err := d.FundTx()
+	if err != nil {
+		return 0, err
+	}
+
+	idx, err := d.fundTxOutAt()
+	if err != nil {
+		return 0, err
+	}
+
+	fee := btcutil.Amount(fundtx.TxOut[idx].Value)
+	for _, txout := range tx.TxOut {
+		fee -= btcutil.Amount(txout.Value)
+	}
+	return fee, nil
+}
+
+// AnchorCPFPTx constructs a child tx that spends the party's anchor txout
+// of a given redeem tx (CET or refund tx) and wallet utxos,
+// so that the redeem tx and the child are mined at a target feerate.
+// Wallet utxos added to the child are reserved for a while to be broadcast.
+func (b *Builder) AnchorCPFPTx(
+	parent *wire.MsgTx, feerate btcutil.Amount) (*wire.MsgTx, error) {
+	anchorAt, err := b.dlc.anchorTxOutAt(b.party, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	parentFee, err := b.dlc.redeemTxFeePaid(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := b.dlc.pubs[b.party]
+	tx, _, err := b.cpfpTx(parent, anchorAt, pub, parentFee, feerate)
+	return tx, err
+}
+
+// SendAnchorCPFPTx sends a child tx bumping the fee of a redeem tx,
+// which must have been sent already
+func (b *Builder) SendAnchorCPFPTx(
+	parent *wire.MsgTx, feerate btcutil.Amount) error {
+	tx, err := b.AnchorCPFPTx(parent, feerate)
+	if err != nil {
+		return err
+	}
+
+	if _, err = b.wallet.SendRawTransaction(tx); err != nil {
+		_ = b.releaseCPFPTxIns(tx)
+		return err
+	}
+	return nil
+}
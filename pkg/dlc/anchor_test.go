@@ -0,0 +1,89 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testAnchorAmt = btcutil.Amount(1000)
+
+func setupDLCRefundWithAnchors() (b1, b2 *Builder, d *DLC) {
+	b1, b2, d = setupDLCRefund()
+	d.Conds.AnchorAmt = testAnchorAmt
+	return b1, b2, d
+}
+
+func TestRedeemTxsWithAnchors(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCRefundWithAnchors()
+
+	refundtx, err := d.RefundTx()
+	assert.NoError(err)
+	assert.Len(refundtx.TxOut, 4) // 2 for parties and 2 for anchors
+	for _, p := range []Contractor{FirstParty, SecondParty} {
+		idx, err := d.anchorTxOutAt(p, refundtx)
+		assert.NoError(err)
+		assert.Equal(2+int(p), idx)
+		assert.Equal(int64(testAnchorAmt), refundtx.TxOut[idx].Value)
+	}
+
+	// the fund txout pays for anchors
+	fee, err := d.redeemTxFeePaid(refundtx)
+	assert.NoError(err)
+	assert.Equal(d.redeemTxFee(cetxSize+2*anchorTxOutSize), fee)
+
+	// CET
+	_, C := test.RandKeys()
+	d.oracleReqs.commitments[0] = C
+	cetx, err := d.ContractExecutionTx(b1.party, d.Conds.Deals[0], 0)
+	assert.NoError(err)
+	assert.Len(cetx.TxOut, 4)
+	_, err = d.anchorTxOutAt(SecondParty, cetx)
+	assert.NoError(err)
+}
+
+func TestAnchorCPFPTx(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCRefundWithAnchors()
+	refundtx, err := d.SignedRefundTx()
+	assert.NoError(err)
+
+	// a wallet utxo is selected with change of the dust limit
+	w := b1.wallet.(*walletmock.Wallet)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), []int{1},
+	).Return([]wire.TxWitness{{}}, nil)
+
+	tx, err := b1.AnchorCPFPTx(refundtx, 20)
+	assert.NoError(err)
+	assert.Len(tx.TxIn, 2)
+	assert.Len(tx.TxOut, 1)
+	assert.Equal(int64(2*DustLimit), tx.TxOut[0].Value)
+
+	// the child spends the anchor of the first party
+	txid := refundtx.TxHash()
+	assert.Equal(*wire.NewOutPoint(&txid, 2), tx.TxIn[0].PreviousOutPoint)
+	anchor := refundtx.TxOut[2]
+	err = test.ExecuteScript(anchor.PkScript, tx, anchor.Value)
+	assert.NoError(err)
+
+	// no need to bump if the refund tx pays enough
+	_, err = b1.AnchorCPFPTx(refundtx, d.Conds.RedeemFeerate)
+	assert.Error(err)
+}
+
+func TestAnchorCPFPTxWithoutAnchors(t *testing.T) {
+	b1, _, d := setupDLCRefund()
+	refundtx, _ := d.SignedRefundTx()
+
+	_, err := b1.AnchorCPFPTx(refundtx, 20)
+	assert.Error(t, err)
+}
@@ -0,0 +1,374 @@
+package dlc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dgarage/dlc/pkg/wallet"
+)
+
+// FundBatch is a set of contracts funded by one fund tx.
+// A party opening many contracts can pay the fee of the tx base only once
+// and fund all of them with the same utxos.
+//
+// Txins and txouts of all contracts are ordered by serial ids
+// and each contract's CETs and refund tx spend its own fund txout.
+// The party funding the batch describes the fund tx by Describe
+// and sends the description to the counterparties of the contracts.
+type FundBatch struct {
+	dlcs []*DLC
+}
+
+// BatchDescription describes the fund tx of a batch in plain data
+// so that each party of the contracts can rebuild the fund tx.
+// A contract out of batches is described as a batch of itself.
+type BatchDescription struct {
+	TxIns      []BatchTxIn  // txins of all contracts
+	ChangeOuts []BatchTxOut // change txouts of all contracts
+	FundOuts   []BatchTxOut // fund txouts of all contracts
+}
+
+// BatchTxIn is a fund txin in a batch
+type BatchTxIn struct {
+	SerialID uint64
+	OutPoint wire.OutPoint
+	Sequence uint32
+}
+
+// BatchTxOut is a fund txout in a batch
+type BatchTxOut struct {
+	SerialID uint64
+	Value    int64
+	PkScript []byte
+}
+
+func (out BatchTxOut) equal(o BatchTxOut) bool {
+	return out.SerialID == o.SerialID && out.Value == o.Value &&
+		bytes.Equal(out.PkScript, o.PkScript)
+}
+
+// NewFundBatch creates a batch of given contracts.
+// The contracts must be in negotiation and have the same fund feerate.
+// Their fund txouts must have unique serial ids.
+// The contracts can't construct the fund tx until the batch is described.
+func NewFundBatch(dlcs []*DLC) (*FundBatch, error) {
+	if len(dlcs) == 0 {
+		return nil, errors.New("no contracts to batch")
+	}
+
+	feerate := dlcs[0].Conds.FundFeerate
+	ids := make(map[uint64]bool)
+	for i, d := range dlcs {
+		if ids[d.fundTxOutSerialID] {
+			return nil, fmt.Errorf(
+				"contract %d has a duplicate fund txout serial id", i)
+		}
+		ids[d.fundTxOutSerialID] = true
+
+		if d.batch != nil {
+			return nil, fmt.Errorf("contract %d is already batched", i)
+		}
+		if d.state != ContractNegotiating {
+			return nil, fmt.Errorf("contract %d is %s", i, d.state)
+		}
+		if d.Conds.FundFeerate != feerate {
+			return nil, fmt.Errorf(
+				"contract %d has a different fund feerate", i)
+		}
+	}
+
+	fb := &FundBatch{dlcs: dlcs}
+	for _, d := range dlcs {
+		d.batch = &BatchDescription{}
+	}
+	return fb, nil
+}
+
+// Describe describes the fund tx of the batch
+// after the txins and change txouts of all parties are prepared.
+// The description is set to the contracts and has to be sent to
+// the counterparties, which accept it by Builder.AcceptBatchDescription.
+func (fb *FundBatch) Describe(builders []*Builder) (*BatchDescription, error) {
+	if err := fb.checkBuilders(builders); err != nil {
+		return nil, err
+	}
+	for _, b := range builders {
+		if err := b.checkNegotiating(); err != nil {
+			return nil, err
+		}
+	}
+
+	desc, err := describeFundTx(fb.dlcs)
+	if err != nil {
+		return nil, err
+	}
+	if err = desc.checkSerialIDs(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range builders {
+		b.dlc.batch = desc.copy()
+		b.cpTxInsVerified = false
+	}
+	return desc.copy(), nil
+}
+
+// FundTx constructs the fund tx of the contracts
+// with the witnesses of all contracts' txins
+func (fb *FundBatch) FundTx() (*wire.MsgTx, error) {
+	tx, err := fb.dlcs[0].FundTx()
+	if err != nil {
+		return nil, err
+	}
+	setFundTxWitnesses(tx, fb.dlcs)
+	return tx, nil
+}
+
+// checkBuilders checks if given builders are of the contracts in the batch
+// and owned by the same party
+func (fb *FundBatch) checkBuilders(builders []*Builder) error {
+	if len(builders) != len(fb.dlcs) {
+		return fmt.Errorf(
+			"number of builders doesn't match. builders: %d, contracts: %d",
+			len(builders), len(fb.dlcs))
+	}
+	for i, b := range builders {
+		if b.dlc != fb.dlcs[i] {
+			return fmt.Errorf("builder %d isn't of contract %d", i, i)
+		}
+		if b.party != builders[0].party {
+			return fmt.Errorf("builder %d is of a different party", i)
+		}
+	}
+	return nil
+}
+
+// PrepareFundTxIns prepares utxos of a party funding all the contracts.
+// The builders are the party's ones of the contracts in the same order.
+// The txins and the change are set to the first contract,
+// and the party has no txins in the others.
+func (fb *FundBatch) PrepareFundTxIns(
+	builders []*Builder, selector wallet.CoinSelector) error {
+	if err := fb.checkBuilders(builders); err != nil {
+		return err
+	}
+
+	b0 := builders[0]
+	amt := b0.dlc.fundTxFeeBase(len(builders))
+	for _, b := range builders {
+		amt += b.dlc.Conds.FundAmts[b.party] +
+			b.dlc.redeemTxFee(cetxSize) + b.dlc.anchorCost()
+	}
+
+	for _, b := range builders[1:] {
+		if err := b.ReleaseFundTxIns(); err != nil {
+			return err
+		}
+	}
+	return b0.prepareFundTxIns(selector, amt)
+}
+
+// SignFundTx signs the fund tx by a party and
+// returns witnesses for the party's txins of each contract
+func (fb *FundBatch) SignFundTx(
+	builders []*Builder) ([][]wire.TxWitness, error) {
+	if err := fb.checkBuilders(builders); err != nil {
+		return nil, err
+	}
+	for _, b := range builders {
+		if err := b.dlc.canTransit(ContractFundSigned); err != nil {
+			return nil, err
+		}
+	}
+
+	var wits [][]wire.TxWitness
+	for _, b := range builders {
+		w, err := b.SignFundTx()
+		if err != nil {
+			return nil, err
+		}
+		wits = append(wits, w)
+	}
+	return wits, nil
+}
+
+// SendFundTx sends the fund tx of the contracts to the network
+func (fb *FundBatch) SendFundTx(builders []*Builder) error {
+	if err := fb.checkBuilders(builders); err != nil {
+		return err
+	}
+	for _, b := range builders {
+		if err := b.dlc.canTransit(ContractFundSent); err != nil {
+			return err
+		}
+	}
+
+	tx, err := fb.FundTx()
+	if err != nil {
+		return err
+	}
+
+	_, err = builders[0].wallet.SendRawTransaction(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range builders {
+		if err := b.dlc.transit(ContractFundSent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptBatchDescription accepts the description of the batch
+// funding the contract, which the counterparty funding the batch sends.
+// It must have the contract's txins and change txouts,
+// and the fund txout of the contract's conditions and pubkeys.
+func (b *Builder) AcceptBatchDescription(desc *BatchDescription) error {
+	if err := b.checkNegotiating(); err != nil {
+		return err
+	}
+
+	desc = desc.copy()
+	if err := desc.checkSerialIDs(); err != nil {
+		return err
+	}
+	if err := b.dlc.checkBatch(desc); err != nil {
+		return err
+	}
+
+	b.dlc.batch = desc
+	b.cpTxInsVerified = false
+	return nil
+}
+
+// describeFundTx describes the fund tx of given contracts
+func describeFundTx(dlcs []*DLC) (*BatchDescription, error) {
+	desc := &BatchDescription{}
+	for _, d := range dlcs {
+		fout, err := d.fundTxOutForRedeemTx()
+		if err != nil {
+			return nil, err
+		}
+		desc.FundOuts = append(desc.FundOuts, BatchTxOut{
+			SerialID: d.fundTxOutSerialID,
+			Value:    fout.Value,
+			PkScript: fout.PkScript,
+		})
+
+		for _, p := range []Contractor{FirstParty, SecondParty} {
+			for i, txin := range d.fundTxReqs.txIns[p] {
+				desc.TxIns = append(desc.TxIns, BatchTxIn{
+					SerialID: d.fundTxReqs.txInSerialID(p, i),
+					OutPoint: txin.PreviousOutPoint,
+					Sequence: txin.Sequence,
+				})
+			}
+			if txout := d.fundTxReqs.txOut[p]; txout != nil {
+				desc.ChangeOuts = append(desc.ChangeOuts, BatchTxOut{
+					SerialID: d.fundTxReqs.txOutSerialID[p],
+					Value:    txout.Value,
+					PkScript: txout.PkScript,
+				})
+			}
+		}
+	}
+	return desc, nil
+}
+
+// checkBatch checks if a batch description has the fund txout
+// derived from the contract's own conditions and pubkeys,
+// and the txins and change txouts of both parties of the contract
+func (d *DLC) checkBatch(desc *BatchDescription) error {
+	own, err := describeFundTx([]*DLC{d})
+	if err != nil {
+		return err
+	}
+
+	for _, out := range own.FundOuts {
+		if !hasTxOut(desc.FundOuts, out) {
+			return errors.New("batch doesn't have the fund txout of the contract")
+		}
+	}
+	for _, out := range own.ChangeOuts {
+		if !hasTxOut(desc.ChangeOuts, out) {
+			return errors.New("batch doesn't have a change txout of the contract")
+		}
+	}
+	for _, in := range own.TxIns {
+		if !hasTxIn(desc.TxIns, in) {
+			return fmt.Errorf(
+				"batch doesn't have fund txin %v of the contract", in.OutPoint)
+		}
+	}
+	return nil
+}
+
+func hasTxIn(ins []BatchTxIn, in BatchTxIn) bool {
+	for _, i := range ins {
+		if i == in {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTxOut(outs []BatchTxOut, out BatchTxOut) bool {
+	for _, o := range outs {
+		if o.equal(out) {
+			return true
+		}
+	}
+	return false
+}
+
+// fundTx constructs the unsigned fund tx described
+func (desc *BatchDescription) fundTx() *wire.MsgTx {
+	tx := wire.NewMsgTx(txVersion)
+	for _, in := range desc.sortedTxIns() {
+		txin := wire.NewTxIn(&in.OutPoint, nil, nil)
+		txin.Sequence = in.Sequence
+		tx.AddTxIn(txin)
+	}
+	for _, out := range desc.sortedTxOuts() {
+		tx.AddTxOut(wire.NewTxOut(out.Value, out.PkScript))
+	}
+	return tx
+}
+
+// copy returns a deep copy of the description
+func (desc *BatchDescription) copy() *BatchDescription {
+	copyOuts := func(outs []BatchTxOut) []BatchTxOut {
+		var copied []BatchTxOut
+		for _, out := range outs {
+			out.PkScript = append([]byte{}, out.PkScript...)
+			copied = append(copied, out)
+		}
+		return copied
+	}
+	return &BatchDescription{
+		TxIns:      append([]BatchTxIn{}, desc.TxIns...),
+		ChangeOuts: copyOuts(desc.ChangeOuts),
+		FundOuts:   copyOuts(desc.FundOuts),
+	}
+}
+
+// setFundTxWitnesses sets the witnesses of the contracts' txins to fund tx
+func setFundTxWitnesses(tx *wire.MsgTx, dlcs []*DLC) {
+	wits := make(map[wire.OutPoint]wire.TxWitness)
+	for _, d := range dlcs {
+		for _, p := range []Contractor{FirstParty, SecondParty} {
+			for _, txin := range d.fundTxReqs.txIns[p] {
+				if txin.Witness != nil {
+					wits[txin.PreviousOutPoint] = txin.Witness
+				}
+			}
+		}
+	}
+	for _, txin := range tx.TxIn {
+		txin.Witness = wits[txin.PreviousOutPoint]
+	}
+}
@@ -0,0 +1,335 @@
+package dlc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setupFundBatch sets up contracts between a first party and second parties,
+// which the first party funds in a batch.
+// Each party builds its own contracts sharing no objects with the others,
+// and the batch is sent to the second parties as a plain description.
+func setupFundBatch(
+	t *testing.T, n int) (fb *FundBatch, bs1, bs2 []*Builder) {
+	conds := newTestConditions()
+	conds.RefundLockTime = testLockTime
+
+	testVout++
+	w1 := mockExactSelectUnspent(setupTestWallet(), testVout)
+	var dlcs []*DLC
+	for i := 0; i < n; i++ {
+		b1 := NewBuilder(FirstParty, w1, conds)
+		b1.PreparePubkey()
+		bs1 = append(bs1, b1)
+		dlcs = append(dlcs, b1.DLC())
+	}
+
+	fb, err := NewFundBatch(dlcs)
+	assert.NoError(t, err)
+	err = fb.PrepareFundTxIns(bs1, nil)
+	assert.NoError(t, err)
+
+	for _, b1 := range bs1 {
+		conds2 := newTestConditions()
+		conds2.FixingTime = conds.FixingTime
+		conds2.RefundLockTime = testLockTime
+
+		testVout++
+		w2 := mockExactSelectUnspent(setupTestWallet(), testVout)
+		b2 := NewBuilder(SecondParty, w2, conds2)
+		b2.PreparePubkey()
+		b2.PrepareFundTxIns(nil)
+
+		assert.NoError(t, b1.CopyReqsFromCounterparty(b2.DLC()))
+		assert.NoError(t, b2.CopyReqsFromCounterparty(b1.DLC()))
+		bs2 = append(bs2, b2)
+	}
+
+	desc, err := fb.Describe(bs1)
+	assert.NoError(t, err)
+	desc = sendBatchDescription(t, desc)
+	for _, b2 := range bs2 {
+		assert.NoError(t, b2.AcceptBatchDescription(desc))
+	}
+
+	return fb, bs1, bs2
+}
+
+// sendBatchDescription passes a batch description through JSON
+// as if it's sent to a counterparty
+func sendBatchDescription(
+	t *testing.T, desc *BatchDescription) *BatchDescription {
+	data, err := json.Marshal(desc)
+	assert.NoError(t, err)
+	received := &BatchDescription{}
+	assert.NoError(t, json.Unmarshal(data, received))
+	return received
+}
+
+func TestFundBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	fb, bs1, bs2 := setupFundBatch(t, 2)
+
+	fundtx, err := fb.FundTx()
+	assert.NoError(err)
+	assert.Len(fundtx.TxIn, 3)  // the first party's and second parties'
+	assert.Len(fundtx.TxOut, 5) // 2 fund txouts and 3 changes
+
+	// the first party funds all contracts with the txins of the first one
+	assert.Len(bs1[0].dlc.fundTxReqs.txIns[FirstParty], 1)
+	assert.Empty(bs1[1].dlc.fundTxReqs.txIns[FirstParty])
+
+	for i, b2 := range bs2 {
+		d := b2.DLC()
+		idx, err := d.fundTxOutAt()
+		assert.NoError(err)
+		idx1, _ := bs1[i].dlc.fundTxOutAt()
+		assert.Equal(idx1, idx)
+
+		// the second party rebuilds the same fund tx
+		tx, err := d.FundTx()
+		assert.NoError(err)
+		assert.Equal(fundtx.TxHash(), tx.TxHash())
+
+		fout, _ := d.fundTxOutForRedeemTx()
+		assert.Equal(fout, fundtx.TxOut[idx])
+
+		// refund tx spends the contract's own fund txout
+		rs1, err := bs1[i].SignRefundTx()
+		assert.NoError(err)
+		rs2, err := b2.SignRefundTx()
+		assert.NoError(err)
+		assert.NoError(b2.AcceptRefundTxSign(rs1))
+		assert.NoError(bs1[i].AcceptRefundTxSign(rs2))
+
+		refundtx, err := d.SignedRefundTx()
+		assert.NoError(err)
+		txid := fundtx.TxHash()
+		assert.Equal(
+			*wire.NewOutPoint(&txid, uint32(idx)),
+			refundtx.TxIn[0].PreviousOutPoint)
+		err = test.ExecuteScript(fout.PkScript, refundtx, fout.Value)
+		assert.NoError(err)
+	}
+}
+
+func TestFundBatchFeerate(t *testing.T) {
+	assert := assert.New(t)
+
+	n := 3
+	fb, bs1, bs2 := setupFundBatch(t, n)
+	d := bs1[0].dlc
+
+	fundtx, err := fb.FundTx()
+	assert.NoError(err)
+	// p2wpkh witnesses of a signature and a pubkey
+	for _, txin := range fundtx.TxIn {
+		txin.Witness = wire.TxWitness{make([]byte, 72), make([]byte, 33)}
+	}
+
+	// each txin spends a 10 BTC utxo
+	fee := (10 * oneBTC).MulF64(float64(len(fundtx.TxIn)))
+	for _, txout := range fundtx.TxOut {
+		fee -= btcutil.Amount(txout.Value)
+	}
+	// parties pay the redeem tx fee that fund txouts don't include as well
+	surplus := d.redeemTxFee(cetxSize).MulF64(float64(n))
+	assert.Equal(d.fundTxFee()+surplus, fee)
+	for _, b2 := range bs2 {
+		assert.Equal(d.fundTxFee(), b2.dlc.fundTxFee())
+	}
+
+	weight := 3*fundtx.SerializeSizeStripped() + fundtx.SerializeSize()
+	vsize := (weight + 3) / 4
+	assert.True(d.fundTxFee() >= d.Conds.FundFeerate.MulF64(float64(vsize)))
+}
+
+func TestFundBatchSignAndSend(t *testing.T) {
+	assert := assert.New(t)
+
+	fb, bs1, bs2 := setupFundBatch(t, 2)
+
+	// second parties send witnesses of their txins to the first party
+	for i, b2 := range bs2 {
+		idxs, _ := b2.fundTxInAt()
+		w2 := b2.wallet.(*walletmock.Wallet)
+		w2.On("WitnessSignTxByIdxs",
+			mock.AnythingOfType("*wire.MsgTx"), idxs,
+		).Return([]wire.TxWitness{{{byte(2 + i)}}}, nil)
+		wits, err := b2.SignFundTx()
+		assert.NoError(err)
+		bs1[i].AcceptFundWitnesses(wits)
+	}
+
+	idxs, _ := bs1[0].fundTxInAt()
+	w1 := bs1[0].wallet.(*walletmock.Wallet)
+	w1.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), idxs,
+	).Return([]wire.TxWitness{{{1}}}, nil)
+	var sent *wire.MsgTx
+	w1.On("SendRawTransaction", mock.AnythingOfType("*wire.MsgTx")).
+		Run(func(args mock.Arguments) {
+			sent = args.Get(0).(*wire.MsgTx)
+		}).Return(&chainhash.Hash{}, nil)
+
+	wits, err := fb.SignFundTx(bs1)
+	assert.NoError(err)
+	assert.Len(wits, 2)
+	assert.Len(wits[0], 1)
+	assert.Empty(wits[1])
+	w1.AssertNumberOfCalls(t, "WitnessSignTxByIdxs", 1)
+
+	assert.NoError(fb.SendFundTx(bs1))
+	w1.AssertNumberOfCalls(t, "SendRawTransaction", 1)
+	for _, b1 := range bs1 {
+		assert.Equal(ContractFundSent, b1.dlc.State())
+	}
+
+	// the fund tx has witnesses of all parties
+	assert.Equal(wire.TxWitness{{1}}, sent.TxIn[idxs[0]].Witness)
+	for i, b2 := range bs2 {
+		idxs, _ := b2.fundTxInAt()
+		assert.Equal(wire.TxWitness{{byte(2 + i)}}, sent.TxIn[idxs[0]].Witness)
+	}
+}
+
+func TestAcceptBatchDescriptionInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	fb, bs1, bs2 := setupFundBatch(t, 2)
+	b2 := bs2[1]
+	fundtx, _ := fb.FundTx()
+
+	tests := []struct {
+		name   string
+		modify func(desc *BatchDescription)
+	}{
+		{"fund txout amount", func(desc *BatchDescription) {
+			for i := range desc.FundOuts {
+				desc.FundOuts[i].Value++
+			}
+		}},
+		{"fund txout script", func(desc *BatchDescription) {
+			for i := range desc.FundOuts {
+				desc.FundOuts[i].PkScript[2] ^= 1
+			}
+		}},
+		{"missing txins", func(desc *BatchDescription) {
+			desc.TxIns = nil
+		}},
+		{"missing change", func(desc *BatchDescription) {
+			desc.ChangeOuts = nil
+		}},
+		{"txin serial ids", func(desc *BatchDescription) {
+			for i := range desc.TxIns {
+				desc.TxIns[i].SerialID++
+			}
+		}},
+		{"duplicate serial ids", func(desc *BatchDescription) {
+			desc.TxIns = append(desc.TxIns, desc.TxIns[0])
+		}},
+	}
+
+	for _, test := range tests {
+		desc, err := fb.Describe(bs1)
+		assert.NoError(err)
+		test.modify(desc)
+		err = b2.AcceptBatchDescription(desc)
+		assert.Error(err, test.name)
+	}
+
+	// the accepted description is kept
+	tx, err := b2.DLC().FundTx()
+	assert.NoError(err)
+	assert.Equal(fundtx.TxHash(), tx.TxHash())
+
+	// the batch can't change after negotiation
+	desc, _ := fb.Describe(bs1)
+	b2.dlc.state = ContractFundSigned
+	assert.Error(b2.AcceptBatchDescription(desc))
+}
+
+func TestFundTxLookupNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, bs2 := setupFundBatch(t, 2)
+	b2 := bs2[1]
+	w2 := b2.wallet.(*walletmock.Wallet)
+	w2.On("WitnessSignTxByIdxs", mock.Anything, mock.Anything).
+		Return([]wire.TxWitness{}, nil)
+
+	// the batch loses the party's txins and the contract's fund txout
+	b2.dlc.batch.TxIns = nil
+	b2.dlc.batch.FundOuts = nil
+
+	_, err := b2.fundTxInAt()
+	assert.Error(err)
+	_, err = b2.dlc.fundTxOutAt()
+	assert.Error(err)
+
+	_, err = b2.SignFundTx()
+	assert.Error(err)
+	_, err = b2.SignRefundTx()
+	assert.Error(err)
+	w2.AssertNotCalled(t, "WitnessSignTxByIdxs", mock.Anything, mock.Anything)
+	assert.Equal(ContractNegotiating, b2.dlc.State())
+}
+
+func TestVerifyBatchFundTxInsDuplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	fb, bs1, bs2 := setupFundBatch(t, 2)
+	assert.NoError(bs2[1].VerifyCounterpartyFundTxIns())
+
+	// a txin spends the outpoint of another second party
+	desc, _ := fb.Describe(bs1)
+	op := bs2[0].dlc.fundTxReqs.txIns[SecondParty][0].PreviousOutPoint
+	desc.TxIns = append(desc.TxIns, BatchTxIn{SerialID: 1, OutPoint: op})
+	assert.NoError(bs2[1].AcceptBatchDescription(desc))
+	assert.Error(bs2[1].VerifyCounterpartyFundTxIns())
+
+	// a txin spends our outpoint
+	desc, _ = fb.Describe(bs1)
+	op = bs2[1].dlc.fundTxReqs.txIns[SecondParty][0].PreviousOutPoint
+	desc.TxIns = append(desc.TxIns, BatchTxIn{SerialID: 1, OutPoint: op})
+	assert.NoError(bs2[1].AcceptBatchDescription(desc))
+	assert.Error(bs2[1].VerifyCounterpartyFundTxIns())
+
+	// a second party spends the outpoint of the first party
+	op = bs1[0].dlc.fundTxReqs.txIns[FirstParty][0].PreviousOutPoint
+	bs1[1].dlc.fundTxReqs.txIns[SecondParty] = []*wire.TxIn{
+		wire.NewTxIn(&op, nil, nil)}
+	_, err := fb.Describe(bs1)
+	assert.NoError(err)
+	assert.Error(bs1[1].VerifyCounterpartyFundTxIns())
+}
+
+func TestNewFundBatchInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewFundBatch(nil)
+	assert.Error(err)
+
+	// different fund feerates
+	d1 := newDLC(newTestConditions())
+	conds := newTestConditions()
+	conds.FundFeerate++
+	d2 := newDLC(conds)
+	_, err = NewFundBatch([]*DLC{d1, d2})
+	assert.Error(err)
+
+	// already batched
+	_, err = NewFundBatch([]*DLC{d1})
+	assert.NoError(err)
+	_, err = NewFundBatch([]*DLC{d1})
+	assert.Error(err)
+}
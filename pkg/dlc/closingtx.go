@@ -17,8 +17,32 @@ const closingTxSize = 216
 const closingTxOutAt = 0
 
 // ClosingTx constructs a tx that redeems a given CET
+// to the party's payout script
 func (d *DLC) ClosingTx(
 	p Contractor, cetx *wire.MsgTx) (*wire.MsgTx, error) {
+	pkScript, err := d.closingScript(p)
+	if err != nil {
+		return nil, err
+	}
+	return d.closingTx(cetx, pkScript)
+}
+
+// closingScript returns the script that a given party's closing tx pays to,
+// which is the party's payout script if it's set.
+// Otherwise it's the script picked when the party prepared its pubkey.
+func (d *DLC) closingScript(p Contractor) ([]byte, error) {
+	if sc, ok := d.payoutScripts[p]; ok {
+		return sc, nil
+	}
+	if sc, ok := d.closingScripts[p]; ok {
+		return sc, nil
+	}
+	return d.fundPubkeyScript(p)
+}
+
+// closingTx constructs a tx that redeems a given CET to a given script
+func (d *DLC) closingTx(
+	cetx *wire.MsgTx, pkScript []byte) (*wire.MsgTx, error) {
 
 	tx := wire.NewMsgTx(txVersion)
 
@@ -35,15 +59,12 @@ func (d *DLC) ClosingTx(
 	fee := d.redeemTxFee(closingTxSize)
 	out := in - fee
 
-	if out <= 0 {
+	// the output can't be moved to the counterparty
+	if isDust(out) {
 		return nil, newNotEnoughFeesError(in, fee)
 	}
 
-	txout, err := d.ClosingTxOut(p, out)
-	if err != nil {
-		return nil, err
-	}
-	tx.AddTxOut(txout)
+	tx.AddTxOut(wire.NewTxOut(int64(out), pkScript))
 
 	return tx, nil
 }
@@ -61,6 +82,10 @@ func (b *Builder) SignedClosingTx(cetx *wire.MsgTx) (*wire.MsgTx, error) {
 		return nil, err
 	}
 
+	if err = checkStandardTx(tx); err != nil {
+		return nil, err
+	}
+
 	wit, err := b.witnessForCEScript(tx, cetx, C)
 	if err != nil {
 		return nil, err
@@ -110,6 +135,10 @@ func genAddSignToPrivkeyFunc(
 
 // ExecuteContract sends CETx and closing tx
 func (b *Builder) ExecuteContract() error {
+	if err := b.dlc.canTransit(ContractClosed); err != nil {
+		return err
+	}
+
 	cetx, err := b.SignedContractExecutionTx()
 	if err != nil {
 		return err
@@ -125,5 +154,8 @@ func (b *Builder) ExecuteContract() error {
 	}
 
 	_, err = b.wallet.SendRawTransaction(cltx)
-	return err
+	if err != nil {
+		return err
+	}
+	return b.dlc.transit(ContractClosed)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/dgarage/dlc/internal/mocks/walletmock"
 	"github.com/dgarage/dlc/internal/oracle"
 	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/schnorr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -70,6 +71,14 @@ func TestSignedClosingTx(t *testing.T) {
 	tx2, err := b2.SignedClosingTx(cetx2)
 	assert.NoError(err)
 
+	// the unsigned closing txs are the same as the signed ones
+	utx1, err := b1.dlc.ClosingTx(FirstParty, cetx1)
+	assert.NoError(err)
+	assert.Equal(tx1.TxHash(), utx1.TxHash())
+	utx2, err := b2.dlc.ClosingTx(SecondParty, cetx2)
+	assert.NoError(err)
+	assert.Equal(tx2.TxHash(), utx2.TxHash())
+
 	// first party can redeem only their tx
 	err = runCEScript(cetx1, tx1)
 	assert.NoError(err)
@@ -84,29 +93,27 @@ func TestSignedClosingTx(t *testing.T) {
 }
 
 func setupContractorsUntilSignExchange() (b1, b2 *Builder) {
-	conds := newTestConditions()
-
 	var damt1, damt2 btcutil.Amount = 1 * btcutil.SatoshiPerBitcoin, 1 * btcutil.SatoshiPerBitcoin
-	msgs := [][]byte{{1}}
-	deal := NewDeal(damt1, damt2, msgs)
-	conds.Deals = []*Deal{deal}
+	deal := NewOutcomeDeal(damt1, damt2, "1")
+	msgs := deal.Msgs
+	conds := newTestConditions(deal)
 
 	// oracle's sign and commitment
 	opriv, C := test.RandKeys()
-	osign := opriv.D.Bytes()
+	osign := schnorr.ScalarBytes(opriv.D)
 	osignset := &oracle.SignSet{Msgs: msgs, Signs: [][]byte{osign}}
 
 	// init first party
 	w1 := setupTestWalletForTestSignedClosingTx(osign)
 	b1 = NewBuilder(FirstParty, w1, conds)
 	b1.PreparePubkey()
-	b1.PrepareFundTxIns()
+	b1.PrepareFundTxIns(nil)
 
 	// init second party
 	w2 := setupTestWalletForTestSignedClosingTx(osign)
 	b2 = NewBuilder(SecondParty, w2, conds)
 	b2.PreparePubkey()
-	b2.PrepareFundTxIns()
+	b2.PrepareFundTxIns(nil)
 
 	// exchange pubkeys
 	b1.CopyReqsFromCounterparty(b2.DLC())
@@ -136,8 +143,10 @@ func setupTestWalletForTestSignedClosingTx(msgSign []byte) *walletmock.Wallet {
 	w := &walletmock.Wallet{}
 	priv, pub := test.RandKeys()
 	w.On("NewPubkey").Return(pub, nil)
+	w.On("NewChangePubkey").Return(pub, nil)
 	w = mockWitnessSignature(w, pub, priv)
-	w = mockSelectUnspent(w, 1, 1, nil)
+	w = mockSelectUnspent(w, 1, DustLimit, nil)
+	w = mockGetTxOut(w, 10*oneBTC, 6)
 	w = mockWitnessSignatureWithCallback(
 		w, pub, priv, genAddSignToPrivkeyFunc(msgSign))
 	return w
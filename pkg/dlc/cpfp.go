@@ -0,0 +1,135 @@
+package dlc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/pkg/script"
+	"github.com/dgarage/dlc/pkg/wallet"
+)
+
+// Tx sizes for fee estimation of child txs (vbytes)
+const cpfpTxBaseSize = int64(11)
+const cpfpTxInSize = int64(68) // p2wpkh txin
+const cpfpTxOutSize = int64(31)
+
+// cpfpLockDuration is how long wallet utxos of a child tx are reserved.
+// They're spent once the child is broadcast, and released if it fails.
+const cpfpLockDuration = 24 * time.Hour
+
+// cpfpTx constructs a signed child tx that spends a p2wpkh txout of a parent
+// owned by a given pubkey of the wallet, so that the parent and the child
+// are mined at a target feerate. Wallet utxos are added if the txout
+// isn't enough for the fee. The child spends the txout at txin 0
+// and sends the rest to a new address of the wallet.
+// The wallet utxos are reserved until the child is broadcast,
+// so they must be released with releaseCPFPTxIns if it isn't.
+// It returns the child and the fee that it pays.
+func (b *Builder) cpfpTx(
+	parent *wire.MsgTx, outAt int, pub *btcec.PublicKey,
+	parentFee, feerate btcutil.Amount,
+) (*wire.MsgTx, btcutil.Amount, error) {
+	txout := parent.TxOut[outAt]
+	in := btcutil.Amount(txout.Value)
+
+	// fee of the child without wallet utxos
+	childSize := cpfpTxBaseSize + cpfpTxInSize + cpfpTxOutSize
+	fee := wallet.ChildFee(
+		wallet.VirtualSize(parent), childSize, parentFee, feerate)
+	if fee <= 0 {
+		return nil, 0, fmt.Errorf(
+			"no need to bump fee. fee paid: %d, fee required: %d",
+			parentFee, parentFee+fee)
+	}
+
+	tx := wire.NewMsgTx(txVersion)
+	txid := parent.TxHash()
+	tx.AddTxIn(wire.NewTxIn(
+		wire.NewOutPoint(&txid, uint32(outAt)), nil, nil))
+
+	// the output gets the dust limit and the change of wallet utxos
+	// unless the txout is enough for the fee
+	out := in - fee
+	if isDust(out) {
+		feePerTxIn := feerate.MulF64(float64(cpfpTxInSize))
+		utxos, change, err := b.wallet.SelectUnspent(
+			nil, fee+DustLimit-in, feePerTxIn, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		txins, err := wallet.UtxosToTxIns(utxos)
+		if err != nil {
+			return nil, 0, err
+		}
+		expiry := time.Now().Add(cpfpLockDuration)
+		if err = b.wallet.LockUnspent(outPoints(txins), expiry); err != nil {
+			return nil, 0, err
+		}
+		for _, txin := range txins {
+			tx.AddTxIn(txin)
+		}
+		out = DustLimit + change
+		fee += feePerTxIn.MulF64(float64(len(txins)))
+	}
+
+	if err := b.signCPFPTx(tx, txout, pub, out); err != nil {
+		_ = b.releaseCPFPTxIns(tx)
+		return nil, 0, err
+	}
+	return tx, fee, nil
+}
+
+// signCPFPTx adds the output of a child tx and signs all its txins
+func (b *Builder) signCPFPTx(tx *wire.MsgTx,
+	txout *wire.TxOut, pub *btcec.PublicKey, out btcutil.Amount) error {
+	outPub, err := b.wallet.NewChangePubkey()
+	if err != nil {
+		return err
+	}
+	pkScript, err := script.P2WPKHpkScript(outPub)
+	if err != nil {
+		return err
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(out), pkScript))
+
+	if err = checkStandardTx(tx); err != nil {
+		return err
+	}
+
+	// witness for the parent's txout
+	in := btcutil.Amount(txout.Value)
+	sign, err := b.wallet.WitnessSignature(tx, 0, in, txout.PkScript, pub)
+	if err != nil {
+		return err
+	}
+	tx.TxIn[0].Witness = wire.TxWitness{sign, pub.SerializeCompressed()}
+
+	// witnesses for wallet utxos
+	if len(tx.TxIn) == 1 {
+		return nil
+	}
+	var idxs []int
+	for i := 1; i < len(tx.TxIn); i++ {
+		idxs = append(idxs, i)
+	}
+	wits, err := b.wallet.WitnessSignTxByIdxs(tx, idxs)
+	if err != nil {
+		return err
+	}
+	for i, wit := range wits {
+		tx.TxIn[idxs[i]].Witness = wit
+	}
+
+	return nil
+}
+
+// releaseCPFPTxIns releases wallet utxos reserved for a child tx
+func (b *Builder) releaseCPFPTxIns(tx *wire.MsgTx) error {
+	if len(tx.TxIn) == 1 {
+		return nil
+	}
+	return b.wallet.UnlockUnspent(outPoints(tx.TxIn[1:]))
+}
@@ -1,17 +1,20 @@
 package dlc
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"reflect"
 
 	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/pkg/oracle"
 )
 
 // Deal contains information about the distributed amounts, commitment messages, and signatures of fixed messages
 type Deal struct {
-	Amts map[Contractor]btcutil.Amount `validate:"required,dive,gte=0"`
-	Msgs [][]byte                      `validate:"required,gt=0"`
+	Amts   map[Contractor]btcutil.Amount `validate:"required,dive,gte=0"`
+	Msgs   [][]byte                      `validate:"required,gt=0,dive,gt=0"` // canonical messages
+	Prefix bool                          // matches fixed messages starting with Msgs
+	Legacy bool                          // Msgs are raw messages of a legacy event
 }
 
 // NewDeal creates a new deal
@@ -25,6 +28,72 @@ func NewDeal(amt1, amt2 btcutil.Amount, msgs [][]byte) *Deal {
 	}
 }
 
+// NewLegacyDeal creates a new deal on raw messages of a legacy event,
+// which the oracle migrated from the deprecated time derivation
+// and attests without the canonical encoding
+func NewLegacyDeal(amt1, amt2 btcutil.Amount, msgs [][]byte) *Deal {
+	deal := NewDeal(amt1, amt2, msgs)
+	deal.Legacy = true
+	return deal
+}
+
+// NewOutcomeDeal creates a new deal on outcomes,
+// which are encoded into canonical messages
+func NewOutcomeDeal(amt1, amt2 btcutil.Amount, outcomes ...string) *Deal {
+	return NewDeal(amt1, amt2, oracle.OutcomeMsgs(outcomes...))
+}
+
+// NewPrefixDeal creates a new deal on leading digits of a numeric outcome.
+// e.g. outcomes "1", "2" matches all outcomes from "120" to "129"
+func NewPrefixDeal(amt1, amt2 btcutil.Amount, digits ...string) *Deal {
+	deal := NewOutcomeDeal(amt1, amt2, digits...)
+	deal.Prefix = true
+	return deal
+}
+
+// NewCancelDeal creates a deal paid out when the oracle attests
+// that the event was cancelled. nMsgs is the number of messages
+// that the other deals use, so that the deal is fixed
+// by picking the same R-points from the oracle's sign set.
+func NewCancelDeal(amt1, amt2 btcutil.Amount, nMsgs int) *Deal {
+	return NewDeal(amt1, amt2, oracle.CancelMsgs(nMsgs))
+}
+
+// equal checks if the deal is the same as another
+func (deal *Deal) equal(o *Deal) bool {
+	if deal == nil || o == nil {
+		return deal == o
+	}
+	if deal.Prefix != o.Prefix || deal.Legacy != o.Legacy ||
+		!equalAmts(deal.Amts, o.Amts) || len(deal.Msgs) != len(o.Msgs) {
+		return false
+	}
+	for i, m := range deal.Msgs {
+		if !bytes.Equal(m, o.Msgs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalAmts checks if amounts of contractors are the same
+func equalAmts(a, b map[Contractor]btcutil.Amount) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, amt := range a {
+		if bamt, ok := b[p]; !ok || amt != bamt {
+			return false
+		}
+	}
+	return true
+}
+
+// IsCancel checks if the deal is for the event's cancellation
+func (deal *Deal) IsCancel() bool {
+	return oracle.SignSet{Msgs: deal.Msgs}.IsCancelled()
+}
+
 // Deal gets a deal by id
 func (d *DLC) Deal(idx int) (*Deal, error) {
 	if len(d.Conds.Deals) < idx+1 {
@@ -36,15 +105,19 @@ func (d *DLC) Deal(idx int) (*Deal, error) {
 	return deal, nil
 }
 
-// DealByMsgs finds a deal by messages
+// DealByMsgs finds a deal by messages.
+// A prefix deal is found by messages starting with its messages.
 func (d *DLC) DealByMsgs(msgs [][]byte) (idx int, deal *Deal, err error) {
-	for i, deal := range d.Conds.Deals {
-		if reflect.DeepEqual(deal.Msgs, msgs) {
-			return i, deal, nil
-		}
+	dealIdx, err := d.Conds.dealIndex()
+	if err != nil {
+		return idx, deal, err
+	}
+	idx, ok := dealIdx.find(msgs)
+	if !ok {
+		err = fmt.Errorf("deal not found. msgs: %v", msgs)
+		return idx, deal, err
 	}
-	err = fmt.Errorf("deal not found. msgs: %v", msgs)
-	return idx, deal, err
+	return idx, d.Conds.Deals[idx], nil
 }
 
 // FixedDealAmt returns fixed amt that the party will receive
@@ -0,0 +1,112 @@
+package dlc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// dealKey is a hash of deal messages
+type dealKey [sha256.Size]byte
+
+func newDealKey(msgs [][]byte) dealKey {
+	h := sha256.New()
+	b := make([]byte, 4)
+	for _, m := range msgs {
+		binary.BigEndian.PutUint32(b, uint32(len(m)))
+		h.Write(b)
+		h.Write(m)
+	}
+	var key dealKey
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// dealIndex finds deals by messages
+type dealIndex struct {
+	exact      map[dealKey]int // deals matching all messages
+	prefix     map[dealKey]int // deals matching leading messages
+	prefixLens []int           // numbers of messages of prefix deals, longest first
+	n          int             // number of indexed deals
+}
+
+// newDealIndex builds an index of deals.
+// It returns an error with the index if deals overlap,
+// in which case the first deal is found.
+func newDealIndex(deals []*Deal) (*dealIndex, error) {
+	idx := &dealIndex{
+		exact:  make(map[dealKey]int),
+		prefix: make(map[dealKey]int),
+		n:      len(deals),
+	}
+
+	var err error
+	lens := make(map[int]bool)
+	for i, deal := range deals {
+		if deal == nil {
+			continue
+		}
+		key := newDealKey(deal.Msgs)
+		if j, ok := idx.lookup(key); ok {
+			if err == nil {
+				err = fmt.Errorf("duplicated deals. deals: %d, %d", j, i)
+			}
+			continue
+		}
+
+		if deal.Prefix {
+			idx.prefix[key] = i
+			lens[len(deal.Msgs)] = true
+		} else {
+			idx.exact[key] = i
+		}
+	}
+	for l := range lens {
+		idx.prefixLens = append(idx.prefixLens, l)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(idx.prefixLens)))
+
+	// a deal must not have leading messages of a prefix deal
+	for i, deal := range deals {
+		if deal == nil {
+			continue
+		}
+		for _, l := range idx.prefixLens {
+			if l >= len(deal.Msgs) {
+				continue
+			}
+			if j, ok := idx.prefix[newDealKey(deal.Msgs[:l])]; ok && err == nil {
+				err = fmt.Errorf("deals overlap. deals: %d, %d", j, i)
+			}
+		}
+	}
+
+	return idx, err
+}
+
+func (idx *dealIndex) lookup(key dealKey) (int, bool) {
+	if i, ok := idx.exact[key]; ok {
+		return i, true
+	}
+	i, ok := idx.prefix[key]
+	return i, ok
+}
+
+// find finds a deal matching messages exactly,
+// or a prefix deal matching the longest leading messages
+func (idx *dealIndex) find(msgs [][]byte) (int, bool) {
+	if i, ok := idx.exact[newDealKey(msgs)]; ok {
+		return i, true
+	}
+
+	for _, l := range idx.prefixLens {
+		if l > len(msgs) {
+			continue
+		}
+		if i, ok := idx.prefix[newDealKey(msgs[:l])]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
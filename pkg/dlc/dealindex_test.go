@@ -0,0 +1,95 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDealIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	deals := []*Deal{
+		NewOutcomeDeal(1, 1, "0", "0", "1"),
+		NewPrefixDeal(2, 0, "0", "1"),
+		NewPrefixDeal(0, 2, "1"),
+		NewOutcomeDeal(1, 1, "fine"),
+	}
+	idx, err := newDealIndex(deals)
+	assert.NoError(err)
+
+	find := func(outcomes ...string) int {
+		i, ok := idx.find(NewOutcomeDeal(0, 0, outcomes...).Msgs)
+		if !ok {
+			return -1
+		}
+		return i
+	}
+	assert.Equal(0, find("0", "0", "1"))
+	assert.Equal(1, find("0", "1", "5"))
+	assert.Equal(1, find("0", "1"))
+	assert.Equal(2, find("1", "9", "9"))
+	assert.Equal(3, find("fine"))
+
+	// not found
+	assert.Equal(-1, find("0", "0", "2"))
+	assert.Equal(-1, find("0"))
+	assert.Equal(-1, find("rain"))
+}
+
+func TestDealIndexDuplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	// same messages
+	_, err := newDealIndex([]*Deal{
+		NewOutcomeDeal(1, 1, "0", "1"),
+		NewOutcomeDeal(2, 0, "0", "1"),
+	})
+	assert.Error(err)
+
+	// same messages of a prefix deal
+	_, err = newDealIndex([]*Deal{
+		NewOutcomeDeal(1, 1, "0", "1"),
+		NewPrefixDeal(2, 0, "0", "1"),
+	})
+	assert.Error(err)
+
+	// overlapping prefix deal
+	_, err = newDealIndex([]*Deal{
+		NewOutcomeDeal(1, 1, "0", "1", "2"),
+		NewPrefixDeal(2, 0, "0", "1"),
+	})
+	assert.Error(err)
+	_, err = newDealIndex([]*Deal{
+		NewPrefixDeal(1, 1, "0"),
+		NewPrefixDeal(2, 0, "0", "1"),
+	})
+	assert.Error(err)
+}
+
+func TestDealByMsgsIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	deal := NewOutcomeDeal(oneBTC, oneBTC, "1")
+	conds := newTestConditions(deal)
+	d := newDLC(conds)
+	_, _, err := d.DealByMsgs(deal.Msgs)
+	assert.NoError(err)
+
+	// fail with stale index after a deal is added
+	other := NewOutcomeDeal(2*oneBTC, 0, "2")
+	conds.Deals = append(conds.Deals, other)
+	_, _, err = d.DealByMsgs(deal.Msgs)
+	assert.Error(err)
+
+	// validation indexes the deals again
+	assert.NoError(conds.Validate())
+	idx, _, err := d.DealByMsgs(other.Msgs)
+	assert.NoError(err)
+	assert.Equal(1, idx)
+
+	// fail with conditions not validated
+	d = newDLC(&Conditions{Deals: []*Deal{deal}})
+	_, _, err = d.DealByMsgs(deal.Msgs)
+	assert.Error(err)
+}
@@ -2,14 +2,15 @@ package dlc
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/dgarage/dlc/pkg/script"
 	"github.com/dgarage/dlc/pkg/wallet"
-	validator "gopkg.in/go-playground/validator.v9"
 )
 
 // DLC contains all information required for DLC contract
@@ -23,6 +24,15 @@ type DLC struct {
 	oracleReqs  *OracleRequirements
 	refundSigns map[Contractor][]byte // counterparty's sign for refund tx
 	cetxSigns   [][]byte              // counterparty's signs for CETs
+
+	payoutScripts  map[Contractor][]byte // scripts receiving payouts (option)
+	changeScripts  map[Contractor][]byte // scripts receiving fund tx change (option)
+	closingScripts map[Contractor][]byte // scripts receiving closing tx outputs
+
+	state ContractState
+
+	fundTxOutSerialID uint64            // serial id of the fund txout chosen by first party
+	batch             *BatchDescription // fund tx shared with other contracts (option)
 }
 
 func newDLC(conds *Conditions) *DLC {
@@ -34,17 +44,25 @@ func newDLC(conds *Conditions) *DLC {
 		oracleReqs:  newOracleReqs(nDeal),
 		refundSigns: make(map[Contractor][]byte),
 		cetxSigns:   make([][]byte, nDeal),
+
+		payoutScripts:  make(map[Contractor][]byte),
+		changeScripts:  make(map[Contractor][]byte),
+		closingScripts: make(map[Contractor][]byte),
 	}
 }
 
 // Conditions contains conditions of a contract
 type Conditions struct {
-	FixingTime     time.Time                     `validate:"required,gt=time.Now()"`
+	FixingTime     time.Time                     `validate:"required"`
 	FundAmts       map[Contractor]btcutil.Amount `validate:"required,dive,gt=0"`
 	FundFeerate    btcutil.Amount                `validate:"required,gt=0"` // fund fee rate (satoshi per byte)
 	RedeemFeerate  btcutil.Amount                `validate:"required,gt=0"` // redeem fee rate (satoshi per byte)
 	RefundLockTime uint32                        `validate:"required,gt=0"` // refund locktime (block height)
 	Deals          []*Deal                       `validate:"required,gt=0,dive,required"`
+	DustPolicy     DustPolicy                    `validate:"gte=0,lte=1"` // how to handle dust outputs
+	AnchorAmt      btcutil.Amount                `validate:"gte=0"`       // amount of anchor txouts (0 for no anchors)
+
+	dealIdx *dealIndex // index of deals by messages
 }
 
 // NewConditions creates a new DLC conditions
@@ -68,21 +86,43 @@ func NewConditions(
 		Deals:          deals,
 	}
 
-	// validate structure
-	err := validator.New().Struct(conds)
+	// validate structure and rules
+	if err := conds.Validate(); err != nil {
+		return conds, err
+	}
 
-	return conds, err
+	// a new contract must be fixed in the future,
+	// but conditions are still valid after the fixing time
+	if !ftime.After(time.Now()) {
+		reason := fmt.Sprintf("fixing time %s has passed",
+			ftime.UTC().Format(time.RFC3339))
+		return conds, newInvalidConditionsError(
+			[]Violation{{Field: "FixingTime", Reason: reason}})
+	}
+	return conds, nil
 }
 
-// ClosingTxOut returns a final txout owned only by a given party
-func (d *DLC) ClosingTxOut(
-	p Contractor, amt btcutil.Amount) (*wire.TxOut, error) {
-	pub := d.pubs[p]
-	if pub == nil {
-		return nil, errors.New("missing pubkey")
+// dealIndex returns the index of deals built by validation.
+// It fails if the conditions aren't validated
+// or deals are added or removed after that.
+func (conds *Conditions) dealIndex() (*dealIndex, error) {
+	idx := conds.dealIdx
+	if idx == nil {
+		return nil, errors.New("deals aren't indexed. validate conditions first")
+	}
+	if idx.n != len(conds.Deals) {
+		return nil, fmt.Errorf(
+			"deal index is stale. indexed: %d, deals: %d", idx.n, len(conds.Deals))
 	}
+	return idx, nil
+}
 
-	pkScript, err := script.P2WPKHpkScript(pub)
+// ClosingTxOut returns a final txout owned only by a given party.
+// It's sent to the party's payout script if it's set,
+// otherwise to the party's fund pubkey.
+func (d *DLC) ClosingTxOut(
+	p Contractor, amt btcutil.Amount) (*wire.TxOut, error) {
+	pkScript, err := d.payoutScript(p)
 	if err != nil {
 		return nil, err
 	}
@@ -116,18 +156,28 @@ func counterparty(p Contractor) (cp Contractor) {
 
 // Builder builds DLC by interacting with wallet
 type Builder struct {
-	party  Contractor
-	wallet wallet.Wallet
-	dlc    *DLC
+	party     Contractor
+	wallet    wallet.Wallet
+	dlc       *DLC
+	changePub *btcec.PublicKey // pubkey of change txout in fund tx
+
+	externalTxIns []*ExternalTxIn // fund txins signed out of the wallet
+
+	cpTxInsVerified bool // counterparty's fund txins are verified
+
+	fundTxInAmts map[wire.OutPoint]btcutil.Amount // amounts of fund txins prepared or verified
+	fundCPFPFee  btcutil.Amount                   // fee of the party's child tx bumping fund tx
+	cancelTxID   *chainhash.Hash                  // double-spend of fund txins being confirmed
 }
 
 // NewBuilder creates a new Builder for a contractor
 func NewBuilder(
 	p Contractor, w wallet.Wallet, conds *Conditions) *Builder {
 	return &Builder{
-		dlc:    newDLC(conds),
-		party:  p,
-		wallet: w,
+		dlc:          newDLC(conds),
+		party:        p,
+		wallet:       w,
+		fundTxInAmts: make(map[wire.OutPoint]btcutil.Amount),
 	}
 }
 
@@ -136,25 +186,82 @@ func (b *Builder) DLC() *DLC {
 	return b.dlc
 }
 
-// PreparePubkey sets fund pubkey
+// PreparePubkey sets fund pubkey.
+// The first party also chooses the serial id of the fund txout.
 func (b *Builder) PreparePubkey() error {
 	pub, err := b.wallet.NewPubkey()
 	if err != nil {
 		return err
 	}
 	b.dlc.pubs[b.party] = pub
+
+	// the closing tx output goes to the wallet's change branch
+	// instead of reusing the fund pubkey unless a payout script is set
+	cpub, err := b.wallet.NewChangePubkey()
+	if err != nil {
+		return err
+	}
+	sc, err := script.P2WPKHpkScript(cpub)
+	if err != nil {
+		return err
+	}
+	b.dlc.closingScripts[b.party] = sc
+
+	if b.party == FirstParty {
+		id, err := newSerialID()
+		if err != nil {
+			return err
+		}
+		b.dlc.fundTxOutSerialID = id
+	}
 	return nil
 }
 
-// CopyReqsFromCounterparty copies requirements from counterparty
-func (b *Builder) CopyReqsFromCounterparty(d *DLC) {
+// CopyReqsFromCounterparty copies requirements from counterparty.
+// It fails if the counterparty's conditions differ from ours.
+func (b *Builder) CopyReqsFromCounterparty(d *DLC) error {
+	if d.Conds == nil {
+		return errors.New("counterparty has no conditions")
+	}
+	if vs := b.dlc.Conds.diffViolations(d.Conds); len(vs) > 0 {
+		return newInvalidConditionsError(vs)
+	}
+
 	p := counterparty(b.party)
 
 	// pubkey
-	b.dlc.pubs[p] = d.pubs[p]
+	if pub := d.pubs[p]; pub != nil {
+		pub, err := btcec.ParsePubKey(pub.SerializeCompressed(), btcec.S256())
+		if err != nil {
+			return err
+		}
+		b.dlc.pubs[p] = pub
+	}
+
+	// payout and change scripts
+	if sc, ok := d.payoutScripts[p]; ok {
+		if err := checkStandardScript(sc); err != nil {
+			return err
+		}
+		b.dlc.payoutScripts[p] = sc
+	}
+	if err := checkChangeScript(p, d); err != nil {
+		return err
+	}
+	if sc, ok := d.changeScripts[p]; ok {
+		b.dlc.changeScripts[p] = sc
+	}
 
 	// fund requirements
-	fundReqs := d.fundTxReqs
-	b.dlc.fundTxReqs.txIns[p] = fundReqs.txIns[p]
-	b.dlc.fundTxReqs.txOut[p] = fundReqs.txOut[p]
+	if err := b.dlc.fundTxReqs.copyFrom(p, d.fundTxReqs); err != nil {
+		return err
+	}
+	b.cpTxInsVerified = false
+
+	// the fund txout
+	if p == FirstParty {
+		b.dlc.fundTxOutSerialID = d.fundTxOutSerialID
+	}
+
+	return b.dlc.checkSerialIDs()
 }
@@ -13,9 +13,9 @@ func TestCondions(t *testing.T) {
 
 	ftime := time.Now().AddDate(0, 0, 1)
 	var famt1, famt2,
-		frate, rrate btcutil.Amount = 1, 1, 1, 1
+		frate, rrate btcutil.Amount = oneBTC, oneBTC, 1, 1
 	var lc uint32 = 1
-	deals := []*Deal{NewDeal(1, 1, [][]byte{{1}})}
+	deals := []*Deal{NewOutcomeDeal(oneBTC, oneBTC, "1")}
 
 	var err error
 	_, err = NewConditions(
@@ -45,6 +45,16 @@ func TestCondions(t *testing.T) {
 	_, err = NewConditions(
 		ftime, famt1, famt2, frate, rrate, lc, []*Deal{})
 	assert.Error(err)
+
+	// duplicated deals
+	_, err = NewConditions(
+		ftime, famt1, famt2, frate, rrate, lc, append(deals, NewOutcomeDeal(0, 2*oneBTC, "1")))
+	assert.Error(err)
+
+	// non-canonical messages
+	_, err = NewConditions(
+		ftime, famt1, famt2, frate, rrate, lc, []*Deal{NewDeal(oneBTC, oneBTC, [][]byte{{1}})})
+	assert.Error(err)
 }
 
 func TestNewBuilder(t *testing.T) {
@@ -3,6 +3,7 @@ package dlc
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/btcsuite/btcutil"
 )
@@ -36,3 +37,28 @@ func newNoFixedDealError() *NoFixedDealError {
 	msg := "No deal has been fixed"
 	return &NoFixedDealError{error: errors.New(msg)}
 }
+
+// InvalidConditionsError is an error for conditions violating rules
+type InvalidConditionsError struct {
+	error
+	Violations []Violation
+}
+
+func newInvalidConditionsError(vs []Violation) *InvalidConditionsError {
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.String()
+	}
+	msg := "Invalid conditions. " + strings.Join(msgs, ", ")
+	return &InvalidConditionsError{error: errors.New(msg), Violations: vs}
+}
+
+// InvalidStateError is an error for an operation not allowed in the contract's state
+type InvalidStateError struct {
+	error
+}
+
+func newInvalidStateError(from, to ContractState) *InvalidStateError {
+	msg := fmt.Sprintf("Contract can't be %s. state: %s", to, from)
+	return &InvalidStateError{error: errors.New(msg)}
+}
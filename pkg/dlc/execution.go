@@ -13,10 +13,12 @@ import (
 // Both parties have different transactions signed by the other side.
 //
 // txins:
-//   [0]:fund transaction output[0]
+//   [0]:fund transaction output of the contract
 // txouts:
 //   [0]:settlement script
-//   [1]:p2wpkh (option)
+//   [1]:counterparty payout (option)
+//   [last 2]:anchors of both parties (option)
+// Dust outputs are settled by the dust policy of the conditions.
 func (d *DLC) ContractExecutionTx(
 	party Contractor, deal *Deal, dID int) (*wire.MsgTx, error) {
 	cparty := counterparty(party)
@@ -27,11 +29,10 @@ func (d *DLC) ContractExecutionTx(
 	}
 
 	// out values
-	amt1 := deal.Amts[party]
-	amt2 := deal.Amts[cparty]
+	amt1, amt2 := d.Conds.settleDust(deal.Amts[party], deal.Amts[cparty])
 
 	if amt1 == 0 {
-		errmsg := "Amount for a multisig script address shouldn't be zero"
+		errmsg := "Amount for a multisig script address shouldn't be zero or dust"
 		return nil, newCETTakeNothingError(errmsg)
 	}
 
@@ -62,7 +63,7 @@ func (d *DLC) ContractExecutionTx(
 	txout1 := wire.NewTxOut(int64(amt1), pkScript)
 	tx.AddTxOut(txout1)
 
-	// txout2: counterparty's p2wpkh
+	// txout2: counterparty's payout
 	if amt2 > 0 {
 		txout2, err := d.ClosingTxOut(cparty, amt2)
 		if err != nil {
@@ -70,14 +71,26 @@ func (d *DLC) ContractExecutionTx(
 		}
 		tx.AddTxOut(txout2)
 	}
+
+	// anchors
+	if err = d.addAnchorTxOuts(tx); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
-// SignContractExecutionTxs signs contract execution txs for all deals
+// SignContractExecutionTxs signs contract execution txs for all deals.
+// The sign is nil for a deal in which the counterparty takes nothing,
+// since the counterparty has no CET to send for the deal.
 func (b *Builder) SignContractExecutionTxs() ([][]byte, error) {
 	var signs [][]byte
 	for idx, deal := range b.dlc.Conds.Deals {
 		sign, err := b.SignContractExecutionTx(deal, idx)
+		if _, ok := err.(*CETTakeNothingError); ok {
+			signs = append(signs, nil)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -88,6 +101,13 @@ func (b *Builder) SignContractExecutionTxs() ([][]byte, error) {
 
 // SignContractExecutionTx signs a contract execution tx for a given party
 func (b *Builder) SignContractExecutionTx(deal *Deal, idx int) ([]byte, error) {
+	if err := b.dlc.checkActive(); err != nil {
+		return nil, err
+	}
+	if err := b.verifyCounterpartyFundTxInsOnce(); err != nil {
+		return nil, err
+	}
+
 	cparty := counterparty(b.party)
 
 	tx, err := b.dlc.ContractExecutionTx(cparty, deal, idx)
@@ -95,6 +115,10 @@ func (b *Builder) SignContractExecutionTx(deal *Deal, idx int) ([]byte, error) {
 		return nil, err
 	}
 
+	if err = checkStandardTx(tx); err != nil {
+		return nil, err
+	}
+
 	return b.witsigForFundScript(tx)
 }
 
@@ -109,7 +133,8 @@ func (b *Builder) AcceptCETxSigns(signs [][]byte) error {
 	return nil
 }
 
-// AcceptCETxSign sets a sign if it's valid for an identified CETx
+// AcceptCETxSign sets a sign if it's valid for an identified CETx.
+// A nil sign is accepted only for a deal in which the party takes nothing.
 func (d *DLC) AcceptCETxSign(party Contractor, idx int, sign []byte) error {
 	deal, err := d.Deal(idx)
 	if err != nil {
@@ -117,6 +142,9 @@ func (d *DLC) AcceptCETxSign(party Contractor, idx int, sign []byte) error {
 	}
 
 	tx, err := d.ContractExecutionTx(party, deal, idx)
+	if _, ok := err.(*CETTakeNothingError); ok && sign == nil {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -147,7 +175,11 @@ func (d *DLC) verifyCETxSign(
 		return err
 	}
 
-	fout := ftx.TxOut[fundTxOutAt]
+	idx, err := d.fundTxOutAt()
+	if err != nil {
+		return err
+	}
+	fout := ftx.TxOut[idx]
 
 	hash, err := txscript.CalcWitnessSigHash(
 		fsc, sighashes, txscript.SigHashAll, tx, fundTxInAt, fout.Value)
@@ -183,6 +215,10 @@ func (b *Builder) SignedContractExecutionTx() (*wire.MsgTx, error) {
 		return nil, err
 	}
 
+	if err = checkStandardTx(tx); err != nil {
+		return nil, err
+	}
+
 	sign, err := b.witsigForFundScript(tx)
 	if err != nil {
 		return nil, err
@@ -7,6 +7,7 @@ import (
 	"github.com/btcsuite/btcutil"
 	"github.com/dgarage/dlc/internal/oracle"
 	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/schnorr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,7 +15,7 @@ func TestContractExecutionTx(t *testing.T) {
 	assert := assert.New(t)
 
 	// A deal that has both amounts are > 0
-	var damt1, damt2 btcutil.Amount = 1, 1
+	var damt1, damt2 btcutil.Amount = oneBTC, oneBTC
 	b, _, dID, deal := setupContractorsUntilPubkeyExchange(damt1, damt2)
 
 	// fail without oracle's message commitment
@@ -35,7 +36,7 @@ func TestContractExecutionTx(t *testing.T) {
 
 // An edge case that a executing party tx takes all funds
 func TestContractExecutionTxTakeAll(t *testing.T) {
-	var damt1, damt2 btcutil.Amount = 1, 0
+	var damt1, damt2 btcutil.Amount = 2 * oneBTC, 0
 	b, _, dID, deal := setupContractorsUntilPubkeyExchange(damt1, damt2)
 	_, C := test.RandKeys()
 	b.dlc.oracleReqs.commitments[dID] = C
@@ -51,7 +52,7 @@ func TestContractExecutionTxTakeAll(t *testing.T) {
 
 // An edge case that a executing party tx takes nothing
 func TestContractExecutionTxTakeNothing(t *testing.T) {
-	var damt1, damt2 btcutil.Amount = 0, 1
+	var damt1, damt2 btcutil.Amount = 0, 2 * oneBTC
 	b, _, dID, deal := setupContractorsUntilPubkeyExchange(damt1, damt2)
 	_, C := test.RandKeys()
 	b.dlc.oracleReqs.commitments[dID] = C
@@ -65,16 +66,45 @@ func TestContractExecutionTxTakeNothing(t *testing.T) {
 	assert.IsType(&CETTakeNothingError{}, err)
 }
 
+// Dust outputs are settled by the dust policy
+func TestContractExecutionTxDust(t *testing.T) {
+	assert := assert.New(t)
+
+	dust := DustLimit - 1
+	damt1, damt2 := 2*oneBTC-dust, dust
+	b, _, dID, deal := setupContractorsUntilPubkeyExchange(damt1, damt2)
+	_, C := test.RandKeys()
+	b.dlc.oracleReqs.commitments[dID] = C
+
+	// dust is paid to miners
+	tx, err := b.dlc.ContractExecutionTx(b.party, deal, dID)
+	assert.NoError(err)
+	assert.Len(tx.TxOut, 1)
+	assert.Equal(int64(damt1), tx.TxOut[0].Value)
+
+	// dust is moved to the other party
+	b.dlc.Conds.DustPolicy = DustToCounterparty
+	tx, err = b.dlc.ContractExecutionTx(b.party, deal, dID)
+	assert.NoError(err)
+	assert.Len(tx.TxOut, 1)
+	assert.Equal(int64(damt1+damt2), tx.TxOut[0].Value)
+
+	// the counterparty's CET takes nothing, so it isn't signed
+	signs, err := b.SignContractExecutionTxs()
+	assert.NoError(err)
+	assert.Nil(signs[0])
+}
+
 func TestSignedContractExecutionTx(t *testing.T) {
 	assert := assert.New(t)
 	var err error
 
 	// setup
-	b1, b2, dID, deal := setupContractorsUntilPubkeyExchange(1, 1)
+	b1, b2, dID, deal := setupContractorsUntilPubkeyExchange(oneBTC, oneBTC)
 	privkey, C := test.RandKeys()
 	b1.dlc.oracleReqs.commitments[dID] = C
 	b2.dlc.oracleReqs.commitments[dID] = C
-	osigns := [][]byte{privkey.D.Bytes()}
+	osigns := [][]byte{schnorr.ScalarBytes(privkey.D)}
 	osignset := &oracle.SignSet{Msgs: deal.Msgs, Signs: osigns}
 
 	err = b1.FixDeal(osignset, []int{0})
@@ -123,26 +153,24 @@ func TestSignedContractExecutionTx(t *testing.T) {
 
 func setupContractorsUntilPubkeyExchange(
 	damt1, damt2 btcutil.Amount) (b1, b2 *Builder, dID int, deal *Deal) {
-	conds := newTestConditions()
-
 	// set deals
-	msgs := [][]byte{{1}}
-	deal = NewDeal(damt1, damt2, [][]byte{{1}})
-	conds.Deals = []*Deal{deal}
+	deal = NewOutcomeDeal(damt1, damt2, "1")
+	msgs := deal.Msgs
+	conds := newTestConditions(deal)
 
 	// init first party
 	w1 := setupTestWallet()
-	w1 = mockSelectUnspent(w1, 1, 1, nil)
+	w1 = mockSelectUnspent(w1, 1, DustLimit, nil)
 	b1 = NewBuilder(FirstParty, w1, conds)
 	b1.PreparePubkey()
-	b1.PrepareFundTxIns()
+	b1.PrepareFundTxIns(nil)
 
 	// init second party
 	w2 := setupTestWallet()
-	w2 = mockSelectUnspent(w2, 1, 1, nil)
+	w2 = mockSelectUnspent(w2, 1, DustLimit, nil)
 	b2 = NewBuilder(SecondParty, w2, conds)
 	b2.PreparePubkey()
-	b2.PrepareFundTxIns()
+	b2.PrepareFundTxIns(nil)
 
 	// exchange pubkeys
 	b1.CopyReqsFromCounterparty(b2.DLC())
@@ -156,6 +184,7 @@ func setupContractorsUntilPubkeyExchange(
 func runFundScript(b *Builder, tx *wire.MsgTx) error {
 	d := b.DLC()
 	fundtx, _ := d.FundTx()
-	fout := fundtx.TxOut[fundTxOutAt]
+	idx, _ := d.fundTxOutAt()
+	fout := fundtx.TxOut[idx]
 	return test.ExecuteScript(fout.PkScript, tx, fout.Value)
 }
@@ -0,0 +1,333 @@
+package dlc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/pkg/script"
+)
+
+// fundTxInSequence is sequence of fund txins signaling replaceability (BIP125)
+const fundTxInSequence = wire.MaxTxInSequenceNum - 2
+
+// fundTxFee estimates the fee that fund tx pays,
+// which parties pay for the base, their txins and change txouts.
+// If the contract is funded in a batch, it's the fee of the batch's fund tx.
+func (d *DLC) fundTxFee() btcutil.Amount {
+	if d.batch != nil {
+		return d.fundTxFeeFor(len(d.batch.FundOuts),
+			len(d.batch.TxIns), len(d.batch.ChangeOuts))
+	}
+
+	nTxIns, nChangeOuts := 0, 0
+	for _, p := range []Contractor{FirstParty, SecondParty} {
+		nTxIns += len(d.fundTxReqs.txIns[p])
+		if d.fundTxReqs.txOut[p] != nil {
+			nChangeOuts++
+		}
+	}
+	return d.fundTxFeeFor(1, nTxIns, nChangeOuts)
+}
+
+// FundCPFPTx constructs a child tx that spends the party's change txout
+// of a given fund tx, so that the fund tx and the child are mined
+// at a target feerate. The fund tx must be the signed one sent to the network.
+// Wallet utxos added to the child are reserved for a while to be broadcast.
+func (b *Builder) FundCPFPTx(
+	fundtx *wire.MsgTx, feerate btcutil.Amount) (*wire.MsgTx, error) {
+	tx, _, err := b.fundCPFPTx(fundtx, feerate)
+	return tx, err
+}
+
+// fundCPFPTx constructs a child tx bumping the fee of fund tx
+// and returns the fee that the child pays
+func (b *Builder) fundCPFPTx(fundtx *wire.MsgTx,
+	feerate btcutil.Amount) (*wire.MsgTx, btcutil.Amount, error) {
+	change := b.dlc.fundTxReqs.txOut[b.party]
+	if change == nil || b.changePub == nil {
+		return nil, 0, errors.New("fund tx has no change txout of the party")
+	}
+
+	changeAt := -1
+	for i, txout := range fundtx.TxOut {
+		if txout.Value == change.Value &&
+			string(txout.PkScript) == string(change.PkScript) {
+			changeAt = i
+			break
+		}
+	}
+	if changeAt < 0 {
+		return nil, 0, errors.New("change txout isn't found in fund tx")
+	}
+
+	return b.cpfpTx(
+		fundtx, changeAt, b.changePub, b.dlc.fundTxFee(), feerate)
+}
+
+// SendFundCPFPTx sends a child tx bumping the fee of fund tx.
+// The child's fee is kept, since a tx abandoning fund tx also evicts the child
+// and has to pay for it. A new child replaces the previous one.
+func (b *Builder) SendFundCPFPTx(
+	fundtx *wire.MsgTx, feerate btcutil.Amount) error {
+	tx, fee, err := b.fundCPFPTx(fundtx, feerate)
+	if err != nil {
+		return err
+	}
+
+	if _, err = b.wallet.SendRawTransaction(tx); err != nil {
+		_ = b.releaseCPFPTxIns(tx)
+		return err
+	}
+	b.fundCPFPFee = fee
+	return nil
+}
+
+// abandonTxSize is size of a tx that double-spends the party's fund txins
+func (b *Builder) abandonTxSize() int64 {
+	nTxIns := int64(len(b.dlc.fundTxReqs.txIns[b.party]))
+	return cpfpTxBaseSize + cpfpTxInSize*nTxIns + cpfpTxOutSize
+}
+
+// AbandonFundTx constructs a tx that double-spends the party's txins
+// of fund tx to a new address of the wallet at a given feerate.
+// It cancels the contract if it's mined instead of the fund tx,
+// so it must pay enough fee to replace the fund tx (BIP125).
+// See docs/Considerations.md for when to use it.
+func (b *Builder) AbandonFundTx(feerate btcutil.Amount) (*wire.MsgTx, error) {
+	minFee, err := b.minAbandonFee()
+	if err != nil {
+		return nil, err
+	}
+
+	fee := feerate.MulF64(float64(b.abandonTxSize()))
+	if fee < minFee {
+		return nil, fmt.Errorf(
+			"fee doesn't replace fund tx. fee: %d, required: %d", fee, minFee)
+	}
+	return b.abandonFundTx(fee)
+}
+
+// minAbandonFee returns the least fee of a tx replacing fund tx (BIP125).
+// It pays the fees of fund tx and the party's child bumping it,
+// which are evicted together, and the incremental relay fee for its own size.
+// A child sent by the counterparty isn't known and must be paid by a higher feerate.
+func (b *Builder) minAbandonFee() (btcutil.Amount, error) {
+	fundFee, err := b.fundTxFeePaid()
+	if err != nil {
+		return 0, err
+	}
+	size := b.abandonTxSize()
+	return fundFee + b.fundCPFPFee + minRelayFeerate.MulF64(float64(size)), nil
+}
+
+// fundTxFeePaid returns the fee that fund tx actually pays,
+// computed with the amounts of txins recorded when they're prepared or verified
+func (b *Builder) fundTxFeePaid() (btcutil.Amount, error) {
+	fundtx, err := b.dlc.FundTx()
+	if err != nil {
+		return 0, err
+	}
+
+	var fee btcutil.Amount
+	for _, txin := range fundtx.TxIn {
+		amt, ok := b.fundTxInAmts[txin.PreviousOutPoint]
+		if !ok {
+			return 0, fmt.Errorf(
+				"amount of fund txin isn't known. outpoint: %v",
+				txin.PreviousOutPoint)
+		}
+		fee += amt
+	}
+	for _, txout := range fundtx.TxOut {
+		fee -= btcutil.Amount(txout.Value)
+	}
+	return fee, nil
+}
+
+// abandonFundTx constructs a tx that double-spends the party's txins
+// of fund tx paying a given fee
+func (b *Builder) abandonFundTx(fee btcutil.Amount) (*wire.MsgTx, error) {
+	txins := b.dlc.fundTxReqs.txIns[b.party]
+	if len(txins) == 0 {
+		return nil, errors.New("fund tx has no txins of the party")
+	}
+
+	// amounts of txins recorded when they're prepared,
+	// since the wallet doesn't list them once fund tx is in the mempool
+	var total btcutil.Amount
+	for _, txin := range txins {
+		amt, ok := b.fundTxInAmts[txin.PreviousOutPoint]
+		if !ok {
+			return nil, fmt.Errorf(
+				"amount of fund txin isn't known. outpoint: %v",
+				txin.PreviousOutPoint)
+		}
+		total += amt
+	}
+
+	out := total - fee
+	if isDust(out) {
+		return nil, newNotEnoughFeesError(total, fee)
+	}
+
+	tx := wire.NewMsgTx(txVersion)
+	var idxs []int
+	for i, txin := range txins {
+		tx.AddTxIn(wire.NewTxIn(&txin.PreviousOutPoint, nil, nil))
+		idxs = append(idxs, i)
+	}
+
+	pub, err := b.wallet.NewChangePubkey()
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := script.P2WPKHpkScript(pub)
+	if err != nil {
+		return nil, err
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(out), pkScript))
+
+	if err = checkStandardTx(tx); err != nil {
+		return nil, err
+	}
+
+	wits, err := b.wallet.WitnessSignTxByIdxs(tx, idxs)
+	if err != nil {
+		return nil, err
+	}
+	for i, wit := range wits {
+		tx.TxIn[i].Witness = wit
+	}
+
+	return tx, nil
+}
+
+// SendAbandonFundTx sends a tx abandoning fund tx,
+// and the contract is being cancelled until either tx is confirmed.
+// Before signing fund tx, it only releases the reserved utxos.
+func (b *Builder) SendAbandonFundTx(feerate btcutil.Amount) error {
+	if b.dlc.state == ContractNegotiating {
+		return b.cancelNegotiation()
+	}
+	if err := b.dlc.canTransit(ContractCancelling); err != nil {
+		return err
+	}
+
+	tx, err := b.AbandonFundTx(feerate)
+	if err != nil {
+		return err
+	}
+
+	return b.sendCancelTx(tx)
+}
+
+// minRelayFeerate is the incremental relay feerate (satoshi per byte)
+// that a replacement tx pays in addition to the fee of the original
+const minRelayFeerate = btcutil.Amount(1)
+
+// CancelBeforeFunding cancels the contract before fund tx is confirmed.
+// It sends a tx that double-spends the party's fund txins back to the wallet,
+// so that the counterparty can't send the fund tx signed by the party.
+// The tx pays the least fee that replaces the fund tx in the mempool.
+// Before signing fund tx, nothing is sent and the reserved utxos are released.
+func (b *Builder) CancelBeforeFunding() error {
+	if b.dlc.state == ContractNegotiating {
+		return b.cancelNegotiation()
+	}
+	if err := b.dlc.canTransit(ContractCancelling); err != nil {
+		return err
+	}
+
+	minFee, err := b.minAbandonFee()
+	if err != nil {
+		return err
+	}
+	fee := b.dlc.Conds.FundFeerate.MulF64(float64(b.abandonTxSize()))
+	if fee < minFee {
+		fee = minFee
+	}
+
+	tx, err := b.abandonFundTx(fee)
+	if err != nil {
+		return err
+	}
+
+	return b.sendCancelTx(tx)
+}
+
+// cancelNegotiation cancels the contract before signing fund tx.
+// The counterparty can't send fund tx without the party's signatures,
+// so the reserved utxos are just released.
+func (b *Builder) cancelNegotiation() error {
+	if err := b.ReleaseFundTxIns(); err != nil {
+		return err
+	}
+	return b.dlc.transit(ContractCancelled)
+}
+
+// sendCancelTx sends a tx double-spending fund txins
+// and moves the contract to the cancelling state
+func (b *Builder) sendCancelTx(tx *wire.MsgTx) error {
+	if _, err := b.wallet.SendRawTransaction(tx); err != nil {
+		return err
+	}
+
+	txid := tx.TxHash()
+	b.cancelTxID = &txid
+	return b.dlc.transit(ContractCancelling)
+}
+
+// ConfirmCancellation checks which of fund tx and the double-spend
+// of its txins is confirmed. The contract is cancelled and the reserved utxos
+// are released if the double-spend is. The contract goes back to
+// the fund sent state if fund tx is, and it's executed as usual.
+// It fails if neither is confirmed yet.
+func (b *Builder) ConfirmCancellation() error {
+	if b.dlc.state != ContractCancelling || b.cancelTxID == nil {
+		return fmt.Errorf("contract is %s", b.dlc.state)
+	}
+
+	fundtx, err := b.dlc.FundTx()
+	if err != nil {
+		return err
+	}
+	idx, err := b.dlc.fundTxOutAt()
+	if err != nil {
+		return err
+	}
+	fundTxID := fundtx.TxHash()
+	fundOp := wire.NewOutPoint(&fundTxID, uint32(idx))
+	ok, err := b.isConfirmed(*fundOp)
+	if err != nil {
+		return err
+	}
+	if ok {
+		b.cancelTxID = nil
+		return b.dlc.transit(ContractFundSent)
+	}
+
+	ok, err = b.isConfirmed(*wire.NewOutPoint(b.cancelTxID, 0))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("neither fund tx nor its double-spend is confirmed")
+	}
+
+	ops := outPoints(b.dlc.fundTxReqs.txIns[b.party])
+	if err = b.wallet.UnlockUnspent(ops); err != nil {
+		return err
+	}
+	return b.dlc.transit(ContractCancelled)
+}
+
+// isConfirmed checks if a txout is unspent and confirmed
+func (b *Builder) isConfirmed(op wire.OutPoint) (bool, error) {
+	txout, err := b.wallet.GetTxOut(op)
+	if err != nil {
+		return false, err
+	}
+	return txout != nil && txout.Confirmations > 0, nil
+}
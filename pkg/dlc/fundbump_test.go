@@ -0,0 +1,161 @@
+package dlc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setupDLCExactFunds sets up a contract funded with the exact changes.
+// Refund tx is signed and the counterparty's fund txins are verified.
+func setupDLCExactFunds() (b1, b2 *Builder, d *DLC) {
+	conds := newTestConditions()
+	conds.RefundLockTime = testLockTime
+
+	b1 = NewBuilder(FirstParty, mockExactSelectUnspent(setupTestWallet(), 0), conds)
+	b2 = NewBuilder(SecondParty, mockExactSelectUnspent(setupTestWallet(), 1), conds)
+	for _, b := range []*Builder{b1, b2} {
+		b.PreparePubkey()
+		b.PrepareFundTxIns(nil)
+	}
+	b1.CopyReqsFromCounterparty(b2.DLC())
+	b2.CopyReqsFromCounterparty(b1.DLC())
+
+	rs1, _ := b1.SignRefundTx()
+	rs2, _ := b2.SignRefundTx()
+	b1.AcceptRefundTxSign(rs2)
+	b2.AcceptRefundTxSign(rs1)
+
+	return b1, b2, b1.DLC()
+}
+
+func TestFundTxReplaceable(t *testing.T) {
+	b1, _, d := setupDLCRefund()
+
+	fundtx, err := d.FundTx()
+	assert.NoError(t, err)
+	idxs, err := b1.fundTxInAt()
+	assert.NoError(t, err)
+	for _, idx := range idxs {
+		assert.Equal(t, uint32(fundTxInSequence), fundtx.TxIn[idx].Sequence)
+	}
+}
+
+func TestFundCPFPTx(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCRefund()
+	fundtx, _ := d.FundTx()
+
+	w := b1.wallet.(*walletmock.Wallet)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), []int{1},
+	).Return([]wire.TxWitness{{}}, nil)
+
+	tx, err := b1.FundCPFPTx(fundtx, 20)
+	assert.NoError(err)
+
+	// the child spends the change txout of first party
+	change := b1.dlc.fundTxReqs.txOut[FirstParty]
+	changeAt := -1
+	for i, txout := range fundtx.TxOut {
+		if txout.Value == change.Value &&
+			string(txout.PkScript) == string(change.PkScript) {
+			changeAt = i
+		}
+	}
+	txid := fundtx.TxHash()
+	assert.Equal(
+		*wire.NewOutPoint(&txid, uint32(changeAt)), tx.TxIn[0].PreviousOutPoint)
+	err = test.ExecuteScript(change.PkScript, tx, change.Value)
+	assert.NoError(err)
+
+	// the wallet utxo added to the child is reserved
+	w.AssertCalled(t, "LockUnspent", outPoints(tx.TxIn[1:]), mock.Anything)
+
+	// no need to bump at the fund feerate
+	_, err = b1.FundCPFPTx(fundtx, d.Conds.FundFeerate)
+	assert.Error(err)
+}
+
+// SendFundCPFPTx should release the wallet utxos if the child isn't sent
+func TestSendFundCPFPTxFail(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCRefund()
+	fundtx, _ := d.FundTx()
+
+	w := b1.wallet.(*walletmock.Wallet)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), []int{1},
+	).Return([]wire.TxWitness{{}}, nil)
+	var sent *wire.MsgTx
+	w.On("SendRawTransaction", mock.AnythingOfType("*wire.MsgTx")).
+		Run(func(args mock.Arguments) {
+			sent = args.Get(0).(*wire.MsgTx)
+		}).Return(nil, errors.New("rejected"))
+
+	err := b1.SendFundCPFPTx(fundtx, 20)
+	assert.Error(err)
+	ops := outPoints(sent.TxIn[1:])
+	w.AssertCalled(t, "LockUnspent", ops, mock.Anything)
+	w.AssertCalled(t, "UnlockUnspent", ops)
+	assert.Zero(b1.fundCPFPFee)
+}
+
+func TestAbandonFundTx(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCExactFunds()
+
+	// the wallet doesn't list utxos spent by fund tx in the mempool
+	w := b1.wallet.(*walletmock.Wallet)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), []int{0},
+	).Return([]wire.TxWitness{{}}, nil)
+
+	// fund tx pays more than the estimated fee,
+	// since both parties pay the fee of redeem tx
+	fundFee, err := b1.fundTxFeePaid()
+	assert.NoError(err)
+	assert.Equal(d.fundTxFee()+d.redeemTxFee(cetxSize), fundFee)
+
+	// fee must pay fund tx's and the incremental relay fee
+	size := b1.abandonTxSize()
+	_, err = b1.AbandonFundTx(d.Conds.FundFeerate)
+	assert.Error(err)
+	minFee := fundFee + minRelayFeerate.MulF64(float64(size))
+	feerate := minFee/btcutil.Amount(size) + 1
+	tx, err := b1.AbandonFundTx(feerate)
+	assert.NoError(err)
+
+	// double-spends the txin of fund tx
+	fundtx, _ := d.FundTx()
+	idxs, _ := b1.fundTxInAt()
+	txin := fundtx.TxIn[idxs[0]]
+	assert.Len(tx.TxIn, 1)
+	assert.Equal(txin.PreviousOutPoint, tx.TxIn[0].PreviousOutPoint)
+	fee := feerate.MulF64(float64(size))
+	assert.Equal(int64(10*oneBTC-fee), tx.TxOut[0].Value)
+
+	// a child bumping fund tx is evicted too and must be paid for
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), []int{1},
+	).Return([]wire.TxWitness{{}}, nil)
+	w.On("SendRawTransaction", mock.AnythingOfType("*wire.MsgTx")).
+		Return(&chainhash.Hash{}, nil)
+	assert.NoError(b1.SendFundCPFPTx(fundtx, 20))
+	assert.True(b1.fundCPFPFee > 0)
+	_, err = b1.AbandonFundTx(feerate)
+	assert.Error(err)
+	minFee += b1.fundCPFPFee
+	_, err = b1.AbandonFundTx(minFee/btcutil.Amount(size) + 1)
+	assert.NoError(err)
+}
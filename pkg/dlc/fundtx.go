@@ -2,6 +2,7 @@ package dlc
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -13,39 +14,61 @@ import (
 type FundTxRequirements struct {
 	txIns map[Contractor][]*wire.TxIn
 	txOut map[Contractor]*wire.TxOut
+
+	// serial ids ordering txins and txouts in fund tx
+	txInSerialIDs map[Contractor][]uint64
+	txOutSerialID map[Contractor]uint64
 }
 
 func newFundTxReqs() *FundTxRequirements {
 	return &FundTxRequirements{
-		txIns: make(map[Contractor][]*wire.TxIn),
-		txOut: make(map[Contractor]*wire.TxOut),
+		txIns:         make(map[Contractor][]*wire.TxIn),
+		txOut:         make(map[Contractor]*wire.TxOut),
+		txInSerialIDs: make(map[Contractor][]uint64),
+		txOutSerialID: make(map[Contractor]uint64),
 	}
 }
 
-const fundTxOutAt = 0 // fund txout is always at 0 in fund tx
-const fundTxInAt = 0  // fund txin is always at 0 in redeem tx
-
-// FundTx constructs fund tx using prepared fund tx requirements
-func (d *DLC) FundTx() (*wire.MsgTx, error) {
-	tx := wire.NewMsgTx(txVersion)
+const fundTxInAt = 0 // fund txin is always at 0 in redeem tx
 
-	txout, err := d.fundTxOutForRedeemTx()
+// fundTxOutAt returns the index of the fund txout in fund tx,
+// which is derived from the serial ids of the txouts
+func (d *DLC) fundTxOutAt() (int, error) {
+	desc, err := d.fundTxDescription()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	tx.AddTxOut(txout)
-
-	for _, p := range []Contractor{FirstParty, SecondParty} {
-		for _, txin := range d.fundTxReqs.txIns[p] {
-			tx.AddTxIn(txin)
-		}
-		// txout for change
-		txout := d.fundTxReqs.txOut[p]
-		if txout != nil {
-			tx.AddTxOut(txout)
+	for i, out := range desc.sortedTxOuts() {
+		if out.SerialID == d.fundTxOutSerialID {
+			return i, nil
 		}
 	}
+	return 0, errors.New("fund txout isn't found in fund tx")
+}
+
+// fundTxDescription describes the fund tx of the contract.
+// It's the description of the batch if the contract is funded in a batch,
+// which is checked against the contract.
+func (d *DLC) fundTxDescription() (*BatchDescription, error) {
+	if d.batch == nil {
+		return describeFundTx([]*DLC{d})
+	}
+	if err := d.checkBatch(d.batch); err != nil {
+		return nil, err
+	}
+	return d.batch, nil
+}
 
+// FundTx constructs fund tx using prepared fund tx requirements.
+// It's the fund tx of the batch if the contract is funded in a batch.
+// Txins have the witnesses of the contract's parties.
+func (d *DLC) FundTx() (*wire.MsgTx, error) {
+	desc, err := d.fundTxDescription()
+	if err != nil {
+		return nil, err
+	}
+	tx := desc.fundTx()
+	setFundTxWitnesses(tx, []*DLC{d})
 	return tx, nil
 }
 
@@ -64,6 +87,7 @@ func (d *DLC) fundScript() ([]byte, error) {
 
 // fundTxOutForRedeemTx creates a txout for the txin of redeem tx.
 // The value of the txout is calculated by `fund amount + redeem tx fee`
+// and anchor txouts of both parties if any
 func (d *DLC) fundTxOutForRedeemTx() (*wire.TxOut, error) {
 	fs, err := d.fundScript()
 	if err != nil {
@@ -80,7 +104,7 @@ func (d *DLC) fundTxOutForRedeemTx() (*wire.TxOut, error) {
 		return nil, err
 	}
 
-	amt += d.redeemTxFee(cetxSize)
+	amt += d.redeemTxFee(cetxSize) + 2*d.anchorCost()
 
 	txout := wire.NewTxOut(int64(amt), pkScript)
 
@@ -122,10 +146,25 @@ func (b *Builder) FundAmt() btcutil.Amount {
 const fundTxBaseSize = int64(55)
 const fundTxInSize = int64(149)
 const fundTxOutSize = int64(31)
-const cetxSize = int64(345) // context execution tx size
+const fundTxFundOutSize = int64(43) // p2wsh fund txout size
+const cetxSize = int64(345)         // context execution tx size
+
+// fundTxFeeBase returns the fee that a party pays for the base of fund tx
+// funding a number of contracts, which includes the extra fund txouts
+func (d *DLC) fundTxFeeBase(nContracts int) btcutil.Amount {
+	size := fundTxBaseSize + int64(nContracts-1)*fundTxFundOutSize
+	return d.Conds.FundFeerate.MulF64(float64(size))
+}
 
-func (d *DLC) fundTxFeeBase() btcutil.Amount {
-	return d.Conds.FundFeerate.MulF64(float64(fundTxBaseSize))
+// fundTxFeeFor estimates the fee of fund tx funding a number of contracts
+// with given numbers of txins and change txouts.
+// The party funding all the contracts pays the base for them,
+// and the counterparty of each contract pays the base of its own.
+func (d *DLC) fundTxFeeFor(nContracts, nTxIns, nChangeOuts int) btcutil.Amount {
+	return d.fundTxFeeBase(nContracts) +
+		d.fundTxFeeBase(1).MulF64(float64(nContracts)) +
+		d.fundTxFeePerTxIn().MulF64(float64(nTxIns)) +
+		d.fundTxFeePerTxOut().MulF64(float64(nChangeOuts))
 }
 
 func (d *DLC) fundTxFeePerTxIn() btcutil.Amount {
@@ -140,13 +179,30 @@ func (d *DLC) redeemTxFee(size int64) btcutil.Amount {
 	return d.Conds.RedeemFeerate.MulF64(float64(size))
 }
 
-// PrepareFundTxIns prepares utxos for fund tx by calculating fees
-func (b *Builder) PrepareFundTxIns() error {
+// PrepareFundTxIns prepares utxos for fund tx by calculating fees.
+// Utxos are selected by a given coin selector,
+// or by wallet.DefaultCoinSelector if it's nil.
+func (b *Builder) PrepareFundTxIns(selector wallet.CoinSelector) error {
 	famt := b.dlc.Conds.FundAmts[b.party]
-	feeBase := b.dlc.fundTxFeeBase()
+	feeBase := b.dlc.fundTxFeeBase(1)
 	redeemTxFee := b.dlc.redeemTxFee(cetxSize)
+	anchorCost := b.dlc.anchorCost()
+	return b.prepareFundTxIns(
+		selector, famt+feeBase+redeemTxFee+anchorCost)
+}
+
+// prepareFundTxIns selects utxos for a given amount
+// and sets them and the change to fund tx requirements
+func (b *Builder) prepareFundTxIns(
+	selector wallet.CoinSelector, amt btcutil.Amount) error {
+	// release utxos reserved before, so that they can be selected again
+	if err := b.ReleaseFundTxIns(); err != nil {
+		return err
+	}
+
 	utxos, change, err := b.wallet.SelectUnspent(
-		famt+feeBase+redeemTxFee,
+		selector,
+		amt,
 		b.dlc.fundTxFeePerTxIn(),
 		b.dlc.fundTxFeePerTxOut())
 	if err != nil {
@@ -158,33 +214,117 @@ func (b *Builder) PrepareFundTxIns() error {
 		return err
 	}
 
-	// set txins to DLC
-	b.dlc.fundTxReqs.txIns[b.party] = txins
-
-	if change > 0 {
-		pub, err := b.wallet.NewPubkey()
+	// amounts of the utxos to abandon fund tx without the wallet's help,
+	// since they're no longer listed once fund tx is in the mempool
+	amts := make(map[wire.OutPoint]btcutil.Amount)
+	for i, utxo := range utxos {
+		amt, err := btcutil.NewAmount(utxo.Amount)
 		if err != nil {
 			return err
 		}
+		amts[txins[i].PreviousOutPoint] = amt
+	}
 
-		pkScript, err := script.P2WPKHpkScript(pub)
-		if err != nil {
-			return err
-		}
+	// signal replaceability so that the party can abandon fund tx
+	for _, txin := range txins {
+		txin.Sequence = fundTxInSequence
+	}
 
-		txout := wire.NewTxOut(int64(change), pkScript)
+	if err = b.setFundTxIns(txins, change); err != nil {
+		b.resetFundTxIns()
+		return err
+	}
+	for op, amt := range amts {
+		b.fundTxInAmts[op] = amt
+	}
 
-		// set change txout to DLC
-		b.dlc.fundTxReqs.txOut[b.party] = txout
+	// reserve utxos during negotiation.
+	// the fund tx should be confirmed before the fixing time anyway.
+	err = b.wallet.LockUnspent(outPoints(txins), b.dlc.Conds.FixingTime)
+	if err != nil {
+		b.resetFundTxIns()
+		return err
 	}
 
 	return nil
 }
 
+// setFundTxIns sets the party's txins and the change
+// to fund tx requirements
+func (b *Builder) setFundTxIns(
+	txins []*wire.TxIn, change btcutil.Amount) error {
+	if err := b.dlc.fundTxReqs.setTxIns(b.party, txins); err != nil {
+		return err
+	}
+	if change <= 0 {
+		return nil
+	}
+
+	pkScript, err := b.changePkScript()
+	if err != nil {
+		return err
+	}
+
+	txout := wire.NewTxOut(int64(change), pkScript)
+
+	// set change txout to DLC
+	return b.dlc.fundTxReqs.setTxOut(b.party, txout)
+}
+
+// changePkScript returns the party's change script if it's set.
+// Otherwise the change goes to a new change address of the wallet,
+// whose pubkey is kept to bump the fee of fund tx.
+func (b *Builder) changePkScript() ([]byte, error) {
+	if sc, ok := b.dlc.changeScripts[b.party]; ok {
+		b.changePub = nil
+		return sc, nil
+	}
+
+	pub, err := b.wallet.NewChangePubkey()
+	if err != nil {
+		return nil, err
+	}
+	b.changePub = pub
+	return script.P2WPKHpkScript(pub)
+}
+
+// ReleaseFundTxIns releases utxos reserved for fund tx
+// so that other contracts can use them.
+// It's used when a contract is rejected.
+func (b *Builder) ReleaseFundTxIns() error {
+	// external utxos aren't reserved in the wallet
+	txins := b.dlc.fundTxReqs.txIns[b.party]
+	if len(txins) > 0 && b.externalTxIns == nil {
+		if err := b.wallet.UnlockUnspent(outPoints(txins)); err != nil {
+			return err
+		}
+	}
+
+	b.resetFundTxIns()
+	return nil
+}
+
+// resetFundTxIns removes the party's txins and change from fund tx requirements
+func (b *Builder) resetFundTxIns() {
+	b.dlc.fundTxReqs.setTxIns(b.party, nil)
+	b.dlc.fundTxReqs.setTxOut(b.party, nil)
+	b.changePub = nil
+	b.externalTxIns = nil
+}
+
+// outPoints returns previous outpoints of txins
+func outPoints(txins []*wire.TxIn) []wire.OutPoint {
+	var ops []wire.OutPoint
+	for _, txin := range txins {
+		ops = append(ops, txin.PreviousOutPoint)
+	}
+	return ops
+}
+
 // newRedeemTx creates a new tx to redeem fundtx
 // redeem tx
 //  inputs:
-//   [0]: fund transaction output[0]
+//   [0]: fund transaction output of the contract
 func (d *DLC) newRedeemTx() (*wire.MsgTx, error) {
 	fundtx, err := d.FundTx()
 	if err != nil {
@@ -193,9 +333,14 @@ func (d *DLC) newRedeemTx() (*wire.MsgTx, error) {
 
 	tx := wire.NewMsgTx(txVersion)
 
+	idx, err := d.fundTxOutAt()
+	if err != nil {
+		return nil, err
+	}
+
 	// txin
 	txid := fundtx.TxHash()
-	fout := wire.NewOutPoint(&txid, fundTxOutAt)
+	fout := wire.NewOutPoint(&txid, uint32(idx))
 	txin := wire.NewTxIn(fout, nil, nil)
 	tx.AddTxIn(txin)
 
@@ -208,8 +353,11 @@ func (b *Builder) witsigForFundScript(tx *wire.MsgTx) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	fout := fundtx.TxOut[fundTxOutAt]
-	famt := btcutil.Amount(fout.Value)
+	idx, err := b.dlc.fundTxOutAt()
+	if err != nil {
+		return nil, err
+	}
+	famt := btcutil.Amount(fundtx.TxOut[idx].Value)
 
 	fc, err := b.dlc.fundScript()
 	if err != nil {
@@ -223,53 +371,88 @@ func (b *Builder) witsigForFundScript(tx *wire.MsgTx) ([]byte, error) {
 
 // SignFundTx signs fund tx and return witnesses for the txins owned by the party
 func (b *Builder) SignFundTx() ([]wire.TxWitness, error) {
+	if err := b.dlc.canTransit(ContractFundSigned); err != nil {
+		return nil, err
+	}
+
+	if len(b.externalTxIns) > 0 {
+		return nil, errors.New("external fund txins must be signed via PSBT")
+	}
+
 	fundtx, err := b.dlc.FundTx()
 	if err != nil {
 		return nil, err
 	}
 
-	// get witnesses
-	idxs := b.fundTxInAt()
-	wits, err := b.wallet.WitnessSignTxByIdxs(fundtx, idxs)
+	// get witnesses.
+	// the party may have no txins in a batch funded by another contract
+	idxs, err := b.fundTxInAt()
 	if err != nil {
 		return nil, err
 	}
+	var wits []wire.TxWitness
+	if len(idxs) > 0 {
+		wits, err = b.wallet.WitnessSignTxByIdxs(fundtx, idxs)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// set witnesses to txins
 	for i, wit := range wits {
 		b.dlc.fundTxReqs.txIns[b.party][i].Witness = wit
 	}
 
-	return wits, nil
+	return wits, b.dlc.transit(ContractFundSigned)
 }
 
 // SendFundTx sends fund tx to the network
 func (b *Builder) SendFundTx() error {
+	if err := b.dlc.checkActive(); err != nil {
+		return err
+	}
+	if err := b.dlc.canTransit(ContractFundSent); err != nil {
+		return err
+	}
+
 	tx, err := b.dlc.FundTx()
 	if err != nil {
 		return err
 	}
 
 	_, err = b.wallet.SendRawTransaction(tx)
-	return err
+	if err != nil {
+		return err
+	}
+	return b.dlc.transit(ContractFundSent)
 }
 
-// fundTxInAt returns indices of txin in fundtx by the party
-func (b *Builder) fundTxInAt() (idxs []int) {
-	nTxInMe := len(b.dlc.fundTxReqs.txIns[b.party])
-	var txinFrom, txinTo int
-	if b.party == FirstParty {
-		txinFrom = 0
-		txinTo = nTxInMe
-	} else {
-		nTxInCP := len(b.dlc.fundTxReqs.txIns[SecondParty])
-		txinFrom = nTxInCP
-		txinTo = txinFrom + nTxInMe
-	}
-	for i := txinFrom; i < txinTo; i++ {
-		idxs = append(idxs, i)
-	}
-	return idxs
+// fundTxInAt returns indices of txin in fundtx by the party,
+// which are in the same order as the party's txins.
+// It fails if any of the party's txins isn't in fund tx.
+func (b *Builder) fundTxInAt() ([]int, error) {
+	desc, err := b.dlc.fundTxDescription()
+	if err != nil {
+		return nil, err
+	}
+	ins := desc.sortedTxIns()
+
+	var idxs []int
+	for _, txin := range b.dlc.fundTxReqs.txIns[b.party] {
+		idx := -1
+		for i, in := range ins {
+			if in.OutPoint == txin.PreviousOutPoint {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf(
+				"fund txin %v isn't found in fund tx", txin.PreviousOutPoint)
+		}
+		idxs = append(idxs, idx)
+	}
+	return idxs, nil
 }
 
 // AcceptFundWitnesses accepts witnesses for fund txins owned by the counerparty
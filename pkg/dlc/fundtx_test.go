@@ -6,6 +6,7 @@ import (
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
 	"github.com/dgarage/dlc/internal/test"
 	"github.com/dgarage/dlc/pkg/wallet"
 	"github.com/stretchr/testify/assert"
@@ -16,14 +17,14 @@ import (
 func TestPrepareFundTxNotEnoughUtxos(t *testing.T) {
 	testWallet := setupTestWallet()
 	testWallet.On("SelectUnspent",
-		mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
 	).Return(
 		[]wallet.Utxo{}, btcutil.Amount(0), errors.New("not enough utxos"))
 
 	conds := newTestConditions()
 	builder := NewBuilder(FirstParty, testWallet, conds)
 
-	err := builder.PrepareFundTxIns()
+	err := builder.PrepareFundTxIns(nil)
 	assert.NotNil(t, err) // not enough balance for fee
 }
 
@@ -39,13 +40,109 @@ func TestPrepareFundTx(t *testing.T) {
 	conds := newTestConditions()
 	b := NewBuilder(FirstParty, testWallet, conds)
 
-	err := b.PrepareFundTxIns()
+	err := b.PrepareFundTxIns(nil)
 	assert.Nil(err)
 
 	txins := b.dlc.fundTxReqs.txIns[b.party]
 	assert.NotEmpty(txins, "txins")
 	txout := b.dlc.fundTxReqs.txOut[b.party]
 	assert.NotNil(txout, "txout")
+	testWallet.AssertCalled(t, "NewChangePubkey")
+}
+
+// PrepareFundTx should select utxos by a given coin selector
+func TestPrepareFundTxWithCoinSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	testWallet := setupTestWallet()
+	mockSelectUnspent(testWallet, 1, DustLimit, nil)
+
+	conds := newTestConditions()
+	b := NewBuilder(FirstParty, testWallet, conds)
+
+	err := b.PrepareFundTxIns(wallet.OldestFirst{})
+	assert.NoError(err)
+	testWallet.AssertCalled(t, "SelectUnspent",
+		wallet.OldestFirst{}, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// PrepareFundTx should reserve the utxos until the fixing time,
+// and ReleaseFundTxIns should release them
+func TestPrepareFundTxLocksUtxos(t *testing.T) {
+	assert := assert.New(t)
+
+	testWallet := setupTestWallet()
+	mockSelectUnspent(testWallet, 1, DustLimit, nil)
+
+	conds := newTestConditions()
+	b := NewBuilder(FirstParty, testWallet, conds)
+
+	err := b.PrepareFundTxIns(nil)
+	assert.NoError(err)
+
+	ops := outPoints(b.dlc.fundTxReqs.txIns[b.party])
+	testWallet.AssertCalled(t, "LockUnspent", ops, conds.FixingTime)
+
+	err = b.ReleaseFundTxIns()
+	assert.NoError(err)
+	testWallet.AssertCalled(t, "UnlockUnspent", ops)
+	assert.Empty(b.dlc.fundTxReqs.txIns[b.party])
+	assert.Nil(b.dlc.fundTxReqs.txOut[b.party])
+}
+
+// PrepareFundTxIns again should release the utxos reserved before
+func TestPrepareFundTxInsAgain(t *testing.T) {
+	assert := assert.New(t)
+
+	testWallet := setupTestWallet()
+	mockSelectUnspent(testWallet, 1, DustLimit, nil)
+	b := NewBuilder(FirstParty, testWallet, newTestConditions())
+
+	err := b.PrepareFundTxIns(nil)
+	assert.NoError(err)
+	ops := outPoints(b.dlc.fundTxReqs.txIns[b.party])
+	testWallet.AssertNotCalled(t, "UnlockUnspent", mock.Anything)
+
+	err = b.PrepareFundTxIns(nil)
+	assert.NoError(err)
+	testWallet.AssertCalled(t, "UnlockUnspent", ops)
+	testWallet.AssertNumberOfCalls(t, "LockUnspent", 2)
+}
+
+// PrepareFundTxIns shouldn't leave utxos reserved or set if it fails
+func TestPrepareFundTxInsFail(t *testing.T) {
+	assert := assert.New(t)
+
+	utxo := wallet.Utxo{TxID: testTxID, Amount: 1}
+	newWallet := func() *walletmock.Wallet {
+		w := &walletmock.Wallet{}
+		w.On("SelectUnspent",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return([]wallet.Utxo{utxo}, DustLimit, nil)
+		return w
+	}
+
+	// fail to get a change pubkey
+	w := newWallet()
+	w.On("NewChangePubkey").Return(nil, errors.New("error"))
+	b := NewBuilder(FirstParty, w, newTestConditions())
+	err := b.PrepareFundTxIns(nil)
+	assert.Error(err)
+	w.AssertNotCalled(t, "LockUnspent", mock.Anything, mock.Anything)
+	assert.Empty(b.dlc.fundTxReqs.txIns[b.party])
+	assert.Nil(b.dlc.fundTxReqs.txOut[b.party])
+
+	// fail to lock utxos
+	w = newWallet()
+	_, pub := test.RandKeys()
+	w.On("NewChangePubkey").Return(pub, nil)
+	w.On("LockUnspent", mock.Anything, mock.Anything).
+		Return(errors.New("utxo is already locked"))
+	b = NewBuilder(FirstParty, w, newTestConditions())
+	err = b.PrepareFundTxIns(nil)
+	assert.Error(err)
+	assert.Empty(b.dlc.fundTxReqs.txIns[b.party])
+	assert.Nil(b.dlc.fundTxReqs.txOut[b.party])
 }
 
 // PrepareFundTx shouldn't have txouts if no changes
@@ -60,7 +157,7 @@ func TestPrepareFundTxNoChange(t *testing.T) {
 	conds := newTestConditions()
 	b := NewBuilder(FirstParty, testWallet, conds)
 
-	err := b.PrepareFundTxIns()
+	err := b.PrepareFundTxIns(nil)
 	assert.Nil(err)
 
 	txins := b.dlc.fundTxReqs.txIns[b.party]
@@ -75,16 +172,16 @@ func TestFundTx(t *testing.T) {
 
 	// first party
 	w1 := setupTestWallet()
-	w1 = mockSelectUnspent(w1, 1, 1, nil)
+	w1 = mockSelectUnspent(w1, 1, DustLimit, nil)
 	b1 := NewBuilder(FirstParty, w1, conds)
-	b1.PrepareFundTxIns()
+	b1.PrepareFundTxIns(nil)
 	b1.PreparePubkey()
 
 	// second party
 	w2 := setupTestWallet()
-	w2 = mockSelectUnspent(w2, 1, 1, nil)
+	w2 = mockSelectUnspent(w2, 1, DustLimit, nil)
 	b2 := NewBuilder(SecondParty, w2, conds)
-	b2.PrepareFundTxIns()
+	b2.PrepareFundTxIns(nil)
 	b2.PreparePubkey()
 
 	// fail if it hasn't received a pubkey from the counterparty
@@ -102,23 +199,96 @@ func TestFundTx(t *testing.T) {
 	assert.Len(tx.TxOut, 3) // 1 for reddemtx and 2 for changes
 }
 
+// txins and txouts of fund tx should be ordered by serial ids
+// and each party's txins should be found by them
+func TestFundTxSerialIDs(t *testing.T) {
+	assert := assert.New(t)
+	conds := newTestConditions()
+
+	// first party funds with 2 utxos
+	w1 := setupTestWallet()
+	utxos := []wallet.Utxo{
+		{TxID: testTxID, Vout: 0, Amount: 1},
+		{TxID: testTxID, Vout: 1, Amount: 1},
+	}
+	w1.On("SelectUnspent",
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+	).Return(utxos, DustLimit, nil)
+	w1.On("LockUnspent", mock.Anything, mock.Anything).Return(nil)
+	b1 := NewBuilder(FirstParty, w1, conds)
+	b1.PreparePubkey()
+	b1.PrepareFundTxIns(nil)
+
+	// second party funds with 1 utxo
+	w2 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b2 := NewBuilder(SecondParty, w2, conds)
+	b2.PreparePubkey()
+	b2.PrepareFundTxIns(nil)
+
+	assert.NoError(b1.CopyReqsFromCounterparty(b2.DLC()))
+	assert.NoError(b2.CopyReqsFromCounterparty(b1.DLC()))
+
+	// both parties construct the same fund tx
+	d := b1.DLC()
+	tx, err := d.FundTx()
+	assert.NoError(err)
+	tx2, _ := b2.DLC().FundTx()
+	assert.Equal(tx.TxHash(), tx2.TxHash())
+
+	desc, err := d.fundTxDescription()
+	assert.NoError(err)
+	ins := desc.sortedTxIns()
+	for i := 1; i < len(ins); i++ {
+		assert.True(ins[i-1].SerialID <= ins[i].SerialID)
+	}
+
+	// fund txout
+	fout, _ := d.fundTxOutForRedeemTx()
+	idx, err := d.fundTxOutAt()
+	assert.NoError(err)
+	assert.Equal(fout, tx.TxOut[idx])
+	idx2, _ := b2.DLC().fundTxOutAt()
+	assert.Equal(idx, idx2)
+
+	// txins of each party
+	for _, b := range []*Builder{b1, b2} {
+		idxs, err := b.fundTxInAt()
+		assert.NoError(err)
+		txins := b.dlc.fundTxReqs.txIns[b.party]
+		assert.Len(idxs, len(txins))
+		for i, idx := range idxs {
+			assert.Equal(txins[i], tx.TxIn[idx])
+		}
+	}
+
+	// each txin must have a serial id
+	reqs1 := b1.dlc.fundTxReqs
+	reqs1.txInSerialIDs[FirstParty] = reqs1.txInSerialIDs[FirstParty][:1]
+	assert.Error(b2.CopyReqsFromCounterparty(b1.DLC()))
+	assert.Error(newFundTxReqs().copyFrom(FirstParty, reqs1))
+
+	// serial ids must be unique
+	b2.dlc.fundTxReqs.txOutSerialID[SecondParty] = d.fundTxOutSerialID
+	assert.Error(b1.CopyReqsFromCounterparty(b2.DLC()))
+}
+
 func TestRedeemFundTx(t *testing.T) {
 	assert := assert.New(t)
 	conds := newTestConditions()
 
 	// init first party
 	w1 := setupTestWallet()
-	w1 = mockSelectUnspent(w1, 1, 1, nil)
+	w1 = mockSelectUnspent(w1, 1, DustLimit, nil)
 	b1 := NewBuilder(FirstParty, w1, conds)
 	b1.PreparePubkey()
-	b1.PrepareFundTxIns()
+	b1.PrepareFundTxIns(nil)
 
 	// init second party
 	w2 := setupTestWallet()
-	w2 = mockSelectUnspent(w2, 1, 1, nil)
+	w2 = mockSelectUnspent(w2, 1, DustLimit, nil)
 	b2 := NewBuilder(SecondParty, w2, conds)
 	b2.PreparePubkey()
-	b2.PrepareFundTxIns()
+	b2.PrepareFundTxIns(nil)
 
 	// exchange pubkeys
 	b1.CopyReqsFromCounterparty(b2.DLC())
@@ -142,7 +312,8 @@ func TestRedeemFundTx(t *testing.T) {
 
 	// run script
 	fundtx, _ := d.FundTx()
-	fout := fundtx.TxOut[fundTxOutAt]
+	idx, _ := d.fundTxOutAt()
+	fout := fundtx.TxOut[idx]
 	err = test.ExecuteScript(fout.PkScript, redeemtx, fout.Value)
 	assert.Nil(err)
 }
@@ -2,6 +2,7 @@ package dlc
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/dgarage/dlc/pkg/oracle"
@@ -10,6 +11,7 @@ import (
 
 // OracleRequirements contains pubkeys and commitments and sign received from oracle
 type OracleRequirements struct {
+	identity    *btcec.PublicKey   // Oracle's identity pubkey trusted by contractors
 	pubkeySet   *oracle.PubkeySet  // Oracle's pubkey set
 	commitments []*btcec.PublicKey // Commitments for deals
 	sign        []byte             // Sign for a fixed deal
@@ -30,20 +32,63 @@ func (d *DLC) PrepareOracleCommitments(
 	}
 }
 
-// SetOraclePubkeySet sets oracle's pubkey set
-func (b *Builder) SetOraclePubkeySet(pubset *oracle.PubkeySet) {
+// SetOracleIdentity pins oracle's identity pubkey,
+// the long-term pubkey of its first key epoch
+func (b *Builder) SetOracleIdentity(pub *btcec.PublicKey) {
+	b.dlc.oracleReqs.identity = pub
+}
+
+// SetOraclePubkeySet sets oracle's pubkey set.
+// If oracle's identity is pinned, the pubkey set must be announced
+// by the key of its epoch, rotated from the identity key.
+// It fails if deals use more messages than the committed R-points.
+func (b *Builder) SetOraclePubkeySet(pubset *oracle.PubkeySet) error {
+	if identity := b.dlc.oracleReqs.identity; identity != nil {
+		if err := pubset.Verify(identity); err != nil {
+			return err
+		}
+	}
+
+	nRpoints := len(pubset.CommittedRpoints)
+	if vs := b.dlc.Conds.msgViolations(nRpoints); len(vs) > 0 {
+		return newInvalidConditionsError(vs)
+	}
+
 	b.dlc.PrepareOracleCommitments(
 		pubset.Pubkey, pubset.CommittedRpoints)
 	b.dlc.oracleReqs.pubkeySet = pubset
+	return nil
 }
 
 // FixDeal fixes a deal by setting the signature provided by oracle
 func (d *DLC) FixDeal(msgs [][]byte, signs [][]byte) error {
-	dID, _, err := d.DealByMsgs(msgs)
+	if len(msgs) != len(signs) {
+		return errors.New("numbers of messages and signs don't match")
+	}
+	for i := range signs {
+		if !schnorr.IsCanonicalSign(signs[i]) {
+			return fmt.Errorf("non-canonical oracle sign at %d", i)
+		}
+	}
+
+	dID, deal, err := d.DealByMsgs(msgs)
 	if err != nil {
 		return err
 	}
 
+	// messages of legacy events are raw
+	if !deal.Legacy {
+		for i := range msgs {
+			if !oracle.IsCanonicalMsg(msgs[i]) {
+				return fmt.Errorf("non-canonical oracle message at %d", i)
+			}
+		}
+	}
+
+	// a prefix deal uses only the leading messages
+	msgs = msgs[:len(deal.Msgs)]
+	signs = signs[:len(deal.Msgs)]
+
 	C := d.oracleReqs.commitments[dID]
 	s := schnorr.SumSigns(signs)
 
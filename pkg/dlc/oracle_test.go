@@ -2,10 +2,12 @@ package dlc
 
 import (
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/dgarage/dlc/internal/oracle"
 	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/schnorr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,12 +19,37 @@ func TestSetOraclePubkeySet(t *testing.T) {
 	pubset := &oracle.PubkeySet{
 		Pubkey: pub, CommittedRpoints: []*btcec.PublicKey{R}}
 
-	b.SetOraclePubkeySet(pubset)
+	err := b.SetOraclePubkeySet(pubset)
+	assert.NoError(t, err)
 	d := b.DLC()
 
 	assert.NotNil(t, d.oracleReqs.commitments[dID])
 }
 
+func TestSetOraclePubkeySetWithIdentity(t *testing.T) {
+	assert := assert.New(t)
+
+	b, _, dID := setupContractorForOracleTest()
+
+	o := oracle.NewTestOracle()
+	id := oracle.TimeEventID(oracle.TestStream, time.Now())
+	_ = o.AddEvent(oracle.Event{ID: id, NRpoints: 1})
+	pubset, _ := o.PubkeySet(id)
+
+	// fail with another oracle's identity
+	_, other := test.RandKeys()
+	b.SetOracleIdentity(other)
+	err := b.SetOraclePubkeySet(&pubset)
+	assert.Error(err)
+	assert.Nil(b.dlc.oracleReqs.commitments[dID])
+
+	identity, _ := o.Pubkey()
+	b.SetOracleIdentity(identity)
+	err = b.SetOraclePubkeySet(&pubset)
+	assert.NoError(err)
+	assert.NotNil(b.dlc.oracleReqs.commitments[dID])
+}
+
 func TestFixDeal(t *testing.T) {
 	assert := assert.New(t)
 	var err error
@@ -34,14 +61,14 @@ func TestFixDeal(t *testing.T) {
 
 	// fail with invalid sign
 	privInvalid, _ := test.RandKeys()
-	osignsInvalid := [][]byte{privInvalid.D.Bytes()}
+	osignsInvalid := [][]byte{schnorr.ScalarBytes(privInvalid.D)}
 	osignsetInvalid := &oracle.SignSet{Msgs: deal.Msgs, Signs: osignsInvalid}
 
 	err = b.FixDeal(osignsetInvalid, []int{0})
 	assert.Error(err)
 
 	// success with valid sign and message set
-	osigns := [][]byte{privkey.D.Bytes()}
+	osigns := [][]byte{schnorr.ScalarBytes(privkey.D)}
 	osignset := &oracle.SignSet{Msgs: deal.Msgs, Signs: osigns}
 	err = b.FixDeal(osignset, []int{0})
 	assert.NoError(err)
@@ -54,19 +81,110 @@ func TestFixDeal(t *testing.T) {
 }
 
 func setupContractorForOracleTest() (*Builder, *Deal, int) {
-	conds := newTestConditions()
-
 	// set deals
-	msgs := [][]byte{{1}}
-	deal := NewDeal(1, 1, msgs)
-	conds.Deals = []*Deal{deal}
+	deal := NewOutcomeDeal(oneBTC, oneBTC, "1")
+	msgs := deal.Msgs
+	conds := newTestConditions(deal)
 
 	// init first party
 	w := setupTestWallet()
-	w = mockSelectUnspent(w, 1, 1, nil)
+	w = mockSelectUnspent(w, 1, DustLimit, nil)
 	b := NewBuilder(FirstParty, w, conds)
 
 	dID, _, _ := b.dlc.DealByMsgs(msgs)
 
 	return b, deal, dID
 }
+
+func TestFixCancelDeal(t *testing.T) {
+	assert := assert.New(t)
+
+	// deals on 2 digits and a cancellation deal
+	conds := newTestConditions(
+		NewOutcomeDeal(2*oneBTC, 0, "0", "1"),
+		NewOutcomeDeal(0, 2*oneBTC, "0", "2"),
+		NewCancelDeal(oneBTC, oneBTC, 2),
+	)
+	w := setupTestWallet()
+	b := NewBuilder(FirstParty, w, conds)
+
+	o := oracle.NewTestOracle()
+	id := oracle.TimeEventID(oracle.TestStream, time.Now())
+	_ = o.AddEvent(oracle.Event{ID: id, NRpoints: 2})
+	pubset, _ := o.PubkeySet(id)
+	_ = b.SetOraclePubkeySet(&pubset)
+
+	// oracle attests the event's cancellation
+	assert.NoError(o.CancelEvent(id))
+	signSet, _ := o.SignSet(id)
+
+	err := b.FixDeal(&signSet, []int{0, 1})
+	assert.NoError(err)
+
+	idx, deal, err := b.dlc.FixedDeal()
+	assert.NoError(err)
+	assert.Equal(2, idx)
+	assert.True(deal.IsCancel())
+	assert.False(conds.Deals[0].IsCancel())
+}
+
+func TestFixPrefixDeal(t *testing.T) {
+	assert := assert.New(t)
+
+	// deals on 3 digits grouped by leading digits
+	conds := newTestConditions(
+		NewPrefixDeal(2*oneBTC, 0, "0"),
+		NewPrefixDeal(oneBTC, oneBTC, "1", "0"),
+		NewOutcomeDeal(0, 2*oneBTC, "1", "1", "0"),
+	)
+	w := setupTestWallet()
+	b := NewBuilder(FirstParty, w, conds)
+
+	o := oracle.NewTestOracle()
+	id := oracle.TimeEventID(oracle.TestStream, time.Now())
+	e := oracle.Event{ID: id, NRpoints: 3}
+	_ = o.AddEvent(e)
+	pubset, _ := o.PubkeySet(id)
+	_ = b.SetOraclePubkeySet(&pubset)
+
+	msgs, _ := e.EncodeOutcome("105")
+	assert.NoError(o.FixMsgs(id, msgs))
+	signSet, _ := o.SignSet(id)
+
+	err := b.FixDeal(&signSet, []int{0, 1, 2})
+	assert.NoError(err)
+
+	idx, _, err := b.dlc.FixedDeal()
+	assert.NoError(err)
+	assert.Equal(1, idx)
+}
+
+func TestFixLegacyDeal(t *testing.T) {
+	assert := assert.New(t)
+
+	// deals on raw messages of a legacy event
+	conds := newTestConditions(
+		NewLegacyDeal(2*oneBTC, 0, [][]byte{{1}}),
+		NewLegacyDeal(0, 2*oneBTC, [][]byte{{2}}),
+	)
+	assert.NotNil(conds.dealIdx)
+	w := setupTestWallet()
+	b := NewBuilder(FirstParty, w, conds)
+
+	o := oracle.NewTestOracle()
+	ftime := time.Now()
+	_ = o.MigrateLegacyEvents(oracle.TestStream, 1, ftime)
+	id := oracle.TimeEventID(oracle.TestStream, ftime)
+	pubset, _ := o.PubkeySet(id)
+	assert.NoError(b.SetOraclePubkeySet(&pubset))
+
+	assert.NoError(o.FixMsgs(id, [][]byte{{2}}))
+	signSet, _ := o.SignSet(id)
+
+	err := b.FixDeal(&signSet, []int{0})
+	assert.NoError(err)
+
+	idx, _, err := b.dlc.FixedDeal()
+	assert.NoError(err)
+	assert.Equal(1, idx)
+}
@@ -0,0 +1,127 @@
+package dlc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/pkg/script"
+)
+
+// SetPayoutScript sets a script that the party's payouts of CETs,
+// closing tx and refund tx are sent to.
+// Any standard script is allowed, e.g. an address of another wallet.
+// The payouts are sent to the fund pubkey if it isn't set.
+func (b *Builder) SetPayoutScript(sc []byte) error {
+	if err := b.checkNegotiating(); err != nil {
+		return err
+	}
+	if err := checkStandardScript(sc); err != nil {
+		return err
+	}
+	b.dlc.payoutScripts[b.party] = sc
+	return nil
+}
+
+// SetPayoutAddress sets an address that the party's payouts are sent to
+func (b *Builder) SetPayoutAddress(addr btcutil.Address) error {
+	sc, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	return b.SetPayoutScript(sc)
+}
+
+// SetChangeScript sets a script that the change of fund tx is sent to.
+// It must be set before preparing fund txins.
+// The change is sent to a new change address of the wallet if it isn't set.
+func (b *Builder) SetChangeScript(sc []byte) error {
+	if err := b.checkNegotiating(); err != nil {
+		return err
+	}
+	if err := checkStandardScript(sc); err != nil {
+		return err
+	}
+	b.dlc.changeScripts[b.party] = sc
+	return nil
+}
+
+// SetChangeAddress sets an address that the change of fund tx is sent to
+func (b *Builder) SetChangeAddress(addr btcutil.Address) error {
+	sc, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	return b.SetChangeScript(sc)
+}
+
+// checkNegotiating checks that the contract is still being negotiated
+func (b *Builder) checkNegotiating() error {
+	if b.dlc.state != ContractNegotiating {
+		return fmt.Errorf("contract is %s", b.dlc.state)
+	}
+	return nil
+}
+
+// payoutScript returns the script that a given party's payouts are sent to
+func (d *DLC) payoutScript(p Contractor) ([]byte, error) {
+	if sc, ok := d.payoutScripts[p]; ok {
+		return sc, nil
+	}
+	return d.fundPubkeyScript(p)
+}
+
+// fundPubkeyScript returns a p2wpkh script of a given party's fund pubkey
+func (d *DLC) fundPubkeyScript(p Contractor) ([]byte, error) {
+	pub := d.pubs[p]
+	if pub == nil {
+		return nil, errors.New("missing pubkey")
+	}
+	return script.P2WPKHpkScript(pub)
+}
+
+// fundPubkeyTxOut returns a txout sent to a given party's fund pubkey
+func (d *DLC) fundPubkeyTxOut(
+	p Contractor, amt btcutil.Amount) (*wire.TxOut, error) {
+	pkScript, err := d.fundPubkeyScript(p)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewTxOut(int64(amt), pkScript), nil
+}
+
+// checkChangeScript checks the change script and txout of a party.
+// The change txout must be standard and pay the change script if it's set.
+func checkChangeScript(p Contractor, d *DLC) error {
+	sc, ok := d.changeScripts[p]
+	if ok {
+		if err := checkStandardScript(sc); err != nil {
+			return err
+		}
+	}
+	txout := d.fundTxReqs.txOut[p]
+	if txout == nil {
+		return nil
+	}
+	if err := checkStandardScript(txout.PkScript); err != nil {
+		return err
+	}
+	if ok && !bytes.Equal(sc, txout.PkScript) {
+		return fmt.Errorf(
+			"change txout doesn't pay the change script %x", sc)
+	}
+	return nil
+}
+
+// checkStandardScript checks if a script is a standard one
+// that can receive payouts
+func checkStandardScript(sc []byte) error {
+	switch txscript.GetScriptClass(sc) {
+	case txscript.NonStandardTy, txscript.NullDataTy:
+		return fmt.Errorf("non-standard payout script %x", sc)
+	}
+	return nil
+}
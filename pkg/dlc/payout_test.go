@@ -0,0 +1,134 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestP2PKHScript returns a p2pkh script of a random key,
+// which is a payout address out of the wallet
+func newTestP2PKHScript() []byte {
+	_, pub := test.RandKeys()
+	addr, _ := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(pub.SerializeCompressed()), &chaincfg.RegressionNetParams)
+	sc, _ := txscript.PayToAddrScript(addr)
+	return sc
+}
+
+func TestPayoutScripts(t *testing.T) {
+	assert := assert.New(t)
+
+	conds := newTestConditions()
+	conds.RefundLockTime = testLockTime
+	conds.AnchorAmt = testAnchorAmt
+	payout1, payout2 := newTestP2PKHScript(), newTestP2PKHScript()
+	change1 := newTestP2PKHScript()
+
+	w1 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b1 := NewBuilder(FirstParty, w1, conds)
+	b1.PreparePubkey()
+	assert.NoError(b1.SetPayoutScript(payout1))
+	assert.NoError(b1.SetChangeScript(change1))
+	assert.NoError(b1.PrepareFundTxIns(nil))
+
+	w2 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b2 := NewBuilder(SecondParty, w2, conds)
+	b2.PreparePubkey()
+	assert.NoError(b2.SetPayoutScript(payout2))
+	assert.NoError(b2.PrepareFundTxIns(nil))
+
+	assert.NoError(b1.CopyReqsFromCounterparty(b2.DLC()))
+	assert.NoError(b2.CopyReqsFromCounterparty(b1.DLC()))
+	d := b2.DLC()
+
+	// fund tx change
+	assert.Equal(change1, d.fundTxReqs.txOut[FirstParty].PkScript)
+	assert.Nil(b1.changePub, "change out of the wallet can't be bumped")
+
+	// refund tx
+	refundtx, err := d.RefundTx()
+	assert.NoError(err)
+	assert.Equal(payout1, refundtx.TxOut[0].PkScript)
+	assert.Equal(payout2, refundtx.TxOut[1].PkScript)
+
+	// anchors are still sent to fund pubkeys
+	for _, p := range []Contractor{FirstParty, SecondParty} {
+		_, err = d.anchorTxOutAt(p, refundtx)
+		assert.NoError(err)
+	}
+
+	// CET pays the counterparty's payout script
+	deal := conds.Deals[0]
+	_, C := test.RandKeys()
+	d.oracleReqs.commitments[0] = C
+	cetx, err := d.ContractExecutionTx(FirstParty, deal, 0)
+	assert.NoError(err)
+	assert.Equal(payout2, cetx.TxOut[1].PkScript)
+
+	// closing tx
+	cltx, err := d.ClosingTx(FirstParty, cetx)
+	assert.NoError(err)
+	assert.Equal(payout1, cltx.TxOut[0].PkScript)
+}
+
+func TestSetPayoutScriptNonStandard(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBuilder(FirstParty, setupTestWallet(), newTestConditions())
+	err := b.SetPayoutScript([]byte{txscript.OP_TRUE})
+	assert.Error(err)
+	err = b.SetChangeScript([]byte{txscript.OP_RETURN})
+	assert.Error(err)
+
+	// counterparty's script is also checked
+	d := newDLC(newTestConditions())
+	d.payoutScripts[SecondParty] = []byte{txscript.OP_TRUE}
+	err = b.CopyReqsFromCounterparty(d)
+	assert.Error(err)
+}
+
+func TestCopyReqsChangeScript(t *testing.T) {
+	assert := assert.New(t)
+
+	conds := newTestConditions()
+	w2 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b2 := NewBuilder(SecondParty, w2, conds)
+	b2.PreparePubkey()
+	change2 := newTestP2PKHScript()
+	assert.NoError(b2.SetChangeScript(change2))
+	assert.NoError(b2.PrepareFundTxIns(nil))
+	d := b2.DLC()
+
+	b1 := NewBuilder(FirstParty, setupTestWallet(), conds)
+	assert.NoError(b1.CopyReqsFromCounterparty(d))
+	assert.Equal(change2, b1.dlc.changeScripts[SecondParty])
+
+	// non-standard change script
+	d.changeScripts[SecondParty] = []byte{txscript.OP_TRUE}
+	err := b1.CopyReqsFromCounterparty(d)
+	assert.Error(err)
+
+	// change script that the change txout doesn't pay
+	d.changeScripts[SecondParty] = newTestP2PKHScript()
+	err = b1.CopyReqsFromCounterparty(d)
+	assert.Error(err)
+
+	// non-standard change txout
+	delete(d.changeScripts, SecondParty)
+	d.fundTxReqs.txOut[SecondParty].PkScript = []byte{txscript.OP_TRUE}
+	err = b1.CopyReqsFromCounterparty(d)
+	assert.Error(err)
+}
+
+func TestSetPayoutScriptAfterNegotiation(t *testing.T) {
+	b1, _, _ := setupDLCRefund()
+	b1.dlc.state = ContractFundSigned
+
+	err := b1.SetPayoutScript(newTestP2PKHScript())
+	assert.Error(t, err)
+}
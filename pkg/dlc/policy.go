@@ -0,0 +1,92 @@
+package dlc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// DustLimit is the minimum amount of a txout in redeem txs.
+// It's the dust threshold of a p2pkh output at the default relay fee,
+// so that it's above the thresholds of p2wpkh and p2wsh outputs.
+const DustLimit btcutil.Amount = 546
+
+// maxStandardTxWeight is the max weight of a tx relayed by nodes
+const maxStandardTxWeight = 400000
+
+// DustPolicy decides how to handle an output below the dust limit
+type DustPolicy int
+
+const (
+	// DustToFee drops a dust output and pays it to miners as fee
+	DustToFee DustPolicy = iota
+	// DustToCounterparty moves a dust output to the other party's output
+	DustToCounterparty
+)
+
+func isDust(amt btcutil.Amount) bool {
+	return amt < DustLimit
+}
+
+// settleDust applies the dust policy to amounts of a party and the other.
+// The amount of a dropped output is returned as 0.
+func (conds *Conditions) settleDust(
+	amt, otherAmt btcutil.Amount) (btcutil.Amount, btcutil.Amount) {
+	dust, otherDust := isDust(amt), isDust(otherAmt)
+
+	if conds.DustPolicy == DustToCounterparty {
+		if dust && !otherDust {
+			return 0, otherAmt + amt
+		}
+		if otherDust && !dust {
+			return amt + otherAmt, 0
+		}
+	}
+
+	if dust {
+		amt = 0
+	}
+	if otherDust {
+		otherAmt = 0
+	}
+	return amt, otherAmt
+}
+
+// checkStandardTx checks if a tx is relayed by nodes by default.
+// A tx must be checked before signing it,
+// since a non-standard tx can't be broadcasted after the contract.
+func checkStandardTx(tx *wire.MsgTx) error {
+	if tx.Version > txVersion || tx.Version < 1 {
+		return fmt.Errorf("non-standard tx version %d", tx.Version)
+	}
+
+	if len(tx.TxOut) == 0 {
+		return fmt.Errorf("tx has no outputs")
+	}
+
+	weight := txWeight(tx)
+	if weight > maxStandardTxWeight {
+		return fmt.Errorf(
+			"tx weight %d exceeds %d", weight, maxStandardTxWeight)
+	}
+
+	for i, txout := range tx.TxOut {
+		class := txscript.GetScriptClass(txout.PkScript)
+		if class == txscript.NonStandardTy {
+			return fmt.Errorf("non-standard script in txout %d", i)
+		}
+		if isDust(btcutil.Amount(txout.Value)) {
+			return fmt.Errorf(
+				"txout %d is below dust limit. amount: %d", i, txout.Value)
+		}
+	}
+
+	return nil
+}
+
+// txWeight returns weight of a tx. weight = base size * 3 + total size
+func txWeight(tx *wire.MsgTx) int {
+	return tx.SerializeSizeStripped()*3 + tx.SerializeSize()
+}
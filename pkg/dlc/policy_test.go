@@ -0,0 +1,61 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/script"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettleDust(t *testing.T) {
+	assert := assert.New(t)
+
+	dust := DustLimit - 1
+	conds := &Conditions{}
+	tests := []struct {
+		policy       DustPolicy
+		amt1, amt2   btcutil.Amount
+		want1, want2 btcutil.Amount
+	}{
+		{DustToFee, oneBTC, oneBTC, oneBTC, oneBTC},
+		{DustToFee, oneBTC, dust, oneBTC, 0},
+		{DustToFee, dust, dust, 0, 0},
+		{DustToCounterparty, oneBTC, dust, oneBTC + dust, 0},
+		{DustToCounterparty, dust, oneBTC, 0, oneBTC + dust},
+		{DustToCounterparty, dust, dust, 0, 0},
+		{DustToCounterparty, 0, oneBTC, 0, oneBTC},
+	}
+	for _, tt := range tests {
+		conds.DustPolicy = tt.policy
+		amt1, amt2 := conds.settleDust(tt.amt1, tt.amt2)
+		assert.Equal(tt.want1, amt1)
+		assert.Equal(tt.want2, amt2)
+	}
+}
+
+func TestCheckStandardTx(t *testing.T) {
+	assert := assert.New(t)
+
+	_, pub := test.RandKeys()
+	pkScript, _ := script.P2WPKHpkScript(pub)
+
+	tx := wire.NewMsgTx(txVersion)
+	assert.Error(checkStandardTx(tx)) // no outputs
+
+	tx.AddTxOut(wire.NewTxOut(int64(DustLimit), pkScript))
+	assert.NoError(checkStandardTx(tx))
+
+	tx.AddTxOut(wire.NewTxOut(int64(DustLimit-1), pkScript))
+	assert.Error(checkStandardTx(tx)) // dust
+
+	tx.TxOut = tx.TxOut[:1]
+	tx.AddTxOut(wire.NewTxOut(int64(DustLimit), []byte{0xff}))
+	assert.Error(checkStandardTx(tx)) // non-standard script
+
+	tx.TxOut = tx.TxOut[:1]
+	tx.Version = txVersion + 1
+	assert.Error(checkStandardTx(tx))
+}
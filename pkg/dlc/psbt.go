@@ -0,0 +1,207 @@
+package dlc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/pkg/psbt"
+)
+
+// ExternalTxIn is a fund txin owned by a signer out of the wallet,
+// such as a hardware wallet or a multisig vault
+type ExternalTxIn struct {
+	OutPoint    wire.OutPoint
+	Utxo        *wire.TxOut             // txout being spent
+	PrevTx      *wire.MsgTx             // tx of the utxo (option)
+	Derivations []*psbt.Bip32Derivation // BIP32 paths of keys signing the txin
+}
+
+// utxo returns the txout spent by an external txin.
+// It's taken from the previous tx if the utxo isn't given,
+// and must be a native segwit output so that the fund txid doesn't change.
+func (in *ExternalTxIn) utxo() (*wire.TxOut, error) {
+	utxo := in.Utxo
+	if tx := in.PrevTx; tx != nil {
+		if tx.TxHash() != in.OutPoint.Hash ||
+			int(in.OutPoint.Index) >= len(tx.TxOut) {
+			return nil, fmt.Errorf(
+				"previous tx doesn't have utxo %v", in.OutPoint)
+		}
+		prev := tx.TxOut[in.OutPoint.Index]
+		if utxo == nil {
+			utxo = prev
+		} else if utxo.Value != prev.Value ||
+			!bytes.Equal(utxo.PkScript, prev.PkScript) {
+			return nil, fmt.Errorf(
+				"utxo of %v doesn't match the previous tx", in.OutPoint)
+		}
+	}
+	if utxo == nil {
+		return nil, fmt.Errorf("missing utxo of %v", in.OutPoint)
+	}
+	if !txscript.IsWitnessProgram(utxo.PkScript) {
+		return nil, fmt.Errorf(
+			"utxo of %v isn't native segwit", in.OutPoint)
+	}
+	return utxo, nil
+}
+
+// PrepareExternalFundTxIns prepares fund txins with utxos out of the wallet.
+// The fee of each txin is estimated in the same way as wallet utxos.
+// The txins have to be signed by exporting the fund tx as a PSBT
+// with FundPSBT and importing the signed one with AcceptSignedFundPSBT.
+func (b *Builder) PrepareExternalFundTxIns(ins []*ExternalTxIn) error {
+	if len(ins) == 0 {
+		return errors.New("no external txins")
+	}
+
+	var total btcutil.Amount
+	var txins []*wire.TxIn
+	for _, in := range ins {
+		utxo, err := in.utxo()
+		if err != nil {
+			return err
+		}
+		in.Utxo = utxo
+		total += btcutil.Amount(utxo.Value)
+		txin := wire.NewTxIn(&in.OutPoint, nil, nil)
+		txin.Sequence = fundTxInSequence
+		txins = append(txins, txin)
+	}
+
+	famt := b.dlc.Conds.FundAmts[b.party]
+	required := famt + b.dlc.fundTxFeeBase(1) +
+		b.dlc.redeemTxFee(cetxSize) + b.dlc.anchorCost() +
+		b.dlc.fundTxFeePerTxIn().MulF64(float64(len(ins)))
+	if total < required {
+		return fmt.Errorf(
+			"external utxos aren't enough. total: %d, required: %d",
+			total, required)
+	}
+
+	// release wallet utxos reserved before
+	if err := b.ReleaseFundTxIns(); err != nil {
+		return err
+	}
+
+	change := total - required - b.dlc.fundTxFeePerTxOut()
+	if isDust(change) {
+		change = 0
+	}
+	if err := b.setFundTxIns(txins, change); err != nil {
+		b.resetFundTxIns()
+		return err
+	}
+	b.externalTxIns = ins
+	for _, in := range ins {
+		b.fundTxInAmts[in.OutPoint] = btcutil.Amount(in.Utxo.Value)
+	}
+	return nil
+}
+
+// FundPSBT exports the unsigned fund tx as a PSBT with the utxos,
+// the previous txs if given, and the BIP32 paths of the party's external txins
+func (b *Builder) FundPSBT() (*psbt.Packet, error) {
+	if len(b.externalTxIns) == 0 {
+		return nil, errors.New("no external fund txins")
+	}
+
+	tx, err := b.unsignedFundTx()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	idxs, err := b.fundTxInAt()
+	if err != nil {
+		return nil, err
+	}
+	for i, idx := range idxs {
+		in := b.externalTxIns[i]
+		p.Inputs[idx].WitnessUtxo = in.Utxo
+		p.Inputs[idx].Bip32Derivation = in.Derivations
+		if in.PrevTx != nil {
+			var buf bytes.Buffer
+			if err := in.PrevTx.Serialize(&buf); err != nil {
+				return nil, err
+			}
+			p.Inputs[idx].NonWitnessUtxo = buf.Bytes()
+		}
+	}
+	return p, nil
+}
+
+// AcceptSignedFundPSBT imports witnesses of the party's external txins
+// from a signed PSBT of the fund tx, and returns them like SignFundTx
+func (b *Builder) AcceptSignedFundPSBT(
+	p *psbt.Packet) ([]wire.TxWitness, error) {
+	if err := b.dlc.canTransit(ContractFundSigned); err != nil {
+		return nil, err
+	}
+	if len(b.externalTxIns) == 0 {
+		return nil, errors.New("no external fund txins")
+	}
+
+	tx, err := b.unsignedFundTx()
+	if err != nil {
+		return nil, err
+	}
+	if p.UnsignedTx == nil || p.UnsignedTx.TxHash() != tx.TxHash() {
+		return nil, errors.New("psbt isn't of the fund tx")
+	}
+
+	idxs, err := b.fundTxInAt()
+	if err != nil {
+		return nil, err
+	}
+	var wits []wire.TxWitness
+	for i, idx := range idxs {
+		wit, err := p.Inputs[idx].Witness()
+		if err != nil {
+			return nil, fmt.Errorf("txin %d: %v", idx, err)
+		}
+
+		// verify the witness
+		tx.TxIn[idx].Witness = wit
+		utxo := b.externalTxIns[i].Utxo
+		vm, err := txscript.NewEngine(utxo.PkScript, tx, idx,
+			txscript.StandardVerifyFlags, nil, nil, utxo.Value)
+		if err != nil {
+			return nil, err
+		}
+		if err = vm.Execute(); err != nil {
+			return nil, fmt.Errorf("txin %d: invalid witness. %v", idx, err)
+		}
+
+		wits = append(wits, wit)
+	}
+
+	// set witnesses to txins
+	for i, wit := range wits {
+		b.dlc.fundTxReqs.txIns[b.party][i].Witness = wit
+	}
+
+	return wits, b.dlc.transit(ContractFundSigned)
+}
+
+// unsignedFundTx returns fund tx without witnesses
+func (b *Builder) unsignedFundTx() (*wire.MsgTx, error) {
+	fundtx, err := b.dlc.FundTx()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := fundtx.Copy()
+	for _, txin := range tx.TxIn {
+		txin.Witness = nil
+	}
+	return tx, nil
+}
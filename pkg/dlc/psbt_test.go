@@ -0,0 +1,234 @@
+package dlc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/psbt"
+	"github.com/dgarage/dlc/pkg/script"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupExternalFunding sets up a contract
+// in which the first party funds with a p2wpkh utxo out of the wallet
+func setupExternalFunding(
+	t *testing.T) (b1, b2 *Builder, priv *btcec.PrivateKey) {
+	conds := newTestConditions()
+
+	priv, in := newTestExternalTxIn()
+
+	b1 = NewBuilder(FirstParty, setupTestWallet(), conds)
+	b1.PreparePubkey()
+	err := b1.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.NoError(t, err)
+
+	w2 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b2 = NewBuilder(SecondParty, w2, conds)
+	b2.PreparePubkey()
+	b2.PrepareFundTxIns(nil)
+
+	b1.CopyReqsFromCounterparty(b2.DLC())
+	b2.CopyReqsFromCounterparty(b1.DLC())
+
+	return b1, b2, priv
+}
+
+// newTestExternalTxIn creates an external txin of a 2 BTC p2wpkh utxo
+func newTestExternalTxIn() (*btcec.PrivateKey, *ExternalTxIn) {
+	priv, pub := test.RandKeys()
+	pkScript, _ := script.P2WPKHpkScript(pub)
+	in := &ExternalTxIn{
+		OutPoint: *wire.NewOutPoint(&chainhash.Hash{1}, 0),
+		Utxo:     wire.NewTxOut(int64(2*oneBTC), pkScript),
+		Derivations: []*psbt.Bip32Derivation{{
+			PubKey:      pub.SerializeCompressed(),
+			Fingerprint: 1,
+			Path:        []uint32{0x80000054, 0x80000000, 0x80000000, 0, 0},
+		}},
+	}
+	return priv, in
+}
+
+// PrepareExternalFundTxIns should release wallet utxos reserved before
+func TestPrepareExternalFundTxInsAfterWallet(t *testing.T) {
+	assert := assert.New(t)
+
+	w := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b := NewBuilder(FirstParty, w, newTestConditions())
+	assert.NoError(b.PrepareFundTxIns(nil))
+	ops := outPoints(b.dlc.fundTxReqs.txIns[b.party])
+
+	_, in := newTestExternalTxIn()
+	assert.NoError(b.PrepareExternalFundTxIns([]*ExternalTxIn{in}))
+	w.AssertCalled(t, "UnlockUnspent", ops)
+	assert.Equal(
+		[]wire.OutPoint{in.OutPoint}, outPoints(b.dlc.fundTxReqs.txIns[b.party]))
+
+	// external utxos aren't unlocked in the wallet
+	assert.NoError(b.ReleaseFundTxIns())
+	w.AssertNumberOfCalls(t, "UnlockUnspent", 1)
+}
+
+func TestFundPSBT(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, priv := setupExternalFunding(t)
+
+	// change of the external utxo
+	change := b1.dlc.fundTxReqs.txOut[FirstParty]
+	assert.NotNil(change)
+	assert.True(change.Value < int64(oneBTC))
+
+	// export
+	p, err := b1.FundPSBT()
+	assert.NoError(err)
+	idxs, _ := b1.fundTxInAt()
+	idx := idxs[0]
+	in := p.Inputs[idx]
+	assert.Equal(b1.externalTxIns[0].Utxo, in.WitnessUtxo)
+	assert.Equal(b1.externalTxIns[0].Derivations, in.Bip32Derivation)
+	assert.Nil(p.Inputs[1-idx].WitnessUtxo, "counterparty's txin")
+
+	// the wallet can't sign external txins
+	_, err = b1.SignFundTx()
+	assert.Error(err)
+
+	// sign by the external signer
+	s, _ := p.B64Encode()
+	p, _ = psbt.B64Decode(s)
+	wit, err := txscript.WitnessSignature(
+		p.UnsignedTx, txscript.NewTxSigHashes(p.UnsignedTx), idx,
+		in.WitnessUtxo.Value, in.WitnessUtxo.PkScript,
+		txscript.SigHashAll, priv, true)
+	assert.NoError(err)
+	p.Inputs[idx].PartialSigs = []*psbt.PartialSig{
+		{PubKey: wit[1], Signature: wit[0]}}
+
+	// import
+	wits, err := b1.AcceptSignedFundPSBT(p)
+	assert.NoError(err)
+	assert.Len(wits, 1)
+	assert.Equal(wit, b1.dlc.fundTxReqs.txIns[FirstParty][0].Witness)
+	assert.Equal(ContractFundSigned, b1.dlc.State())
+}
+
+func TestAcceptSignedFundPSBTInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, _ := setupExternalFunding(t)
+	p, _ := b1.FundPSBT()
+
+	// not signed
+	_, err := b1.AcceptSignedFundPSBT(p)
+	assert.Error(err)
+
+	// signed by a wrong key
+	wrongPriv, wrongPub := test.RandKeys()
+	idxs, _ := b1.fundTxInAt()
+	idx := idxs[0]
+	in := p.Inputs[idx]
+	wit, _ := txscript.WitnessSignature(
+		p.UnsignedTx, txscript.NewTxSigHashes(p.UnsignedTx), idx,
+		in.WitnessUtxo.Value, in.WitnessUtxo.PkScript,
+		txscript.SigHashAll, wrongPriv, true)
+	in.PartialSigs = []*psbt.PartialSig{
+		{PubKey: wrongPub.SerializeCompressed(), Signature: wit[0]}}
+	_, err = b1.AcceptSignedFundPSBT(p)
+	assert.Error(err)
+
+	// psbt of another tx
+	p.UnsignedTx.LockTime++
+	_, err = b1.AcceptSignedFundPSBT(p)
+	assert.Error(err)
+
+	assert.Equal(ContractNegotiating, b1.dlc.State())
+}
+
+func TestPrepareExternalFundTxInsNotEnough(t *testing.T) {
+	b := NewBuilder(FirstParty, setupTestWallet(), newTestConditions())
+	_, pub := test.RandKeys()
+	pkScript, _ := script.P2WPKHpkScript(pub)
+	in := &ExternalTxIn{
+		OutPoint: *wire.NewOutPoint(&chainhash.Hash{1}, 0),
+		Utxo:     wire.NewTxOut(int64(oneBTC/2), pkScript),
+	}
+	err := b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.Error(t, err)
+	assert.Empty(t, b.dlc.fundTxReqs.txIns[FirstParty])
+}
+
+func TestPrepareExternalFundTxInsNonSegwit(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBuilder(FirstParty, setupTestWallet(), newTestConditions())
+	in := &ExternalTxIn{
+		OutPoint: *wire.NewOutPoint(&chainhash.Hash{1}, 0),
+		Utxo:     wire.NewTxOut(int64(2*oneBTC), newTestP2PKHScript()),
+	}
+	err := b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.Error(err)
+
+	// p2sh-wrapped segwit isn't native segwit
+	_, pub := test.RandKeys()
+	wpkh, _ := script.P2WPKHpkScript(pub)
+	p2sh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(wpkh)).AddOp(txscript.OP_EQUAL).Script()
+	in.Utxo = wire.NewTxOut(int64(2*oneBTC), p2sh)
+	err = b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.Error(err)
+	assert.Empty(b.dlc.fundTxReqs.txIns[FirstParty])
+}
+
+func TestFundPSBTPrevTx(t *testing.T) {
+	assert := assert.New(t)
+
+	_, pub := test.RandKeys()
+	pkScript, _ := script.P2WPKHpkScript(pub)
+	prevtx := wire.NewMsgTx(2)
+	prevtx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 0), nil, nil))
+	prevtx.AddTxOut(wire.NewTxOut(1000, pkScript))
+	prevtx.AddTxOut(wire.NewTxOut(int64(2*oneBTC), pkScript))
+
+	// the utxo is taken from the previous tx
+	in := &ExternalTxIn{
+		OutPoint: *wire.NewOutPoint(&chainhash.Hash{1}, 1),
+		PrevTx:   prevtx,
+	}
+	conds := newTestConditions()
+	b := NewBuilder(FirstParty, setupTestWallet(), conds)
+	b.PreparePubkey()
+	err := b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.Error(err, "previous tx of another txid")
+
+	in.OutPoint.Hash = prevtx.TxHash()
+	in.Utxo = wire.NewTxOut(1000, pkScript)
+	err = b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.Error(err, "utxo that doesn't match the previous tx")
+
+	in.Utxo = nil
+	err = b.PrepareExternalFundTxIns([]*ExternalTxIn{in})
+	assert.NoError(err)
+	assert.Equal(prevtx.TxOut[1], in.Utxo)
+
+	w2 := mockSelectUnspent(setupTestWallet(), 1, DustLimit, nil)
+	b2 := NewBuilder(SecondParty, w2, conds)
+	b2.PreparePubkey()
+	b2.PrepareFundTxIns(nil)
+	assert.NoError(b.CopyReqsFromCounterparty(b2.DLC()))
+
+	// exported as the non-witness utxo
+	p, err := b.FundPSBT()
+	assert.NoError(err)
+	idxs, _ := b.fundTxInAt()
+	idx := idxs[0]
+	var buf bytes.Buffer
+	prevtx.Serialize(&buf)
+	assert.Equal(buf.Bytes(), p.Inputs[idx].NonWitnessUtxo)
+	assert.Equal(prevtx.TxOut[1], p.Inputs[idx].WitnessUtxo)
+}
@@ -7,16 +7,19 @@ import (
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 )
 
 // RefundTx creates refund tx
 // refund transaction
 // input:
-//   [0]:fund transaction output[0]
+//   [0]:fund transaction output of the contract
 //       Sequence (0xfeffffff LE)
 // output:
-//   [0]:p2wpkh a
-//   [1]:p2wpkh b
+//   [0]:payout a
+//   [1]:payout b
+//   [last 2]:anchors of both parties (option)
+//   A dust output is settled by the dust policy of the conditions.
 // locktime:
 //    Value decided by contract.
 func (d *DLC) RefundTx() (*wire.MsgTx, error) {
@@ -29,9 +32,16 @@ func (d *DLC) RefundTx() (*wire.MsgTx, error) {
 	tx.TxIn[fundTxInAt].Sequence-- // max(0xffffffff-0x01)
 	tx.LockTime = d.Conds.RefundLockTime
 
+	amts := make(map[Contractor]btcutil.Amount)
+	amts[FirstParty], amts[SecondParty] = d.Conds.settleDust(
+		d.Conds.FundAmts[FirstParty], d.Conds.FundAmts[SecondParty])
+
 	// txouts
 	for _, p := range []Contractor{FirstParty, SecondParty} {
-		txout, err := d.ClosingTxOut(p, d.Conds.FundAmts[p])
+		if amts[p] == 0 {
+			continue
+		}
+		txout, err := d.ClosingTxOut(p, amts[p])
 
 		if err != nil {
 			fmt.Printf("err in closing tx out:   %+v\n", err)
@@ -40,16 +50,32 @@ func (d *DLC) RefundTx() (*wire.MsgTx, error) {
 		tx.AddTxOut(txout)
 	}
 
+	// anchors
+	if err = d.addAnchorTxOuts(tx); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
 // SignRefundTx creates signature for a refund tx, sets it, and returns it
 func (b *Builder) SignRefundTx() ([]byte, error) {
+	if err := b.dlc.checkActive(); err != nil {
+		return nil, err
+	}
+	if err := b.verifyCounterpartyFundTxInsOnce(); err != nil {
+		return nil, err
+	}
+
 	tx, err := b.dlc.RefundTx()
 	if err != nil {
 		return nil, err
 	}
 
+	if err = checkStandardTx(tx); err != nil {
+		return nil, err
+	}
+
 	sign, err := b.witsigForFundScript(tx)
 	if err != nil {
 		return nil, err
@@ -138,10 +164,14 @@ func (d *DLC) VerifyRefundTx(sign []byte, pub *btcec.PublicKey) error {
 	if err != nil {
 		return err
 	}
-	amt := fundtx.TxOut[0].Value
+	idx, err := d.fundTxOutAt()
+	if err != nil {
+		return err
+	}
+	amt := fundtx.TxOut[idx].Value
 
 	hash, err := txscript.CalcWitnessSigHash(script, sighashes, txscript.SigHashAll,
-		tx, 0, amt)
+		tx, fundTxInAt, amt)
 	if err != nil {
 		return err
 	}
@@ -156,11 +186,18 @@ func (d *DLC) VerifyRefundTx(sign []byte, pub *btcec.PublicKey) error {
 
 // SendRefundTx sends refund tx
 func (b *Builder) SendRefundTx() error {
+	if err := b.dlc.canTransit(ContractClosed); err != nil {
+		return err
+	}
+
 	tx, err := b.dlc.SignedRefundTx()
 	if err != nil {
 		return err
 	}
 
 	_, err = b.wallet.SendRawTransaction(tx)
-	return err
+	if err != nil {
+		return err
+	}
+	return b.dlc.transit(ContractClosed)
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-const testLockTime = uint32(1541951794) // 2018/11/11 3:46pm (UTC)
+const testLockTime = uint32(4102444800) // 2100/01/01 0:00am (UTC)
 
 func setupDLCRefund() (party1, party2 *Builder, d *DLC) {
 	conds := newTestConditions()
@@ -15,17 +15,17 @@ func setupDLCRefund() (party1, party2 *Builder, d *DLC) {
 
 	// init first party
 	w1 := setupTestWallet()
-	w1 = mockSelectUnspent(w1, 1, 1, nil)
+	w1 = mockSelectUnspent(w1, 1, DustLimit, nil)
 	b1 := NewBuilder(FirstParty, w1, conds)
 	b1.PreparePubkey()
-	b1.PrepareFundTxIns()
+	b1.PrepareFundTxIns(nil)
 
 	// init second party
 	w2 := setupTestWallet()
-	w2 = mockSelectUnspent(w2, 1, 1, nil)
+	w2 = mockSelectUnspent(w2, 1, DustLimit, nil)
 	b2 := NewBuilder(SecondParty, w2, conds)
 	b2.PreparePubkey()
-	b2.PrepareFundTxIns()
+	b2.PrepareFundTxIns(nil)
 
 	// exchange pubkeys
 	b1.CopyReqsFromCounterparty(b2.DLC())
@@ -91,7 +91,22 @@ func TestRefundTxOutput(t *testing.T) {
 	assert.Nil(err)
 
 	fundtx, _ := d.FundTx()
-	fout := fundtx.TxOut[fundTxOutAt]
+	idx, _ := d.fundTxOutAt()
+	fout := fundtx.TxOut[idx]
 	err = test.ExecuteScript(fout.PkScript, refundtx, fout.Value)
 	assert.Nil(err)
 }
+
+func TestRefundTxDust(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, d := setupDLCRefund()
+	d.Conds.FundAmts[SecondParty] = DustLimit - 1
+	d.Conds.DustPolicy = DustToCounterparty
+
+	refundtx, err := d.RefundTx()
+	assert.NoError(err)
+	assert.Len(refundtx.TxOut, 1)
+	total := d.Conds.FundAmts[FirstParty] + d.Conds.FundAmts[SecondParty]
+	assert.Equal(int64(total), refundtx.TxOut[0].Value)
+}
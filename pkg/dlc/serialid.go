@@ -0,0 +1,155 @@
+package dlc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Txins and txouts of fund tx are ordered by serial ids,
+// which are random numbers chosen by the party owning them.
+// The fund txout's serial id is chosen by the first party.
+// The order doesn't reveal which txins and txouts belong to which party.
+
+// newSerialID returns a random serial id
+func newSerialID() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// setTxIns sets a party's fund txins with random serial ids
+func (r *FundTxRequirements) setTxIns(
+	p Contractor, txins []*wire.TxIn) error {
+	var ids []uint64
+	for range txins {
+		id, err := newSerialID()
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	r.txIns[p] = txins
+	r.txInSerialIDs[p] = ids
+	return nil
+}
+
+// setTxOut sets a party's change txout with a random serial id
+func (r *FundTxRequirements) setTxOut(p Contractor, txout *wire.TxOut) error {
+	var id uint64
+	if txout != nil {
+		var err error
+		if id, err = newSerialID(); err != nil {
+			return err
+		}
+	}
+	r.txOut[p] = txout
+	r.txOutSerialID[p] = id
+	return nil
+}
+
+// txInSerialID returns the serial id of a party's i-th txin
+func (r *FundTxRequirements) txInSerialID(p Contractor, i int) uint64 {
+	ids := r.txInSerialIDs[p]
+	if i >= len(ids) {
+		return 0
+	}
+	return ids[i]
+}
+
+// copyFrom copies a party's txins and change txout with their serial ids.
+// Witnesses aren't copied, which are accepted by AcceptFundWitnesses.
+// It fails if the txins don't have one serial id each.
+func (r *FundTxRequirements) copyFrom(
+	p Contractor, src *FundTxRequirements) error {
+	if len(src.txInSerialIDs[p]) != len(src.txIns[p]) {
+		return fmt.Errorf(
+			"number of txin serial ids doesn't match. ids: %d, txins: %d",
+			len(src.txInSerialIDs[p]), len(src.txIns[p]))
+	}
+
+	var txins []*wire.TxIn
+	for _, txin := range src.txIns[p] {
+		in := wire.NewTxIn(&txin.PreviousOutPoint, nil, nil)
+		in.Sequence = txin.Sequence
+		txins = append(txins, in)
+	}
+	r.txIns[p] = txins
+	r.txInSerialIDs[p] = append([]uint64{}, src.txInSerialIDs[p]...)
+
+	var txout *wire.TxOut
+	if out := src.txOut[p]; out != nil {
+		txout = wire.NewTxOut(out.Value, append([]byte{}, out.PkScript...))
+	}
+	r.txOut[p] = txout
+	r.txOutSerialID[p] = src.txOutSerialID[p]
+	return nil
+}
+
+// checkSerialIDs checks if serial ids in the contract are unique
+func (d *DLC) checkSerialIDs() error {
+	txinIDs := make(map[uint64]bool)
+	txoutIDs := map[uint64]bool{d.fundTxOutSerialID: true}
+	for _, p := range []Contractor{FirstParty, SecondParty} {
+		for i := range d.fundTxReqs.txIns[p] {
+			id := d.fundTxReqs.txInSerialID(p, i)
+			if txinIDs[id] {
+				return fmt.Errorf("duplicate txin serial id %d", id)
+			}
+			txinIDs[id] = true
+		}
+		if d.fundTxReqs.txOut[p] != nil {
+			id := d.fundTxReqs.txOutSerialID[p]
+			if txoutIDs[id] {
+				return fmt.Errorf("duplicate txout serial id %d", id)
+			}
+			txoutIDs[id] = true
+		}
+	}
+	return nil
+}
+
+// checkSerialIDs checks if serial ids in the description are unique
+func (desc *BatchDescription) checkSerialIDs() error {
+	txinIDs := make(map[uint64]bool)
+	for _, in := range desc.TxIns {
+		if txinIDs[in.SerialID] {
+			return fmt.Errorf("duplicate txin serial id %d", in.SerialID)
+		}
+		txinIDs[in.SerialID] = true
+	}
+	txoutIDs := make(map[uint64]bool)
+	for _, out := range desc.sortedTxOuts() {
+		if txoutIDs[out.SerialID] {
+			return fmt.Errorf("duplicate txout serial id %d", out.SerialID)
+		}
+		txoutIDs[out.SerialID] = true
+	}
+	return nil
+}
+
+// sortedTxIns returns the described txins ordered by serial ids
+func (desc *BatchDescription) sortedTxIns() []BatchTxIn {
+	ins := append([]BatchTxIn{}, desc.TxIns...)
+	sort.SliceStable(ins, func(i, j int) bool {
+		return ins[i].SerialID < ins[j].SerialID
+	})
+	return ins
+}
+
+// sortedTxOuts returns the described fund and change txouts
+// ordered by serial ids
+func (desc *BatchDescription) sortedTxOuts() []BatchTxOut {
+	var outs []BatchTxOut
+	outs = append(outs, desc.FundOuts...)
+	outs = append(outs, desc.ChangeOuts...)
+	sort.SliceStable(outs, func(i, j int) bool {
+		return outs[i].SerialID < outs[j].SerialID
+	})
+	return outs
+}
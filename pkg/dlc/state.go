@@ -0,0 +1,92 @@
+package dlc
+
+import "fmt"
+
+// ContractState is a state of a contract
+type ContractState int
+
+const (
+	// ContractNegotiating is a state before signing fund tx
+	ContractNegotiating ContractState = iota
+	// ContractFundSigned is a state after signing fund tx
+	ContractFundSigned
+	// ContractFundSent is a state after sending fund tx
+	ContractFundSent
+	// ContractClosed is a state after sending a CET or refund tx
+	ContractClosed
+	// ContractCancelled is a state after a double-spend of fund txins
+	// is confirmed, or after cancelling the negotiation
+	ContractCancelled
+	// ContractCancelling is a state after sending a double-spend of fund txins
+	// until either it or fund tx is confirmed
+	ContractCancelling
+)
+
+func (s ContractState) String() string {
+	switch s {
+	case ContractNegotiating:
+		return "negotiating"
+	case ContractFundSigned:
+		return "fund signed"
+	case ContractFundSent:
+		return "fund sent"
+	case ContractClosed:
+		return "closed"
+	case ContractCancelled:
+		return "cancelled"
+	case ContractCancelling:
+		return "cancelling"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// contractTransitions are states that each state can move to
+var contractTransitions = map[ContractState][]ContractState{
+	ContractNegotiating: {ContractFundSigned, ContractCancelled},
+	ContractFundSigned:  {ContractFundSent, ContractClosed, ContractCancelling},
+	ContractFundSent:    {ContractClosed, ContractCancelling},
+	// fund tx may still win against the double-spend
+	ContractCancelling: {ContractFundSent, ContractClosed, ContractCancelled},
+}
+
+// State returns the state of the contract
+func (d *DLC) State() ContractState {
+	return d.state
+}
+
+// canTransit checks if the contract can move to a given state.
+// Staying in the same state is allowed except for the final states.
+func (d *DLC) canTransit(to ContractState) error {
+	from := d.state
+	nexts, ok := contractTransitions[from]
+	if !ok {
+		return newInvalidStateError(from, to)
+	}
+	if from == to {
+		return nil
+	}
+	for _, next := range nexts {
+		if next == to {
+			return nil
+		}
+	}
+	return newInvalidStateError(from, to)
+}
+
+// transit moves the contract to a given state
+func (d *DLC) transit(to ContractState) error {
+	if err := d.canTransit(to); err != nil {
+		return err
+	}
+	d.state = to
+	return nil
+}
+
+// checkActive checks that the contract is neither closed nor cancelled,
+// nor being cancelled
+func (d *DLC) checkActive() error {
+	if _, ok := contractTransitions[d.state]; !ok || d.state == ContractCancelling {
+		return fmt.Errorf("contract is %s", d.state)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package dlc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestContractStateTransit(t *testing.T) {
+	assert := assert.New(t)
+
+	d := newDLC(newTestConditions())
+	assert.Equal(ContractNegotiating, d.State())
+
+	assert.NoError(d.transit(ContractFundSigned))
+	assert.NoError(d.transit(ContractFundSigned)) // signs again
+	assert.Error(d.transit(ContractNegotiating))
+	assert.NoError(d.transit(ContractFundSent))
+	assert.NoError(d.transit(ContractClosed))
+
+	// closed contract never moves
+	err := d.transit(ContractClosed)
+	assert.IsType(&InvalidStateError{}, err)
+	assert.Error(d.transit(ContractCancelled))
+	assert.Error(d.checkActive())
+}
+
+func TestContractStateCancelling(t *testing.T) {
+	assert := assert.New(t)
+
+	d := newDLC(newTestConditions())
+	assert.Error(d.transit(ContractCancelling))
+	assert.NoError(d.transit(ContractFundSigned))
+	assert.NoError(d.transit(ContractCancelling))
+	assert.Error(d.checkActive())
+
+	// fund tx may win against the double-spend
+	assert.NoError(d.transit(ContractFundSent))
+	assert.NoError(d.transit(ContractCancelling))
+	assert.NoError(d.transit(ContractCancelled))
+	assert.Error(d.transit(ContractFundSent))
+}
+
+// setupCancelWallet mocks a wallet sending a double-spend of fund txins,
+// where only the txout at *confirmed is confirmed
+func setupCancelWallet(confirmed *wire.OutPoint) (
+	w *walletmock.Wallet, sent *[]*wire.MsgTx) {
+	w = &walletmock.Wallet{}
+	_, pub := test.RandKeys()
+	w.On("NewChangePubkey").Return(pub, nil)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), mock.Anything,
+	).Return([]wire.TxWitness{{}}, nil)
+	w.On("UnlockUnspent", mock.Anything).Return(nil)
+
+	sent = &[]*wire.MsgTx{}
+	w.On("SendRawTransaction", mock.AnythingOfType("*wire.MsgTx")).
+		Run(func(args mock.Arguments) {
+			*sent = append(*sent, args.Get(0).(*wire.MsgTx))
+		}).Return(&chainhash.Hash{}, nil)
+
+	call := w.On("GetTxOut", mock.AnythingOfType("wire.OutPoint"))
+	call.Run(func(args mock.Arguments) {
+		var txout *btcjson.GetTxOutResult
+		if args.Get(0).(wire.OutPoint) == *confirmed {
+			txout = &btcjson.GetTxOutResult{Confirmations: 1}
+		}
+		call.ReturnArguments = mock.Arguments{txout, nil}
+	})
+	return w, sent
+}
+
+// setupDLCFundSigned sets up a contract whose fund tx is signed by first party
+func setupDLCFundSigned() (b1 *Builder, d *DLC) {
+	b1, _, d = setupDLCExactFunds()
+	w := b1.wallet.(*walletmock.Wallet)
+	w.On("WitnessSignTxByIdxs",
+		mock.AnythingOfType("*wire.MsgTx"), mock.Anything,
+	).Return([]wire.TxWitness{{}}, nil)
+	b1.SignFundTx()
+	return b1, d
+}
+
+func TestCancelBeforeFunding(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, d := setupDLCFundSigned()
+	assert.Equal(ContractFundSigned, d.State())
+
+	confirmed := &wire.OutPoint{}
+	w, sent := setupCancelWallet(confirmed)
+	b1.wallet = w
+
+	// the tx double-spending the fund txin
+	fundtx, _ := d.FundTx()
+	idxs, _ := b1.fundTxInAt()
+	txin := fundtx.TxIn[idxs[0]]
+	assert.NoError(b1.CancelBeforeFunding())
+	assert.Equal(ContractCancelling, d.State())
+	assert.Len(*sent, 1)
+	cancelTx := (*sent)[0]
+	assert.Equal(txin.PreviousOutPoint, cancelTx.TxIn[0].PreviousOutPoint)
+
+	// it pays the least fee replacing fund tx
+	fee := 10*oneBTC - btcutil.Amount(cancelTx.TxOut[0].Value)
+	minFee, _ := b1.minAbandonFee()
+	fundFee, _ := b1.fundTxFeePaid()
+	assert.Equal(minFee, fee)
+	assert.True(fee > fundFee)
+
+	// nothing can be signed or sent while cancelling
+	_, err := b1.SignFundTx()
+	assert.Error(err)
+	_, err = b1.SignRefundTx()
+	assert.Error(err)
+	_, err = b1.SignContractExecutionTxs()
+	assert.Error(err)
+	assert.Error(b1.SendFundTx())
+
+	// neither tx is confirmed yet
+	assert.Error(b1.ConfirmCancellation())
+	assert.Equal(ContractCancelling, d.State())
+	w.AssertNotCalled(t, "UnlockUnspent", mock.Anything)
+
+	// the contract is cancelled once the double-spend is confirmed
+	*confirmed = wire.OutPoint{Hash: cancelTx.TxHash(), Index: 0}
+	assert.NoError(b1.ConfirmCancellation())
+	assert.Equal(ContractCancelled, d.State())
+	w.AssertCalled(t, "UnlockUnspent", outPoints([]*wire.TxIn{txin}))
+	assert.Error(b1.CancelBeforeFunding())
+	assert.Error(b1.ConfirmCancellation())
+}
+
+func TestCancelBeforeFundingFundTxWins(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, d := setupDLCFundSigned()
+
+	confirmed := &wire.OutPoint{}
+	w, _ := setupCancelWallet(confirmed)
+	b1.wallet = w
+	assert.NoError(b1.CancelBeforeFunding())
+
+	// the contract goes on if fund tx is confirmed instead
+	fundtx, _ := d.FundTx()
+	fundAt, _ := d.fundTxOutAt()
+	*confirmed = wire.OutPoint{
+		Hash: fundtx.TxHash(), Index: uint32(fundAt)}
+	assert.NoError(b1.ConfirmCancellation())
+	assert.Equal(ContractFundSent, d.State())
+	w.AssertNotCalled(t, "UnlockUnspent", mock.Anything)
+	assert.NoError(d.transit(ContractClosed))
+}
+
+func TestCancelNegotiation(t *testing.T) {
+	assert := assert.New(t)
+
+	b1, _, d := setupDLCExactFunds()
+	txins := b1.dlc.fundTxReqs.txIns[FirstParty]
+
+	w, sent := setupCancelWallet(&wire.OutPoint{})
+	b1.wallet = w
+
+	// nothing is broadcast before signing fund tx
+	assert.NoError(b1.CancelBeforeFunding())
+	assert.Equal(ContractCancelled, d.State())
+	assert.Empty(*sent)
+	w.AssertCalled(t, "UnlockUnspent", outPoints(txins))
+	assert.Empty(b1.dlc.fundTxReqs.txIns[FirstParty])
+}
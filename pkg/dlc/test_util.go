@@ -1,8 +1,10 @@
 package dlc
 
 import (
+	"encoding/hex"
 	"time"
 
+	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -18,7 +20,23 @@ func setupTestWallet() *walletmock.Wallet {
 	w := &walletmock.Wallet{}
 	priv, pub := test.RandKeys()
 	w.On("NewPubkey").Return(pub, nil)
+	w.On("NewChangePubkey").Return(pub, nil)
 	w = mockWitnessSignature(w, pub, priv)
+	w = mockGetTxOut(w, 10*oneBTC, 6)
+	return w
+}
+
+// mockGetTxOut mocks that any txout is an unspent p2wpkh txout
+func mockGetTxOut(
+	w *walletmock.Wallet, amt btcutil.Amount, confs int64) *walletmock.Wallet {
+	_, pub := test.RandKeys()
+	pkScript, _ := script.P2WPKHpkScript(pub)
+	txout := &btcjson.GetTxOutResult{
+		Confirmations: confs,
+		Value:         amt.ToBTC(),
+		ScriptPubKey:  btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(pkScript)},
+	}
+	w.On("GetTxOut", mock.Anything).Return(txout, nil)
 	return w
 }
 
@@ -79,20 +97,68 @@ func mockWitnessSignatureWithCallback(
 // Hash of block 234439
 var testTxID = "14a0810ac680a3eb3f82edc878cea25ec41d6b790744e5daeef"
 
+// testVout is the vout of the next utxo mocked by mockSelectUnspent.
+// Each mocked wallet gets a distinct utxo so that fund txins don't overlap.
+var testVout uint32 = 1000
+
 func mockSelectUnspent(
 	w *walletmock.Wallet, balance, change btcutil.Amount, err error) *walletmock.Wallet {
+	testVout++
 	utxo := wallet.Utxo{
 		TxID:   testTxID,
+		Vout:   testVout,
 		Amount: float64(balance) / btcutil.SatoshiPerBitcoin,
 	}
 	w.On("SelectUnspent",
-		mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
 	).Return([]wallet.Utxo{utxo}, change, err)
+	w.On("LockUnspent", mock.Anything, mock.Anything).Return(nil)
+	w.On("UnlockUnspent", mock.Anything).Return(nil)
 
 	return w
 }
 
-func newTestConditions() *Conditions {
-	conds, _ := NewConditions(time.Now(), 1, 1, 1, 1, 1, []*Deal{})
+// mockExactSelectUnspent mocks that a 10 BTC utxo is selected
+// with the exact change after the fees of the txin and the change txout.
+// The amount matches the txout of GetTxOut mocked by setupTestWallet.
+func mockExactSelectUnspent(
+	w *walletmock.Wallet, vout uint32) *walletmock.Wallet {
+	balance := 10 * oneBTC
+	utxo := wallet.Utxo{
+		TxID:   testTxID,
+		Vout:   vout,
+		Amount: balance.ToBTC(),
+	}
+	call := w.On("SelectUnspent",
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	call.Run(func(args mock.Arguments) {
+		amt := args.Get(1).(btcutil.Amount)
+		feePerTxIn := args.Get(2).(btcutil.Amount)
+		feePerTxOut := args.Get(3).(btcutil.Amount)
+		change := balance - amt - feePerTxIn - feePerTxOut
+		call.ReturnArguments = mock.Arguments{
+			[]wallet.Utxo{utxo}, change, nil}
+	})
+	w.On("LockUnspent", mock.Anything, mock.Anything).Return(nil)
+	w.On("UnlockUnspent", mock.Anything).Return(nil)
+
+	return w
+}
+
+const oneBTC btcutil.Amount = btcutil.SatoshiPerBitcoin
+
+// newTestConditions creates conditions on given deals
+// funded by both parties with the total amount of the first deal.
+// A deal paying 1 BTC to each party is used if no deals are given.
+func newTestConditions(deals ...*Deal) *Conditions {
+	if len(deals) == 0 {
+		deals = []*Deal{NewOutcomeDeal(oneBTC, oneBTC, "1")}
+	}
+	total := deals[0].Amts[FirstParty] + deals[0].Amts[SecondParty]
+	famt1 := total / 2
+	famt2 := total - famt1
+
+	ftime := time.Now().AddDate(0, 0, 1)
+	conds, _ := NewConditions(ftime, famt1, famt2, 1, 1, 1, deals)
 	return conds
 }
@@ -0,0 +1,188 @@
+package dlc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/dgarage/dlc/pkg/oracle"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// Violation is a rule that conditions don't satisfy
+type Violation struct {
+	Field  string // field of conditions. e.g. Deals[1].Amts
+	Reason string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Field, v.Reason)
+}
+
+// Validate validates conditions and returns InvalidConditionsError
+// containing all violations if any.
+// It builds the index of deals of valid conditions,
+// so deals must not be modified after validation.
+func (conds *Conditions) Validate() error {
+	vs := structViolations(conds)
+
+	// the other rules depend on fields checked by struct tags
+	if len(vs) == 0 {
+		vs = append(vs, conds.amtViolations()...)
+		// R-points of the oracle are unknown until it announces the event
+		vs = append(vs, conds.msgViolations(-1)...)
+		vs = append(vs, conds.lockTimeViolations()...)
+	}
+
+	if len(vs) > 0 {
+		return newInvalidConditionsError(vs)
+	}
+
+	// deals must be found uniquely by messages
+	idx, err := newDealIndex(conds.Deals)
+	if err != nil {
+		return newInvalidConditionsError(
+			[]Violation{{Field: "Deals", Reason: err.Error()}})
+	}
+	conds.dealIdx = idx
+	return nil
+}
+
+// structViolations converts errors of struct tags to violations
+func structViolations(conds *Conditions) []Violation {
+	err := validator.New().Struct(conds)
+	if err == nil {
+		return nil
+	}
+
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []Violation{{Field: "Conditions", Reason: err.Error()}}
+	}
+
+	var vs []Violation
+	for _, e := range errs {
+		field := strings.TrimPrefix(e.Namespace(), "Conditions.")
+		reason := fmt.Sprintf("failed on '%s'", e.Tag())
+		if e.Param() != "" {
+			reason = fmt.Sprintf("failed on '%s=%s'", e.Tag(), e.Param())
+		}
+		vs = append(vs, Violation{Field: field, Reason: reason})
+	}
+	return vs
+}
+
+// amtViolations checks that every deal distributes the total fund amount
+// and that anchor txouts aren't dust.
+// Dust amounts of deals are allowed since they're settled by the dust policy.
+func (conds *Conditions) amtViolations() []Violation {
+	var vs []Violation
+
+	total := conds.FundAmts[FirstParty] + conds.FundAmts[SecondParty]
+	for i, deal := range conds.Deals {
+		field := fmt.Sprintf("Deals[%d].Amts", i)
+
+		amt1, amt2 := deal.Amts[FirstParty], deal.Amts[SecondParty]
+		if amt1+amt2 != total {
+			reason := fmt.Sprintf(
+				"sum of amounts %d doesn't match fund amount %d", amt1+amt2, total)
+			vs = append(vs, Violation{Field: field, Reason: reason})
+		}
+	}
+
+	anchor := conds.AnchorAmt
+	if anchor > 0 && isDust(anchor) {
+		reason := fmt.Sprintf(
+			"anchor amount %d is below dust limit %d", anchor, DustLimit)
+		vs = append(vs, Violation{Field: "AnchorAmt", Reason: reason})
+	}
+
+	return vs
+}
+
+// msgViolations checks the numbers and encoding of messages of deals.
+// Messages must be canonical except those of legacy deals.
+// Deals except prefix deals must have the same number of messages,
+// and no deal can have more messages than R-points committed by the oracle
+// unless nRpoints < 0.
+// A deal may have fewer messages than R-points
+// when contractors use only the leading R-points of the event.
+func (conds *Conditions) msgViolations(nRpoints int) []Violation {
+	var vs []Violation
+
+	nMsgs := 0
+	for i, deal := range conds.Deals {
+		field := fmt.Sprintf("Deals[%d].Msgs", i)
+		n := len(deal.Msgs)
+
+		for j, m := range deal.Msgs {
+			if !deal.Legacy && !oracle.IsCanonicalMsg(m) {
+				reason := fmt.Sprintf("message %d isn't canonical", j)
+				vs = append(vs, Violation{Field: field, Reason: reason})
+			}
+		}
+
+		if nRpoints >= 0 && n > nRpoints {
+			reason := fmt.Sprintf(
+				"%d messages exceed %d R-points of the oracle", n, nRpoints)
+			vs = append(vs, Violation{Field: field, Reason: reason})
+		}
+
+		if deal.Prefix {
+			continue
+		}
+		if nMsgs == 0 {
+			nMsgs = n
+		} else if n != nMsgs {
+			reason := fmt.Sprintf(
+				"%d messages differ from %d messages of the other deals", n, nMsgs)
+			vs = append(vs, Violation{Field: field, Reason: reason})
+		}
+	}
+
+	return vs
+}
+
+// lockTimeViolations checks that refund tx is unlocked after the fixing time.
+// It's checked only if the locktime is a timestamp.
+// A block height can't be compared without the chain.
+func (conds *Conditions) lockTimeViolations() []Violation {
+	lc := conds.RefundLockTime
+	if lc < txscript.LockTimeThreshold {
+		return nil
+	}
+
+	if !time.Unix(int64(lc), 0).After(conds.FixingTime) {
+		reason := fmt.Sprintf(
+			"refund locktime %d isn't after fixing time %s",
+			lc, conds.FixingTime.UTC().Format(time.RFC3339))
+		return []Violation{{Field: "RefundLockTime", Reason: reason}}
+	}
+	return nil
+}
+
+// diffViolations checks that the counterparty's conditions are the same
+func (conds *Conditions) diffViolations(cp *Conditions) []Violation {
+	var vs []Violation
+	diff := func(field string, same bool) {
+		if !same {
+			reason := "doesn't match the counterparty's"
+			vs = append(vs, Violation{Field: field, Reason: reason})
+		}
+	}
+
+	diff("FixingTime", conds.FixingTime.Equal(cp.FixingTime))
+	diff("FundAmts", equalAmts(conds.FundAmts, cp.FundAmts))
+	diff("FundFeerate", conds.FundFeerate == cp.FundFeerate)
+	diff("RedeemFeerate", conds.RedeemFeerate == cp.RedeemFeerate)
+	diff("RefundLockTime", conds.RefundLockTime == cp.RefundLockTime)
+	diff("DustPolicy", conds.DustPolicy == cp.DustPolicy)
+	diff("AnchorAmt", conds.AnchorAmt == cp.AnchorAmt)
+
+	diff("Deals", len(conds.Deals) == len(cp.Deals))
+	for i := 0; i < len(conds.Deals) && i < len(cp.Deals); i++ {
+		diff(fmt.Sprintf("Deals[%d]", i), conds.Deals[i].equal(cp.Deals[i]))
+	}
+	return vs
+}
@@ -0,0 +1,108 @@
+package dlc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgarage/dlc/internal/oracle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConditions(t *testing.T) {
+	assert := assert.New(t)
+
+	ftime := time.Now().AddDate(0, 0, 1)
+	lc := uint32(ftime.AddDate(0, 0, 1).Unix())
+	deals := []*Deal{
+		NewOutcomeDeal(2*oneBTC, 0, "0", "1"),
+		NewPrefixDeal(oneBTC, oneBTC, "1"),
+	}
+	_, err := NewConditions(ftime, oneBTC, oneBTC, 1, 1, lc, deals)
+	assert.NoError(err)
+
+	// all violations are listed
+	deals = []*Deal{
+		NewOutcomeDeal(oneBTC, 0, "0", "1"),        // not distributing the total
+		NewOutcomeDeal(2*oneBTC-1, 1, "1", "0"),    // dust is settled by the dust policy
+		NewOutcomeDeal(0, 2*oneBTC, "1", "1", "0"), // different number of messages
+	}
+	lc = uint32(ftime.AddDate(0, 0, -1).Unix()) // refunded before fixing time
+	_, err = NewConditions(ftime, oneBTC, oneBTC, 1, 1, lc, deals)
+	assert.IsType(&InvalidConditionsError{}, err)
+
+	var fields []string
+	for _, v := range err.(*InvalidConditionsError).Violations {
+		fields = append(fields, v.Field)
+	}
+	assert.Equal([]string{
+		"Deals[0].Amts", "Deals[2].Msgs", "RefundLockTime",
+	}, fields)
+
+	// struct tags are reported as violations
+	_, err = NewConditions(ftime, 0, oneBTC, 1, 1, 1, deals[:1])
+	assert.IsType(&InvalidConditionsError{}, err)
+	v := err.(*InvalidConditionsError).Violations[0]
+	assert.Equal("FundAmts[0]", v.Field)
+}
+
+func TestCopyReqsFromInvalidCounterparty(t *testing.T) {
+	assert := assert.New(t)
+
+	conds := newTestConditions()
+	b1 := NewBuilder(FirstParty, setupTestWallet(), conds)
+	b2 := NewBuilder(SecondParty, setupTestWallet(), conds)
+	b2.PreparePubkey()
+
+	// counterparty's conditions are malformed
+	d := *b2.DLC()
+	invalid := *conds
+	invalid.Deals = []*Deal{NewOutcomeDeal(3*oneBTC, 0, "1")}
+	d.Conds = &invalid
+
+	err := b1.CopyReqsFromCounterparty(&d)
+	assert.IsType(&InvalidConditionsError{}, err)
+	assert.Nil(b1.dlc.pubs[SecondParty])
+
+	// counterparty's conditions are valid but different
+	different := *conds
+	different.RedeemFeerate++
+	different.Deals = []*Deal{NewOutcomeDeal(oneBTC, oneBTC, "2")}
+	d.Conds = &different
+
+	err = b1.CopyReqsFromCounterparty(&d)
+	assert.IsType(&InvalidConditionsError{}, err)
+	var fields []string
+	for _, v := range err.(*InvalidConditionsError).Violations {
+		fields = append(fields, v.Field)
+	}
+	assert.Equal([]string{"RedeemFeerate", "Deals[0]"}, fields)
+	assert.Nil(b1.dlc.pubs[SecondParty])
+
+	err = b1.CopyReqsFromCounterparty(b2.DLC())
+	assert.NoError(err)
+	assert.NotNil(b1.dlc.pubs[SecondParty])
+
+	// the same conditions are accepted after the fixing time
+	conds.FixingTime = time.Now().Add(-time.Hour)
+	assert.NoError(conds.Validate())
+	d = *b1.DLC()
+	same := *conds
+	d.Conds = &same
+	err = b2.CopyReqsFromCounterparty(&d)
+	assert.NoError(err)
+}
+
+func TestSetOraclePubkeySetWithFewRpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	conds := newTestConditions(NewOutcomeDeal(oneBTC, oneBTC, "1", "0"))
+	b := NewBuilder(FirstParty, setupTestWallet(), conds)
+
+	o := oracle.NewTestOracle()
+	id := oracle.TimeEventID(oracle.TestStream, time.Now())
+	_ = o.AddEvent(oracle.Event{ID: id, NRpoints: 1})
+	pubset, _ := o.PubkeySet(id)
+
+	err := b.SetOraclePubkeySet(&pubset)
+	assert.IsType(&InvalidConditionsError{}, err)
+}
@@ -0,0 +1,178 @@
+package dlc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// fundTxInMinConf is the least confirmations of counterparty's fund txins
+const fundTxInMinConf = 1
+
+// coinbaseMaturity is the confirmations for coinbase outputs to be spendable
+const coinbaseMaturity = 100
+
+// VerifyCounterpartyFundTxIns verifies the counterparty's fund txins
+// and change txout before signing CETs or refund tx.
+// Each txin must spend a confirmed unspent segwit txout,
+// and the txins must cover the fund amount, the fee share and the change.
+// The change mustn't be dust, and no txin may spend the same outpoint
+// as another txin of either party.
+// In a batch, all txins of the fund tx are verified
+// since the counterparty may fund the contract with txins of another one.
+func (b *Builder) VerifyCounterpartyFundTxIns() error {
+	if b.dlc.batch != nil {
+		return b.verifyBatchFundTxIns()
+	}
+
+	p := counterparty(b.party)
+	d := b.dlc
+
+	txins := d.fundTxReqs.txIns[p]
+	if len(txins) == 0 {
+		return errors.New("counterparty has no fund txins")
+	}
+	all := append(append([]*wire.TxIn{}, d.fundTxReqs.txIns[b.party]...), txins...)
+	if err := checkDuplicateTxIns(all); err != nil {
+		return err
+	}
+
+	var total btcutil.Amount
+	for _, txin := range txins {
+		amt, err := b.fundTxInAmount(txin.PreviousOutPoint)
+		if err != nil {
+			return err
+		}
+		total += amt
+	}
+
+	required := d.Conds.FundAmts[p] + d.fundTxFeeBase(1) +
+		d.redeemTxFee(cetxSize) + d.anchorCost() +
+		d.fundTxFeePerTxIn().MulF64(float64(len(txins)))
+
+	if change := d.fundTxReqs.txOut[p]; change != nil {
+		if isDust(btcutil.Amount(change.Value)) {
+			return fmt.Errorf(
+				"counterparty's change is dust. amount: %d", change.Value)
+		}
+		required += btcutil.Amount(change.Value) + d.fundTxFeePerTxOut()
+	}
+
+	if total < required {
+		return fmt.Errorf(
+			"counterparty's fund txins aren't enough. total: %d, required: %d",
+			total, required)
+	}
+
+	b.cpTxInsVerified = true
+	return nil
+}
+
+// verifyBatchFundTxIns verifies all txins of the fund tx of a batch.
+// The txins must be unique and cover all txouts and the fee of the batch,
+// and no change txouts may be dust.
+func (b *Builder) verifyBatchFundTxIns() error {
+	fundtx, err := b.dlc.FundTx()
+	if err != nil {
+		return err
+	}
+
+	if err := checkDuplicateTxIns(fundtx.TxIn); err != nil {
+		return err
+	}
+
+	var total btcutil.Amount
+	for _, txin := range fundtx.TxIn {
+		amt, err := b.fundTxInAmount(txin.PreviousOutPoint)
+		if err != nil {
+			return err
+		}
+		total += amt
+	}
+
+	desc := b.dlc.batch
+	required := b.dlc.fundTxFeeFor(
+		len(desc.FundOuts), len(desc.TxIns), len(desc.ChangeOuts))
+	for _, txout := range fundtx.TxOut {
+		required += btcutil.Amount(txout.Value)
+	}
+	for _, out := range desc.ChangeOuts {
+		if isDust(btcutil.Amount(out.Value)) {
+			return fmt.Errorf("change txout is dust. amount: %d", out.Value)
+		}
+	}
+
+	if total < required {
+		return fmt.Errorf(
+			"fund txins aren't enough. total: %d, required: %d",
+			total, required)
+	}
+
+	b.cpTxInsVerified = true
+	return nil
+}
+
+// checkDuplicateTxIns returns an error if txins spend the same outpoint,
+// which makes fund tx invalid
+func checkDuplicateTxIns(txins []*wire.TxIn) error {
+	ops := make(map[wire.OutPoint]bool)
+	for _, txin := range txins {
+		if ops[txin.PreviousOutPoint] {
+			return fmt.Errorf("duplicate fund txin %v", txin.PreviousOutPoint)
+		}
+		ops[txin.PreviousOutPoint] = true
+	}
+	return nil
+}
+
+// fundTxInAmount returns the amount of the txout that a fund txin spends
+// and records it to compute the actual fee of fund tx later.
+// The txout must be a confirmed unspent segwit txout.
+func (b *Builder) fundTxInAmount(op wire.OutPoint) (btcutil.Amount, error) {
+	txout, err := b.wallet.GetTxOut(op)
+	if err != nil {
+		return 0, err
+	}
+	if txout == nil {
+		return 0, fmt.Errorf("fund txin %v is spent or unknown", op)
+	}
+
+	minConf := int64(fundTxInMinConf)
+	if txout.Coinbase {
+		minConf = coinbaseMaturity
+	}
+	if txout.Confirmations < minConf {
+		return 0, fmt.Errorf(
+			"fund txin %v doesn't have enough confirmations. "+
+				"confirmations: %d, required: %d",
+			op, txout.Confirmations, minConf)
+	}
+
+	pkScript, err := hex.DecodeString(txout.ScriptPubKey.Hex)
+	if err != nil {
+		return 0, err
+	}
+	if !txscript.IsWitnessProgram(pkScript) {
+		return 0, fmt.Errorf("fund txin %v isn't segwit", op)
+	}
+
+	amt, err := btcutil.NewAmount(txout.Value)
+	if err != nil {
+		return 0, err
+	}
+	b.fundTxInAmts[op] = amt
+	return amt, nil
+}
+
+// verifyCounterpartyFundTxInsOnce verifies the counterparty's fund txins
+// unless they have been verified
+func (b *Builder) verifyCounterpartyFundTxInsOnce() error {
+	if b.cpTxInsVerified {
+		return nil
+	}
+	return b.VerifyCounterpartyFundTxIns()
+}
@@ -0,0 +1,113 @@
+package dlc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/dgarage/dlc/internal/mocks/walletmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setupVerifyCounterparty sets up the first party
+// whose counterparty funds with a txin and a change txout,
+// and the wallet returns a given txout of the txin
+func setupVerifyCounterparty(
+	txout *btcjson.GetTxOutResult, change btcutil.Amount) *Builder {
+	w := &walletmock.Wallet{}
+	w.On("GetTxOut", mock.Anything).Return(txout, nil)
+
+	b := NewBuilder(FirstParty, w, newTestConditions())
+	op := wire.NewOutPoint(&chainhash.Hash{1}, 0)
+	b.dlc.fundTxReqs.txIns[SecondParty] = []*wire.TxIn{
+		wire.NewTxIn(op, nil, nil)}
+	if change > 0 {
+		b.dlc.fundTxReqs.txOut[SecondParty] = wire.NewTxOut(
+			int64(change), []byte{})
+	}
+	return b
+}
+
+func newTestGetTxOutResult(
+	amt btcutil.Amount, confs int64, pkScript []byte) *btcjson.GetTxOutResult {
+	return &btcjson.GetTxOutResult{
+		Confirmations: confs,
+		Value:         amt.ToBTC(),
+		ScriptPubKey:  btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(pkScript)},
+	}
+}
+
+func TestVerifyCounterpartyFundTxIns(t *testing.T) {
+	p2wpkh := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	p2pkh, _ := hex.DecodeString(
+		"76a914000000000000000000000000000000000000000088ac")
+	coinbase := newTestGetTxOutResult(2*oneBTC, 99, p2wpkh)
+	coinbase.Coinbase = true
+
+	tests := []struct {
+		name   string
+		txout  *btcjson.GetTxOutResult
+		change btcutil.Amount
+		valid  bool
+	}{
+		{"valid", newTestGetTxOutResult(2*oneBTC, 1, p2wpkh), oneBTC / 2, true},
+		{"spent", nil, 0, false},
+		{"unconfirmed", newTestGetTxOutResult(2*oneBTC, 0, p2wpkh), 0, false},
+		{"immature coinbase", coinbase, 0, false},
+		{"non-segwit", newTestGetTxOutResult(2*oneBTC, 1, p2pkh), 0, false},
+		{"not enough", newTestGetTxOutResult(oneBTC, 1, p2wpkh), 0, false},
+		{"not enough for change",
+			newTestGetTxOutResult(2*oneBTC, 1, p2wpkh), oneBTC, false},
+		{"dust change", newTestGetTxOutResult(2*oneBTC, 1, p2wpkh), 1, false},
+	}
+
+	for _, test := range tests {
+		b := setupVerifyCounterparty(test.txout, test.change)
+		err := b.VerifyCounterpartyFundTxIns()
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+func TestVerifyCounterpartyFundTxInsDuplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	p2wpkh := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	txout := newTestGetTxOutResult(2*oneBTC, 1, p2wpkh)
+
+	// the counterparty spends an outpoint twice
+	b := setupVerifyCounterparty(txout, 0)
+	txin := b.dlc.fundTxReqs.txIns[SecondParty][0]
+	b.dlc.fundTxReqs.txIns[SecondParty] = []*wire.TxIn{
+		txin, wire.NewTxIn(&txin.PreviousOutPoint, nil, nil)}
+	err := b.VerifyCounterpartyFundTxIns()
+	assert.Error(err)
+
+	// the counterparty spends our outpoint
+	b = setupVerifyCounterparty(txout, 0)
+	txin = b.dlc.fundTxReqs.txIns[SecondParty][0]
+	b.dlc.fundTxReqs.txIns[FirstParty] = []*wire.TxIn{
+		wire.NewTxIn(&txin.PreviousOutPoint, nil, nil)}
+	err = b.VerifyCounterpartyFundTxIns()
+	assert.Error(err)
+	assert.False(b.cpTxInsVerified)
+}
+
+// CETs and refund tx shouldn't be signed if counterparty's txins are invalid
+func TestSignWithInvalidCounterpartyFundTxIns(t *testing.T) {
+	assert := assert.New(t)
+
+	b := setupVerifyCounterparty(nil, 0)
+
+	_, err := b.SignRefundTx()
+	assert.Error(err)
+	_, err = b.SignContractExecutionTxs()
+	assert.Error(err)
+}
@@ -0,0 +1,343 @@
+package oracle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/pkg/schnorr"
+)
+
+// Types of audit records
+const (
+	RecordAnnouncement = "announcement" // an event and its pubkey set
+	RecordAttestation  = "attestation"  // fixed messages and signs of an event
+	RecordRotation     = "rotation"     // long-term pubkey of a new key epoch
+)
+
+// AuditRecord is an entry of oracle's append-only audit log.
+//
+// Records are chained by the hash of the previous record,
+// and each record is signed by the oracle's long-term key of its epoch,
+// i.e. an attestation by the key of the epoch its event was announced in.
+// A rotation record is signed by the previous key.
+// Keys, messages and signs are hex encoded.
+type AuditRecord struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Epoch    int       `json:"epoch,omitempty"`
+	Stream   string    `json:"stream"`
+	EventID  string    `json:"event_id"`
+	Pubkey   string    `json:"pubkey,omitempty"`
+	Rpoints  []string  `json:"rpoints,omitempty"`
+	Msgs     []string  `json:"msgs,omitempty"`
+	Signs    []string  `json:"signs,omitempty"`
+	PrevHash string    `json:"prev_hash"`
+	Hash     string    `json:"hash"`
+	Sig      string    `json:"sig"`
+}
+
+// NewAnnouncementRecord creates an unchained record of an announcement
+func NewAnnouncementRecord(id EventID, pubset PubkeySet) *AuditRecord {
+	rpoints := []string{}
+	for _, R := range pubset.CommittedRpoints {
+		rpoints = append(rpoints, hex.EncodeToString(R.SerializeCompressed()))
+	}
+
+	return &AuditRecord{
+		Type:    RecordAnnouncement,
+		Epoch:   pubset.Epoch,
+		Stream:  id.Stream,
+		EventID: id.ID,
+		Pubkey:  hex.EncodeToString(pubset.Pubkey.SerializeCompressed()),
+		Rpoints: rpoints,
+	}
+}
+
+// NewAttestationRecord creates an unchained record of an attestation
+// of an event announced in a given epoch
+func NewAttestationRecord(id EventID, epoch int, signSet SignSet) *AuditRecord {
+	return &AuditRecord{
+		Type:    RecordAttestation,
+		Epoch:   epoch,
+		Stream:  id.Stream,
+		EventID: id.ID,
+		Msgs:    encodeHexes(signSet.Msgs),
+		Signs:   encodeHexes(signSet.Signs),
+	}
+}
+
+// NewRotationRecord creates an unchained record of a key rotation
+func NewRotationRecord(r KeyRotation) *AuditRecord {
+	return &AuditRecord{
+		Type:   RecordRotation,
+		Epoch:  r.Epoch,
+		Pubkey: hex.EncodeToString(r.Pubkey.SerializeCompressed()),
+	}
+}
+
+// ID returns the id of the recorded event
+func (r *AuditRecord) ID() EventID {
+	return EventID{Stream: r.Stream, ID: r.EventID}
+}
+
+// PubkeySet decodes the pubkey set of an announcement record.
+// The announcement signature isn't recorded, since the record itself is signed.
+func (r *AuditRecord) PubkeySet() (PubkeySet, error) {
+	pub, err := parseHexPubkey(r.Pubkey)
+	if err != nil {
+		return PubkeySet{}, err
+	}
+
+	rpoints := []*btcec.PublicKey{}
+	for _, s := range r.Rpoints {
+		R, err := parseHexPubkey(s)
+		if err != nil {
+			return PubkeySet{}, err
+		}
+		rpoints = append(rpoints, R)
+	}
+
+	return PubkeySet{
+		Pubkey:           pub,
+		CommittedRpoints: rpoints,
+		ID:               r.ID(),
+		Epoch:            r.Epoch,
+	}, nil
+}
+
+// SignSet decodes the sign set of an attestation record
+func (r *AuditRecord) SignSet() (SignSet, error) {
+	msgs, err := decodeHexes(r.Msgs)
+	if err != nil {
+		return SignSet{}, err
+	}
+	signs, err := decodeHexes(r.Signs)
+	if err != nil {
+		return SignSet{}, err
+	}
+	return SignSet{Msgs: msgs, Signs: signs}, nil
+}
+
+// Digest computes the hash of the record excluding its hash and signature
+func (r *AuditRecord) Digest() ([]byte, error) {
+	body := *r
+	body.Hash = ""
+	body.Sig = ""
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(b)
+	return h[:], nil
+}
+
+// DigestSigner signs a digest with the oracle's identity key
+// and returns a DER signature
+type DigestSigner func(digest []byte) ([]byte, error)
+
+// Chain links the record to a previous record and signs it
+func (r *AuditRecord) Chain(prev *AuditRecord, sign DigestSigner) error {
+	if prev != nil {
+		r.Seq = prev.Seq + 1
+		r.PrevHash = prev.Hash
+	}
+
+	h, err := r.Digest()
+	if err != nil {
+		return err
+	}
+	sig, err := sign(h)
+	if err != nil {
+		return err
+	}
+
+	r.Hash = hex.EncodeToString(h)
+	r.Sig = hex.EncodeToString(sig)
+	return nil
+}
+
+// verifyChain verifies the link to a previous record and the signature
+func (r *AuditRecord) verifyChain(
+	prev *AuditRecord, pub *btcec.PublicKey) error {
+	var seq uint64
+	prevHash := ""
+	if prev != nil {
+		seq = prev.Seq + 1
+		prevHash = prev.Hash
+	}
+	if r.Seq != seq {
+		return fmt.Errorf("unexpected sequence. expected %d, but got %d", seq, r.Seq)
+	}
+	if r.PrevHash != prevHash {
+		return errors.New("broken hash chain")
+	}
+
+	h, err := r.Digest()
+	if err != nil {
+		return err
+	}
+	if r.Hash != hex.EncodeToString(h) {
+		return errors.New("hash mismatch")
+	}
+
+	sig, err := hex.DecodeString(r.Sig)
+	if err != nil {
+		return err
+	}
+	if err := verifyDERSig(h, sig, pub); err != nil {
+		return fmt.Errorf("invalid record signature: %v", err)
+	}
+
+	return nil
+}
+
+// ReadAuditLog reads records from a JSON lines audit log
+func ReadAuditLog(r io.Reader) ([]*AuditRecord, error) {
+	records := []*AuditRecord{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record := &AuditRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, fmt.Errorf("invalid record at line %d: %v", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// VerifyAuditLog replays audit records signed by a given identity key
+// and the keys rotated from it.
+// It verifies the hash chain, record signatures,
+// and every attestation against the announcement of its event.
+func VerifyAuditLog(records []*AuditRecord, pub *btcec.PublicKey) error {
+	announced := make(map[string]PubkeySet)
+	attested := make(map[string]bool)
+	keys := []*btcec.PublicKey{pub} // long-term pubkeys of each epoch
+
+	var prev *AuditRecord
+	for _, r := range records {
+		// a rotation record is signed by the key it rotates from
+		epoch := r.Epoch
+		if r.Type == RecordRotation {
+			epoch--
+		}
+		if epoch < 0 || epoch >= len(keys) {
+			return fmt.Errorf("record %d: unexpected epoch %d", r.Seq, r.Epoch)
+		}
+		if err := r.verifyChain(prev, keys[epoch]); err != nil {
+			return fmt.Errorf("record %d: %v", r.Seq, err)
+		}
+		prev = r
+
+		key := r.ID().String()
+		switch r.Type {
+		case RecordRotation:
+			if r.Epoch != len(keys) {
+				return fmt.Errorf("record %d: unexpected epoch %d", r.Seq, r.Epoch)
+			}
+			next, err := parseHexPubkey(r.Pubkey)
+			if err != nil {
+				return fmt.Errorf("record %d: %v", r.Seq, err)
+			}
+			keys = append(keys, next)
+		case RecordAnnouncement:
+			if _, ok := announced[key]; ok {
+				return fmt.Errorf("record %d: event announced twice. id: %s", r.Seq, key)
+			}
+			pubset, err := r.PubkeySet()
+			if err != nil {
+				return fmt.Errorf("record %d: %v", r.Seq, err)
+			}
+			announced[key] = pubset
+		case RecordAttestation:
+			pubset, ok := announced[key]
+			if !ok {
+				return fmt.Errorf("record %d: event isn't announced. id: %s", r.Seq, key)
+			}
+			if attested[key] {
+				return fmt.Errorf("record %d: event attested twice. id: %s", r.Seq, key)
+			}
+			if r.Epoch != pubset.Epoch {
+				return fmt.Errorf(
+					"record %d: attestation epoch %d doesn't match announcement epoch %d",
+					r.Seq, r.Epoch, pubset.Epoch)
+			}
+			signSet, err := r.SignSet()
+			if err != nil {
+				return fmt.Errorf("record %d: %v", r.Seq, err)
+			}
+			if err := verifySignSet(pubset, signSet); err != nil {
+				return fmt.Errorf("record %d: %v", r.Seq, err)
+			}
+			attested[key] = true
+		default:
+			return fmt.Errorf("record %d: unknown record type. type: %s", r.Seq, r.Type)
+		}
+	}
+
+	return nil
+}
+
+// verifySignSet verifies oracle's signs against committed R-points
+func verifySignSet(pubset PubkeySet, signSet SignSet) error {
+	n := len(pubset.CommittedRpoints)
+	if len(signSet.Msgs) != n || len(signSet.Signs) != n {
+		return fmt.Errorf("invalid sign set size. expected %d", n)
+	}
+
+	for i, R := range pubset.CommittedRpoints {
+		P := schnorr.Commit(pubset.Pubkey, R, signSet.Msgs[i])
+		if !schnorr.Verify(P, signSet.Signs[i]) {
+			return fmt.Errorf("invalid oracle sign at %d", i)
+		}
+	}
+
+	return nil
+}
+
+func parseHexPubkey(s string) (*btcec.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return btcec.ParsePubKey(b, btcec.S256())
+}
+
+func encodeHexes(bs [][]byte) []string {
+	ss := []string{}
+	for _, b := range bs {
+		ss = append(ss, hex.EncodeToString(b))
+	}
+	return ss
+}
+
+func decodeHexes(ss []string) ([][]byte, error) {
+	bs := [][]byte{}
+	for _, s := range ss {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		bs = append(bs, b)
+	}
+	return bs, nil
+}
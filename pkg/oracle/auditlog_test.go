@@ -0,0 +1,87 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/schnorr"
+	"github.com/stretchr/testify/assert"
+)
+
+func testAuditRecords(t *testing.T) ([]*AuditRecord, *btcec.PublicKey) {
+	ipriv, ipub := test.RandKeys()
+	opriv, V := test.RandKeys()
+	rpriv, R := test.RandKeys()
+
+	id := EventID{Stream: "test", ID: "1"}
+	pubset := PubkeySet{Pubkey: V, CommittedRpoints: []*btcec.PublicKey{R}}
+	m := []byte{1}
+	signSet := SignSet{
+		Msgs:  [][]byte{m},
+		Signs: [][]byte{schnorr.Sign(opriv, rpriv, m)},
+	}
+
+	sign := func(digest []byte) ([]byte, error) {
+		sig, err := ipriv.Sign(digest)
+		if err != nil {
+			return nil, err
+		}
+		return sig.Serialize(), nil
+	}
+
+	records := []*AuditRecord{
+		NewAnnouncementRecord(id, pubset),
+		NewAttestationRecord(id, 0, signSet),
+	}
+	var prev *AuditRecord
+	for _, r := range records {
+		r.Time = time.Now().UTC()
+		assert.NoError(t, r.Chain(prev, sign))
+		prev = r
+	}
+	return records, ipub
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	assert := assert.New(t)
+
+	records, pub := testAuditRecords(t)
+
+	// write and read JSON lines
+	buf := &bytes.Buffer{}
+	for _, r := range records {
+		b, _ := json.Marshal(r)
+		buf.Write(append(b, '\n'))
+	}
+	read, err := ReadAuditLog(buf)
+	assert.NoError(err)
+	assert.Len(read, 2)
+
+	assert.NoError(VerifyAuditLog(read, pub))
+
+	// fail with another identity key
+	_, otherPub := test.RandKeys()
+	assert.Error(VerifyAuditLog(read, otherPub))
+}
+
+func TestVerifyAuditLogTampered(t *testing.T) {
+	assert := assert.New(t)
+
+	// modified attestation
+	records, pub := testAuditRecords(t)
+	records[1].Msgs = []string{"02"}
+	assert.Error(VerifyAuditLog(records, pub))
+
+	// removed record
+	records, pub = testAuditRecords(t)
+	assert.Error(VerifyAuditLog(records[1:], pub))
+
+	// reordered records
+	records, pub = testAuditRecords(t)
+	records[0], records[1] = records[1], records[0]
+	assert.Error(VerifyAuditLog(records, pub))
+}
@@ -0,0 +1,30 @@
+package oracle
+
+import "bytes"
+
+// CancelMsg is a reserved message that the oracle signs
+// on every R-point of an event that didn't occur.
+// It's tagged differently from outcome messages, so they never collide.
+var CancelMsg = taggedHash(cancelTag, nil)
+
+// CancelMsgs returns cancellation messages for n R-points
+func CancelMsgs(n int) [][]byte {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = CancelMsg
+	}
+	return msgs
+}
+
+// IsCancelled checks if the sign set attests the event's cancellation
+func (s SignSet) IsCancelled() bool {
+	if len(s.Msgs) == 0 {
+		return false
+	}
+	for _, m := range s.Msgs {
+		if !bytes.Equal(m, CancelMsg) {
+			return false
+		}
+	}
+	return true
+}
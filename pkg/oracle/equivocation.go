@@ -0,0 +1,89 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/pkg/schnorr"
+)
+
+// EquivocationProof proves that an oracle signed
+// two different messages under the same R-point
+type EquivocationProof struct {
+	Pubkey *btcec.PublicKey // oracle's pubkey
+	Rpoint *btcec.PublicKey // committed R-point used twice
+	Msgs   [2][]byte        // different messages
+	Signs  [2][]byte        // signs for the messages
+}
+
+// ProveEquivocation finds a R-point used for different messages
+// in two sign sets for the same pubkey set
+func ProveEquivocation(
+	pubset PubkeySet, a, b SignSet) (*EquivocationProof, error) {
+	for _, s := range []SignSet{a, b} {
+		if len(s.Msgs) != len(s.Signs) {
+			return nil, fmt.Errorf(
+				"malformed sign set. %d messages, but %d signs",
+				len(s.Msgs), len(s.Signs))
+		}
+	}
+
+	for i, R := range pubset.CommittedRpoints {
+		if i >= len(a.Msgs) || i >= len(b.Msgs) {
+			break
+		}
+		if bytes.Equal(a.Msgs[i], b.Msgs[i]) {
+			continue
+		}
+
+		proof := &EquivocationProof{
+			Pubkey: pubset.Pubkey,
+			Rpoint: R,
+			Msgs:   [2][]byte{a.Msgs[i], b.Msgs[i]},
+			Signs:  [2][]byte{a.Signs[i], b.Signs[i]},
+		}
+		if err := proof.Verify(); err != nil {
+			return nil, fmt.Errorf("invalid sign at %d: %v", i, err)
+		}
+		return proof, nil
+	}
+
+	return nil, errors.New("no equivocation found")
+}
+
+// Verify verifies that both signs are valid for
+// the different messages under the same R-point
+func (p *EquivocationProof) Verify() error {
+	if bytes.Equal(p.Msgs[0], p.Msgs[1]) {
+		return errors.New("messages must be different")
+	}
+
+	for i := range p.Msgs {
+		P := schnorr.Commit(p.Pubkey, p.Rpoint, p.Msgs[i])
+		if !schnorr.Verify(P, p.Signs[i]) {
+			return errors.New("invalid oracle sign")
+		}
+	}
+
+	return nil
+}
+
+// Privkey recovers the oracle's private key from the proof
+func (p *EquivocationProof) Privkey() (*btcec.PrivateKey, error) {
+	if err := p.Verify(); err != nil {
+		return nil, err
+	}
+
+	priv, err := schnorr.RecoverPrivkey(
+		p.Rpoint, p.Msgs[0], p.Signs[0], p.Msgs[1], p.Signs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if !priv.PubKey().IsEqual(p.Pubkey) {
+		return nil, errors.New("recovered key doesn't match oracle's pubkey")
+	}
+	return priv, nil
+}
@@ -0,0 +1,60 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/dgarage/dlc/pkg/schnorr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProveEquivocation(t *testing.T) {
+	assert := assert.New(t)
+
+	// oracle's keys
+	opriv, V := test.RandKeys()
+	rpriv1, R1 := test.RandKeys()
+	rpriv2, R2 := test.RandKeys()
+	pubset := PubkeySet{
+		Pubkey: V, CommittedRpoints: []*btcec.PublicKey{R1, R2}}
+
+	// oracle signs different second messages
+	signSet := func(m1, m2 []byte) SignSet {
+		return SignSet{
+			Msgs: [][]byte{m1, m2},
+			Signs: [][]byte{
+				schnorr.Sign(opriv, rpriv1, m1),
+				schnorr.Sign(opriv, rpriv2, m2),
+			},
+		}
+	}
+	a := signSet([]byte{1}, []byte{2})
+	b := signSet([]byte{1}, []byte{3})
+
+	proof, err := ProveEquivocation(pubset, a, b)
+	assert.NoError(err)
+	assert.True(R2.IsEqual(proof.Rpoint))
+	assert.NoError(proof.Verify())
+
+	priv, err := proof.Privkey()
+	assert.NoError(err)
+	assert.Equal(opriv.D, priv.D)
+
+	// no equivocation with the same messages
+	_, err = ProveEquivocation(pubset, a, a)
+	assert.Error(err)
+
+	// fail with an invalid sign
+	b.Signs[1] = a.Signs[1]
+	_, err = ProveEquivocation(pubset, a, b)
+	assert.Error(err)
+
+	// fail with a malformed sign set
+	b = signSet([]byte{1}, []byte{3})
+	b.Signs = b.Signs[:1]
+	_, err = ProveEquivocation(pubset, a, b)
+	assert.Error(err)
+	_, err = ProveEquivocation(pubset, b, a)
+	assert.Error(err)
+}
@@ -1,11 +1,20 @@
 package oracle
 
-import "github.com/btcsuite/btcd/btcec"
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+)
 
 // PubkeySet contains oracle's pub key and keys for all rate
 type PubkeySet struct {
 	Pubkey           *btcec.PublicKey
 	CommittedRpoints []*btcec.PublicKey
+
+	ID        EventID       // announced event
+	Epoch     int           // key epoch of the event
+	Rotations []KeyRotation // key rotations up to the epoch
+	Sig       []byte        // announcement signed by the epoch's long-term key
 }
 
 // SignSet contains fixed messages and signs
@@ -13,3 +22,22 @@ type SignSet struct {
 	Msgs  [][]byte
 	Signs [][]byte
 }
+
+// EventID identifies an event in one of oracle's event streams
+type EventID struct {
+	Stream string // stream name (e.g. "btcusd", "weather/tokyo")
+	ID     string // event id unique in the stream
+}
+
+// String returns a string representation of the event id
+func (id EventID) String() string {
+	return id.Stream + ":" + id.ID
+}
+
+// TimeFormat is a format of settlement time
+const TimeFormat = "2006-01-02 15:04:05"
+
+// TimeEventID returns an id of an event fixed at a given time in a stream
+func TimeEventID(stream string, ftime time.Time) EventID {
+	return EventID{Stream: stream, ID: ftime.Format(TimeFormat)}
+}
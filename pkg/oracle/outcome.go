@@ -0,0 +1,45 @@
+package oracle
+
+import (
+	"crypto/sha256"
+)
+
+// MsgSize is a size of a canonical message signed by the oracle
+const MsgSize = 32
+
+// Tags of hashes of messages signed by the oracle
+const (
+	outcomeTag = "DLC/oracle/outcome/v0"
+	cancelTag  = "DLC/oracle/cancel/v0"
+)
+
+// OutcomeMsg encodes a UTF-8 outcome string into a canonical message,
+// a tagged hash of the outcome.
+// A digit of a numeric outcome is encoded as a decimal string (e.g. "7").
+func OutcomeMsg(outcome string) []byte {
+	return taggedHash(outcomeTag, []byte(outcome))
+}
+
+// OutcomeMsgs encodes outcome strings into canonical messages
+func OutcomeMsgs(outcomes ...string) [][]byte {
+	msgs := [][]byte{}
+	for _, outcome := range outcomes {
+		msgs = append(msgs, OutcomeMsg(outcome))
+	}
+	return msgs
+}
+
+// IsCanonicalMsg checks if a message is canonically encoded
+func IsCanonicalMsg(m []byte) bool {
+	return len(m) == MsgSize
+}
+
+// taggedHash computes SHA256(SHA256(tag) || SHA256(tag) || msg)
+func taggedHash(tag string, msg []byte) []byte {
+	th := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(th[:])
+	h.Write(th[:])
+	h.Write(msg)
+	return h.Sum(nil)
+}
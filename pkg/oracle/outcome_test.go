@@ -0,0 +1,25 @@
+package oracle
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutcomeMsg(t *testing.T) {
+	assert := assert.New(t)
+
+	// SHA256(SHA256(tag) || SHA256(tag) || "7")
+	m := OutcomeMsg("7")
+	assert.Equal(
+		"37d834abe5636b8eb4b8de57361fcc0b2cf2f0c36d8b834b94f7ccfb9971d425",
+		hex.EncodeToString(m))
+	assert.True(IsCanonicalMsg(m))
+
+	assert.Equal([][]byte{OutcomeMsg("fine"), OutcomeMsg("晴れ")},
+		OutcomeMsgs("fine", "晴れ"))
+	assert.NotEqual(OutcomeMsg(""), CancelMsg)
+	assert.True(IsCanonicalMsg(CancelMsg))
+	assert.False(IsCanonicalMsg([]byte("7")))
+}
@@ -0,0 +1,114 @@
+package oracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// Tags separating digests signed by oracle's long-term keys
+const (
+	rotationTag     = "dlc/oracle/rotation"
+	announcementTag = "dlc/oracle/announcement"
+)
+
+// KeyRotation publishes oracle's long-term pubkey of a new key epoch.
+// It's signed by the long-term key of the previous epoch,
+// so that contractors can keep trusting the oracle from its first pubkey.
+type KeyRotation struct {
+	Epoch  int              // new key epoch
+	Pubkey *btcec.PublicKey // long-term pubkey of the new epoch
+	Sig    []byte           // DER signature by the previous epoch's key
+}
+
+// RotationDigest returns a digest to sign for a key rotation
+func RotationDigest(epoch int, pub *btcec.PublicKey) []byte {
+	h := sha256.New()
+	h.Write([]byte(rotationTag))
+	writeUint32(h, uint32(epoch))
+	h.Write(pub.SerializeCompressed())
+	return h.Sum(nil)
+}
+
+// Verify verifies the rotation is signed by the previous epoch's key
+func (r KeyRotation) Verify(prev *btcec.PublicKey) error {
+	return verifyDERSig(RotationDigest(r.Epoch, r.Pubkey), r.Sig, prev)
+}
+
+// VerifyRotations verifies a chain of key rotations from oracle's first pubkey,
+// and returns the long-term pubkey of the last epoch
+func VerifyRotations(
+	identity *btcec.PublicKey, rotations []KeyRotation) (*btcec.PublicKey, error) {
+	pub := identity
+	for i, r := range rotations {
+		if r.Epoch != i+1 {
+			return nil, fmt.Errorf(
+				"unexpected epoch. expected %d, but got %d", i+1, r.Epoch)
+		}
+		if err := r.Verify(pub); err != nil {
+			return nil, fmt.Errorf("invalid rotation to epoch %d: %v", r.Epoch, err)
+		}
+		pub = r.Pubkey
+	}
+	return pub, nil
+}
+
+// Digest returns a digest of the announcement to sign
+func (p PubkeySet) Digest() []byte {
+	h := sha256.New()
+	h.Write([]byte(announcementTag))
+	writeUint32(h, uint32(p.Epoch))
+	writeBytes(h, []byte(p.ID.Stream))
+	writeBytes(h, []byte(p.ID.ID))
+	h.Write(p.Pubkey.SerializeCompressed())
+	writeUint32(h, uint32(len(p.CommittedRpoints)))
+	for _, R := range p.CommittedRpoints {
+		h.Write(R.SerializeCompressed())
+	}
+	return h.Sum(nil)
+}
+
+// Verify verifies the announcement is signed by the key of its epoch,
+// which is reached from oracle's first pubkey by the key rotations
+func (p PubkeySet) Verify(identity *btcec.PublicKey) error {
+	if len(p.Rotations) != p.Epoch {
+		return fmt.Errorf("rotations don't reach epoch %d", p.Epoch)
+	}
+
+	pub, err := VerifyRotations(identity, p.Rotations)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyDERSig(p.Digest(), p.Sig, pub); err != nil {
+		return fmt.Errorf("invalid announcement: %v", err)
+	}
+	return nil
+}
+
+func verifyDERSig(digest, sig []byte, pub *btcec.PublicKey) error {
+	s, err := btcec.ParseDERSignature(sig, btcec.S256())
+	if err != nil {
+		return err
+	}
+	if !s.Verify(digest, pub) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func writeUint32(w io.Writer, n uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	w.Write(b)
+}
+
+// writeBytes writes length prefixed bytes
+func writeBytes(w io.Writer, b []byte) {
+	writeUint32(w, uint32(len(b)))
+	w.Write(b)
+}
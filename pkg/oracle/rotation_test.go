@@ -0,0 +1,79 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgarage/dlc/internal/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func rotate(
+	t *testing.T, epoch int, prev *btcec.PrivateKey) (*btcec.PrivateKey, KeyRotation) {
+	priv, pub := test.RandKeys()
+	sig, err := prev.Sign(RotationDigest(epoch, pub))
+	assert.NoError(t, err)
+	return priv, KeyRotation{Epoch: epoch, Pubkey: pub, Sig: sig.Serialize()}
+}
+
+func TestVerifyRotations(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, identity := test.RandKeys()
+	priv1, r1 := rotate(t, 1, priv0)
+	_, r2 := rotate(t, 2, priv1)
+
+	pub, err := VerifyRotations(identity, []KeyRotation{r1, r2})
+	assert.NoError(err)
+	assert.True(r2.Pubkey.IsEqual(pub))
+
+	// no rotations
+	pub, err = VerifyRotations(identity, nil)
+	assert.NoError(err)
+	assert.True(identity.IsEqual(pub))
+
+	// fail with a missing rotation
+	_, err = VerifyRotations(identity, []KeyRotation{r2})
+	assert.Error(err)
+
+	// fail with a rotation not signed by the previous key
+	_, r2Invalid := rotate(t, 2, priv0)
+	_, err = VerifyRotations(identity, []KeyRotation{r1, r2Invalid})
+	assert.Error(err)
+}
+
+func TestPubkeySetVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, identity := test.RandKeys()
+	priv1, r1 := rotate(t, 1, priv0)
+	_, V := test.RandKeys()
+	_, R := test.RandKeys()
+
+	pubset := PubkeySet{
+		Pubkey:           V,
+		CommittedRpoints: []*btcec.PublicKey{R},
+		ID:               EventID{Stream: "test", ID: "1"},
+		Epoch:            1,
+		Rotations:        []KeyRotation{r1},
+	}
+	sig, _ := priv1.Sign(pubset.Digest())
+	pubset.Sig = sig.Serialize()
+	assert.NoError(pubset.Verify(identity))
+
+	// fail with a modified announcement
+	modified := pubset
+	modified.ID = EventID{Stream: "test", ID: "2"}
+	assert.Error(modified.Verify(identity))
+
+	// fail when signed by an old key
+	sig, _ = priv0.Sign(pubset.Digest())
+	modified = pubset
+	modified.Sig = sig.Serialize()
+	assert.Error(modified.Verify(identity))
+
+	// fail without rotations
+	modified = pubset
+	modified.Rotations = nil
+	assert.Error(modified.Verify(identity))
+}
@@ -0,0 +1,372 @@
+// Package psbt is a minimal implementation of partially signed bitcoin
+// transactions (BIP174) to let signers out of the wallet,
+// e.g. hardware wallets, sign fund txs.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// magic bytes of psbt
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff} // "psbt" + 0xff
+
+// maxValueSize limits the size of a key or a value
+const maxValueSize = 4000000
+
+// key types
+const (
+	globalUnsignedTxType = 0x00
+
+	inputNonWitnessUtxoType     = 0x00
+	inputWitnessUtxoType        = 0x01
+	inputPartialSigType         = 0x02
+	inputBip32DerivationType    = 0x06
+	inputFinalScriptWitnessType = 0x08
+
+	outputBip32DerivationType = 0x02
+)
+
+// Unknown is a key-value pair that this package doesn't interpret.
+// It's kept as it is to be passed to other signers.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// PartialSig is a signature for an input by a pubkey
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// Bip32Derivation is a BIP32 path of a pubkey
+// from the master key identified by a fingerprint
+type Bip32Derivation struct {
+	PubKey      []byte
+	Fingerprint uint32
+	Path        []uint32
+}
+
+// PInput is a psbt input
+type PInput struct {
+	NonWitnessUtxo     []byte // serialized tx
+	WitnessUtxo        *wire.TxOut
+	PartialSigs        []*PartialSig
+	Bip32Derivation    []*Bip32Derivation
+	FinalScriptWitness []byte // serialized witness
+	Unknowns           []*Unknown
+}
+
+// POutput is a psbt output
+type POutput struct {
+	Bip32Derivation []*Bip32Derivation
+	Unknowns        []*Unknown
+}
+
+// Packet is a psbt
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []*PInput
+	Outputs    []*POutput
+	Unknowns   []*Unknown
+}
+
+// New creates a packet of a given unsigned tx
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for i, txin := range tx.TxIn {
+		if len(txin.SignatureScript) > 0 || len(txin.Witness) > 0 {
+			return nil, fmt.Errorf("txin %d is already signed", i)
+		}
+	}
+
+	p := &Packet{UnsignedTx: tx.Copy()}
+	for range tx.TxIn {
+		p.Inputs = append(p.Inputs, &PInput{})
+	}
+	for range tx.TxOut {
+		p.Outputs = append(p.Outputs, &POutput{})
+	}
+	return p, nil
+}
+
+// Serialize writes the packet
+func (p *Packet) Serialize(w io.Writer) error {
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+
+	// global
+	var tx bytes.Buffer
+	if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+		return err
+	}
+	err := writeKV(w, []byte{globalUnsignedTxType}, tx.Bytes())
+	if err != nil {
+		return err
+	}
+	if err = writeUnknowns(w, p.Unknowns); err != nil {
+		return err
+	}
+	if err = writeSeparator(w); err != nil {
+		return err
+	}
+
+	for _, in := range p.Inputs {
+		if err = in.serialize(w); err != nil {
+			return err
+		}
+	}
+	for _, out := range p.Outputs {
+		if err = out.serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// B64Encode returns the packet encoded in base64
+func (p *Packet) B64Encode() (string, error) {
+	var b bytes.Buffer
+	if err := p.Serialize(&b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// Parse reads a packet
+func Parse(r io.Reader) (*Packet, error) {
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, m); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(m, magic) {
+		return nil, errors.New("invalid psbt magic bytes")
+	}
+
+	p := &Packet{}
+	err := readMap(r, func(k, v []byte) error {
+		if k[0] == globalUnsignedTxType && len(k) == 1 {
+			if p.UnsignedTx != nil {
+				return errors.New("duplicate unsigned tx")
+			}
+			p.UnsignedTx = &wire.MsgTx{}
+			return p.UnsignedTx.DeserializeNoWitness(bytes.NewReader(v))
+		}
+		p.Unknowns = append(p.Unknowns, &Unknown{k, v})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.UnsignedTx == nil {
+		return nil, errors.New("missing unsigned tx")
+	}
+
+	for range p.UnsignedTx.TxIn {
+		in := &PInput{}
+		if err = readMap(r, in.parse); err != nil {
+			return nil, err
+		}
+		p.Inputs = append(p.Inputs, in)
+	}
+	for range p.UnsignedTx.TxOut {
+		out := &POutput{}
+		if err = readMap(r, out.parse); err != nil {
+			return nil, err
+		}
+		p.Outputs = append(p.Outputs, out)
+	}
+	return p, nil
+}
+
+// B64Decode parses a packet encoded in base64
+func B64Decode(s string) (*Packet, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+func (in *PInput) serialize(w io.Writer) error {
+	if in.NonWitnessUtxo != nil {
+		err := writeKV(w, []byte{inputNonWitnessUtxoType}, in.NonWitnessUtxo)
+		if err != nil {
+			return err
+		}
+	}
+	if in.WitnessUtxo != nil {
+		var b bytes.Buffer
+		if err := writeTxOut(&b, in.WitnessUtxo); err != nil {
+			return err
+		}
+		err := writeKV(w, []byte{inputWitnessUtxoType}, b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	for _, sig := range in.PartialSigs {
+		k := append([]byte{inputPartialSigType}, sig.PubKey...)
+		if err := writeKV(w, k, sig.Signature); err != nil {
+			return err
+		}
+	}
+	err := writeDerivations(w, inputBip32DerivationType, in.Bip32Derivation)
+	if err != nil {
+		return err
+	}
+	if in.FinalScriptWitness != nil {
+		err = writeKV(
+			w, []byte{inputFinalScriptWitnessType}, in.FinalScriptWitness)
+		if err != nil {
+			return err
+		}
+	}
+	if err = writeUnknowns(w, in.Unknowns); err != nil {
+		return err
+	}
+	return writeSeparator(w)
+}
+
+func (in *PInput) parse(k, v []byte) error {
+	var err error
+	switch k[0] {
+	case inputNonWitnessUtxoType:
+		in.NonWitnessUtxo = v
+	case inputWitnessUtxoType:
+		in.WitnessUtxo, err = readTxOut(bytes.NewReader(v))
+	case inputPartialSigType:
+		in.PartialSigs = append(in.PartialSigs, &PartialSig{k[1:], v})
+	case inputBip32DerivationType:
+		var d *Bip32Derivation
+		d, err = parseDerivation(k[1:], v)
+		in.Bip32Derivation = append(in.Bip32Derivation, d)
+	case inputFinalScriptWitnessType:
+		in.FinalScriptWitness = v
+	default:
+		in.Unknowns = append(in.Unknowns, &Unknown{k, v})
+	}
+	return err
+}
+
+func (out *POutput) serialize(w io.Writer) error {
+	err := writeDerivations(w, outputBip32DerivationType, out.Bip32Derivation)
+	if err != nil {
+		return err
+	}
+	if err = writeUnknowns(w, out.Unknowns); err != nil {
+		return err
+	}
+	return writeSeparator(w)
+}
+
+func (out *POutput) parse(k, v []byte) error {
+	if k[0] == outputBip32DerivationType {
+		d, err := parseDerivation(k[1:], v)
+		if err != nil {
+			return err
+		}
+		out.Bip32Derivation = append(out.Bip32Derivation, d)
+		return nil
+	}
+	out.Unknowns = append(out.Unknowns, &Unknown{k, v})
+	return nil
+}
+
+// readMap reads key-value pairs until a separator
+func readMap(r io.Reader, f func(k, v []byte) error) error {
+	for {
+		k, err := wire.ReadVarBytes(r, 0, maxValueSize, "key")
+		if err != nil {
+			return err
+		}
+		if len(k) == 0 {
+			return nil // separator
+		}
+		v, err := wire.ReadVarBytes(r, 0, maxValueSize, "value")
+		if err != nil {
+			return err
+		}
+		if err = f(k, v); err != nil {
+			return err
+		}
+	}
+}
+
+func writeKV(w io.Writer, k, v []byte) error {
+	if err := wire.WriteVarBytes(w, 0, k); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, v)
+}
+
+func writeSeparator(w io.Writer) error {
+	_, err := w.Write([]byte{0x00})
+	return err
+}
+
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+	for _, u := range unknowns {
+		if err := writeKV(w, u.Key, u.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDerivations(
+	w io.Writer, keyType byte, derivations []*Bip32Derivation) error {
+	for _, d := range derivations {
+		k := append([]byte{keyType}, d.PubKey...)
+		v := make([]byte, 4*(len(d.Path)+1))
+		binary.LittleEndian.PutUint32(v, d.Fingerprint)
+		for i, idx := range d.Path {
+			binary.LittleEndian.PutUint32(v[4*(i+1):], idx)
+		}
+		if err := writeKV(w, k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseDerivation(pub, v []byte) (*Bip32Derivation, error) {
+	if len(v) == 0 || len(v)%4 != 0 {
+		return nil, errors.New("invalid bip32 derivation")
+	}
+	d := &Bip32Derivation{
+		PubKey:      pub,
+		Fingerprint: binary.LittleEndian.Uint32(v),
+	}
+	for i := 4; i < len(v); i += 4 {
+		d.Path = append(d.Path, binary.LittleEndian.Uint32(v[i:]))
+	}
+	return d, nil
+}
+
+func writeTxOut(w io.Writer, txout *wire.TxOut) error {
+	err := binary.Write(w, binary.LittleEndian, txout.Value)
+	if err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, txout.PkScript)
+}
+
+func readTxOut(r io.Reader) (*wire.TxOut, error) {
+	var value int64
+	if err := binary.Read(r, binary.LittleEndian, &value); err != nil {
+		return nil, err
+	}
+	pkScript, err := wire.ReadVarBytes(r, 0, maxValueSize, "pkScript")
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewTxOut(value, pkScript), nil
+}
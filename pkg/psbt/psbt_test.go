@@ -0,0 +1,85 @@
+package psbt
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTx() *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 1), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(10000, []byte{0x00, 0x14}))
+	return tx
+}
+
+func TestPacketEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := New(newTestTx())
+	assert.NoError(err)
+	assert.Len(p.Inputs, 2)
+	assert.Len(p.Outputs, 1)
+
+	pub := make([]byte, 33)
+	pub[0] = 0x02
+	p.Inputs[0].WitnessUtxo = wire.NewTxOut(20000, []byte{0x00, 0x14, 0x01})
+	p.Inputs[0].Bip32Derivation = []*Bip32Derivation{{
+		PubKey:      pub,
+		Fingerprint: 0xdeadbeef,
+		Path:        []uint32{84 + 0x80000000, 0x80000000, 0x80000000, 0, 1},
+	}}
+	p.Inputs[1].PartialSigs = []*PartialSig{{PubKey: pub, Signature: []byte{1}}}
+	p.Inputs[1].Unknowns = []*Unknown{{Key: []byte{0xfc, 1}, Value: []byte{2}}}
+	p.Outputs[0].Unknowns = []*Unknown{{Key: []byte{0xfc}, Value: []byte{3}}}
+
+	s, err := p.B64Encode()
+	assert.NoError(err)
+	assert.Equal("cHNidP8", s[:7])
+
+	decoded, err := B64Decode(s)
+	assert.NoError(err)
+	assert.Equal(p.UnsignedTx.TxHash(), decoded.UnsignedTx.TxHash())
+	assert.Equal(p.Inputs, decoded.Inputs)
+	assert.Equal(p.Outputs, decoded.Outputs)
+}
+
+func TestNewWithSignedTx(t *testing.T) {
+	tx := newTestTx()
+	tx.TxIn[1].Witness = wire.TxWitness{{1}}
+
+	_, err := New(tx)
+	assert.Error(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := B64Decode("AAAA")
+	assert.Error(t, err)
+}
+
+func TestWitness(t *testing.T) {
+	assert := assert.New(t)
+
+	// finalized
+	wit := wire.TxWitness{{1, 2}, {}, {3}}
+	b, err := SerializeWitness(wit)
+	assert.NoError(err)
+	in := &PInput{FinalScriptWitness: b}
+	parsed, err := in.Witness()
+	assert.NoError(err)
+	assert.Equal(wit, parsed)
+
+	// p2wpkh with a partial sig
+	pkScript := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	in = &PInput{WitnessUtxo: wire.NewTxOut(1, pkScript)}
+	_, err = in.Witness()
+	assert.Error(err, "not signed")
+
+	in.PartialSigs = []*PartialSig{{PubKey: []byte{2}, Signature: []byte{1}}}
+	parsed, err = in.Witness()
+	assert.NoError(err)
+	assert.Equal(wire.TxWitness{{1}, {2}}, parsed)
+}
@@ -0,0 +1,66 @@
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// maxWitnessItems limits the number of witness items
+const maxWitnessItems = 500000
+
+// SerializeWitness serializes a witness for FinalScriptWitness
+func SerializeWitness(wit wire.TxWitness) ([]byte, error) {
+	var b bytes.Buffer
+	if err := wire.WriteVarInt(&b, 0, uint64(len(wit))); err != nil {
+		return nil, err
+	}
+	for _, item := range wit {
+		if err := wire.WriteVarBytes(&b, 0, item); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// ParseWitness parses a serialized witness
+func ParseWitness(b []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(b)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxWitnessItems {
+		return nil, errors.New("too many witness items")
+	}
+
+	wit := make(wire.TxWitness, n)
+	for i := range wit {
+		wit[i], err = wire.ReadVarBytes(r, 0, maxValueSize, "witness item")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wit, nil
+}
+
+// Witness returns the witness of a signed input.
+// It's the final script witness if the input is finalized,
+// or built from the partial signature if the input is p2wpkh.
+func (in *PInput) Witness() (wire.TxWitness, error) {
+	if in.FinalScriptWitness != nil {
+		return ParseWitness(in.FinalScriptWitness)
+	}
+
+	if in.WitnessUtxo == nil ||
+		!txscript.IsPayToWitnessPubKeyHash(in.WitnessUtxo.PkScript) {
+		return nil, errors.New("input isn't finalized")
+	}
+	if len(in.PartialSigs) != 1 {
+		return nil, errors.New("input isn't signed")
+	}
+	sig := in.PartialSigs[0]
+	return wire.TxWitness{sig.Signature, sig.PubKey}, nil
+}
@@ -2,6 +2,7 @@ package schnorr
 
 import (
 	"crypto/sha256"
+	"errors"
 	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
@@ -82,7 +83,7 @@ func Sign(opriv, rpriv *btcec.PrivateKey, m []byte) []byte {
 	// s mod N
 	s = new(big.Int).Mod(s, btcec.S256().N)
 
-	return s.Bytes()
+	return ScalarBytes(s)
 }
 
 // SumSigns sums signs up for a multi-message commitment
@@ -92,7 +93,59 @@ func SumSigns(signs [][]byte) []byte {
 		sb := new(big.Int).SetBytes(sign)
 		sum = new(big.Int).Add(sum, sb)
 	}
-	return sum.Bytes()
+	sum = new(big.Int).Mod(sum, btcec.S256().N)
+	return ScalarBytes(sum)
+}
+
+// SignSize is a size of a canonical sign
+const SignSize = 32
+
+// ScalarBytes encodes a scalar into a canonical sign,
+// 32-byte big-endian padded with zeros
+func ScalarBytes(k *big.Int) []byte {
+	b := make([]byte, SignSize)
+	kb := k.Bytes()
+	copy(b[SignSize-len(kb):], kb)
+	return b
+}
+
+// IsCanonicalSign checks if a sign is 32 bytes
+// and less than the order of the curve
+func IsCanonicalSign(sign []byte) bool {
+	if len(sign) != SignSize {
+		return false
+	}
+	return new(big.Int).SetBytes(sign).Cmp(btcec.S256().N) < 0
+}
+
+// RecoverPrivkey recovers the private key from two signs
+// for different messages under the same R-point
+//   s1 - s2 = (h(R, m2) - h(R, m1)) * v
+// Where
+//   s1, s2: signs for the messages m1, m2
+//   v: oracle's private key
+func RecoverPrivkey(
+	R *btcec.PublicKey, m1, sign1, m2, sign2 []byte) (*btcec.PrivateKey, error) {
+	N := btcec.S256().N
+
+	// h(R, m2) - h(R, m1)
+	dh := new(big.Int).Sub(hash(R, m2), hash(R, m1))
+	dh = new(big.Int).Mod(dh, N)
+	if dh.Sign() == 0 {
+		return nil, errors.New("messages must have different hashes")
+	}
+
+	// s1 - s2
+	ds := new(big.Int).Sub(
+		new(big.Int).SetBytes(sign1), new(big.Int).SetBytes(sign2))
+	ds = new(big.Int).Mod(ds, N)
+
+	// v = (s1 - s2) / (h(R, m2) - h(R, m1))
+	v := new(big.Int).Mul(ds, new(big.Int).ModInverse(dh, N))
+	v = new(big.Int).Mod(v, N)
+
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), v.Bytes())
+	return priv, nil
 }
 
 func hash(R *btcec.PublicKey, m []byte) *big.Int {
@@ -105,7 +158,12 @@ func hash(R *btcec.PublicKey, m []byte) *big.Int {
 }
 
 // Verify verfies sG = R - h(R, m) * V
+// Only canonical signs are valid.
 func Verify(P *btcec.PublicKey, sign []byte) bool {
+	if !IsCanonicalSign(sign) {
+		return false
+	}
+
 	sG := new(btcec.PublicKey)
 	sG.X, sG.Y = btcec.S256().ScalarBaseMult(sign)
 	return P.IsEqual(sG)
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/stretchr/testify/assert"
@@ -40,6 +41,26 @@ func TestSchnorrSignature(t *testing.T) {
 	assert.False(Verify(sG, sign2))
 }
 
+func TestCanonicalSign(t *testing.T) {
+	assert := assert.New(t)
+
+	// signs are always 32 bytes
+	small := ScalarBytes(big.NewInt(1))
+	assert.Len(small, SignSize)
+	assert.True(IsCanonicalSign(small))
+	assert.Len(SumSigns([][]byte{small, small}), SignSize)
+
+	// non-canonical encodings of the same scalar
+	assert.False(IsCanonicalSign(big.NewInt(1).Bytes()))
+	assert.False(IsCanonicalSign(append([]byte{0}, small...)))
+	assert.False(IsCanonicalSign(ScalarBytes(btcec.S256().N)))
+
+	// the sum is reduced by the order of the curve
+	N1 := new(big.Int).Sub(btcec.S256().N, big.NewInt(1))
+	sum := SumSigns([][]byte{ScalarBytes(N1), ScalarBytes(big.NewInt(2))})
+	assert.Equal(small, sum)
+}
+
 func randExtKey() (*hdkeychain.ExtendedKey, error) {
 	seed, err := hdkeychain.GenerateSeed(hdkeychain.MinSeedBytes)
 	if err != nil {
@@ -47,3 +68,27 @@ func randExtKey() (*hdkeychain.ExtendedKey, error) {
 	}
 	return hdkeychain.NewMaster(seed, &chaincfg.RegressionNetParams)
 }
+
+func TestRecoverPrivkey(t *testing.T) {
+	assert := assert.New(t)
+
+	extKey, _ := randExtKey()
+	extKeyChild, _ := extKey.Child(1)
+	opriv, _ := extKey.ECPrivKey()
+	rpriv, _ := extKeyChild.ECPrivKey()
+	R := rpriv.PubKey()
+
+	// signs for different messages under the same R-point
+	m1 := big.NewInt(int64(1)).Bytes()
+	m2 := big.NewInt(int64(2)).Bytes()
+	sign1 := Sign(opriv, rpriv, m1)
+	sign2 := Sign(opriv, rpriv, m2)
+
+	priv, err := RecoverPrivkey(R, m1, sign1, m2, sign2)
+	assert.NoError(err)
+	assert.Equal(opriv.D, priv.D)
+
+	// fail with the same message
+	_, err = RecoverPrivkey(R, m1, sign1, m1, sign1)
+	assert.Error(err)
+}
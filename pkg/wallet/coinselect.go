@@ -0,0 +1,312 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// minChange is the least change worth a txout. Smaller change goes to fee.
+// It's the dust threshold of a p2pkh output at the default relay fee,
+// which is above the p2wpkh one (294), and it must be kept equal to
+// dlc.DustLimit so that a change txout is never rejected as dust.
+const minChange = btcutil.Amount(546)
+
+// bnbMaxTries is the max number of branches BranchAndBound explores
+const bnbMaxTries = 100000
+
+// ErrNotEnoughUtxos is returned if utxos don't cover a target amount
+var ErrNotEnoughUtxos = errors.New("not enough utxos")
+
+// ErrNoChangelessSelection is returned by BranchAndBound
+// if no combination of utxos avoids change
+var ErrNoChangelessSelection = errors.New("no changeless selection")
+
+// Selection is a result of coin selection
+type Selection struct {
+	Utxos  []Utxo
+	Change btcutil.Amount
+
+	// Waste is the cost of a selection compared to an ideal one.
+	// It's the cost of creating and spending the change txout
+	// if the selection has change, or the excess paid to fee otherwise.
+	// It assumes that the current feerate is the long term feerate.
+	Waste btcutil.Amount
+}
+
+// CoinSelector is a strategy to select utxos for a target amount
+// by considering additional fee per txin and txout
+type CoinSelector interface {
+	Select(
+		utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+	) (*Selection, error)
+}
+
+// DefaultCoinSelector is used if no selector is given.
+// It looks for a changeless selection and falls back to largest-first.
+var DefaultCoinSelector CoinSelector = fallbackSelector{
+	BranchAndBound{}, LargestFirst{}}
+
+// SelectCoins selects utxos by a given selector or the default one
+func SelectCoins(
+	selector CoinSelector, utxos []Utxo,
+	amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	if selector == nil {
+		selector = DefaultCoinSelector
+	}
+	return selector.Select(utxos, amt, feePerTxIn, feePerTxOut)
+}
+
+// newSelection evaluates utxos for a target amount.
+// It returns nil if they're not enough.
+func newSelection(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) *Selection {
+	total := utxosTotal(utxos)
+	fee := feePerTxIn.MulF64(float64(len(utxos)))
+	excess := total - amt - fee
+	if excess < 0 {
+		return nil
+	}
+
+	s := &Selection{Utxos: utxos}
+	if excess-feePerTxOut >= minChange {
+		s.Change = excess - feePerTxOut
+		s.Waste = feePerTxOut + feePerTxIn
+	} else {
+		s.Waste = excess
+	}
+	return s
+}
+
+// accumulate adds utxos in order until they cover a target amount
+func accumulate(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	for i := range utxos {
+		s := newSelection(utxos[:i+1], amt, feePerTxIn, feePerTxOut)
+		if s != nil {
+			return s, nil
+		}
+	}
+	return nil, ErrNotEnoughUtxos
+}
+
+// LargestFirst selects the largest utxos first,
+// which minimizes the number of txins
+type LargestFirst struct{}
+
+// Select is an implementation of CoinSelector.Select
+func (LargestFirst) Select(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	if err := checkUtxoAmounts(utxos); err != nil {
+		return nil, err
+	}
+	sorted := sortUtxos(utxos, func(a, b Utxo) bool {
+		return a.Amount > b.Amount
+	})
+	return accumulate(sorted, amt, feePerTxIn, feePerTxOut)
+}
+
+// OldestFirst selects utxos with the most confirmations first,
+// which consolidates old utxos
+type OldestFirst struct{}
+
+// Select is an implementation of CoinSelector.Select
+func (OldestFirst) Select(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	if err := checkUtxoAmounts(utxos); err != nil {
+		return nil, err
+	}
+	sorted := sortUtxos(utxos, func(a, b Utxo) bool {
+		return a.Confirmations > b.Confirmations
+	})
+	return accumulate(sorted, amt, feePerTxIn, feePerTxOut)
+}
+
+// PrivacyPreferring avoids linking addresses in a tx.
+// It selects utxos of a single address with the least waste if possible,
+// otherwise spends all utxos of addresses in the descending order of
+// their balances so that no address is left partially spent.
+type PrivacyPreferring struct{}
+
+// Select is an implementation of CoinSelector.Select
+func (PrivacyPreferring) Select(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	if err := checkUtxoAmounts(utxos); err != nil {
+		return nil, err
+	}
+	var addrs []string
+	groups := make(map[string][]Utxo)
+	for _, utxo := range utxos {
+		if _, ok := groups[utxo.Address]; !ok {
+			addrs = append(addrs, utxo.Address)
+		}
+		groups[utxo.Address] = append(groups[utxo.Address], utxo)
+	}
+
+	// single address
+	var best *Selection
+	for _, addr := range addrs {
+		s, err := LargestFirst{}.Select(
+			groups[addr], amt, feePerTxIn, feePerTxOut)
+		if err != nil {
+			continue
+		}
+		if best == nil || s.Waste < best.Waste {
+			best = s
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+
+	// whole addresses
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return utxosTotal(groups[addrs[i]]) > utxosTotal(groups[addrs[j]])
+	})
+	var selected []Utxo
+	for _, addr := range addrs {
+		selected = append(selected, groups[addr]...)
+		s := newSelection(selected, amt, feePerTxIn, feePerTxOut)
+		if s != nil {
+			return s, nil
+		}
+	}
+	return nil, ErrNotEnoughUtxos
+}
+
+// BranchAndBound searches for a selection without change,
+// whose excess is less than the cost of creating and spending change.
+// It returns ErrNoChangelessSelection if nothing is found.
+type BranchAndBound struct{}
+
+// Select is an implementation of CoinSelector.Select
+func (BranchAndBound) Select(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (*Selection, error) {
+	if err := checkUtxoAmounts(utxos); err != nil {
+		return nil, err
+	}
+	// effective values that utxos contribute after paying their txin fees
+	var candidates []Utxo
+	var values []btcutil.Amount
+	var available btcutil.Amount
+	sorted := sortUtxos(utxos, func(a, b Utxo) bool {
+		return a.Amount > b.Amount
+	})
+	for _, utxo := range sorted {
+		v := utxoAmount(utxo) - feePerTxIn
+		if v <= 0 {
+			continue
+		}
+		candidates = append(candidates, utxo)
+		values = append(values, v)
+		available += v
+	}
+	if available < amt {
+		return nil, ErrNotEnoughUtxos
+	}
+
+	costOfChange := feePerTxOut + feePerTxIn
+	upper := amt + costOfChange
+
+	var best []bool
+	bestExcess := costOfChange + 1
+	picked := make([]bool, len(values))
+	tries := 0
+
+	var search func(i int, sum, rest btcutil.Amount)
+	search = func(i int, sum, rest btcutil.Amount) {
+		tries++
+		if tries > bnbMaxTries || sum > upper || sum+rest < amt {
+			return
+		}
+		if sum >= amt {
+			if excess := sum - amt; excess < bestExcess {
+				bestExcess = excess
+				best = append([]bool{}, picked...)
+			}
+			return
+		}
+		if i == len(values) {
+			return
+		}
+		rest -= values[i]
+
+		picked[i] = true
+		search(i+1, sum+values[i], rest)
+		picked[i] = false
+		search(i+1, sum, rest)
+	}
+	search(0, 0, available)
+
+	if best == nil {
+		return nil, ErrNoChangelessSelection
+	}
+
+	var selected []Utxo
+	for i, ok := range best {
+		if ok {
+			selected = append(selected, candidates[i])
+		}
+	}
+	return &Selection{Utxos: selected, Waste: bestExcess}, nil
+}
+
+// fallbackSelector tries selectors in order until one succeeds
+type fallbackSelector []CoinSelector
+
+func (fs fallbackSelector) Select(
+	utxos []Utxo, amt, feePerTxIn, feePerTxOut btcutil.Amount,
+) (s *Selection, err error) {
+	for _, selector := range fs {
+		s, err = selector.Select(utxos, amt, feePerTxIn, feePerTxOut)
+		if err == nil {
+			return s, nil
+		}
+	}
+	return nil, err
+}
+
+// sortUtxos returns utxos sorted by a given order without modifying them
+func sortUtxos(utxos []Utxo, less func(a, b Utxo) bool) []Utxo {
+	sorted := append([]Utxo{}, utxos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// checkUtxoAmounts returns an error if any utxo has an amount
+// that isn't a valid non-negative number of bitcoins
+func checkUtxoAmounts(utxos []Utxo) error {
+	for _, utxo := range utxos {
+		amt, err := btcutil.NewAmount(utxo.Amount)
+		if err != nil || amt < 0 {
+			return fmt.Errorf("invalid utxo amount %v. txid: %s, vout: %d",
+				utxo.Amount, utxo.TxID, utxo.Vout)
+		}
+	}
+	return nil
+}
+
+// utxoAmount converts the amount of a utxo checked by checkUtxoAmounts
+func utxoAmount(utxo Utxo) btcutil.Amount {
+	amt, _ := btcutil.NewAmount(utxo.Amount)
+	return amt
+}
+
+func utxosTotal(utxos []Utxo) btcutil.Amount {
+	var total btcutil.Amount
+	for _, utxo := range utxos {
+		total += utxoAmount(utxo)
+	}
+	return total
+}
@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUtxo(
+	txid string, amt btcutil.Amount, confs int64, addr string) Utxo {
+	return Utxo{
+		TxID:          txid,
+		Amount:        amt.ToBTC(),
+		Confirmations: confs,
+		Address:       addr,
+	}
+}
+
+func txids(utxos []Utxo) []string {
+	var ids []string
+	for _, utxo := range utxos {
+		ids = append(ids, utxo.TxID)
+	}
+	return ids
+}
+
+var testUtxos = []Utxo{
+	newTestUtxo("a", 30000, 10, "addr1"),
+	newTestUtxo("b", 50000, 1, "addr2"),
+	newTestUtxo("c", 20000, 100, "addr1"),
+	newTestUtxo("d", 10000, 5, "addr3"),
+}
+
+const (
+	testFeePerTxIn  = btcutil.Amount(100)
+	testFeePerTxOut = btcutil.Amount(30)
+)
+
+func TestLargestFirst(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := LargestFirst{}.Select(
+		testUtxos, 60000, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"b", "a"}, txids(s.Utxos))
+	assert.Equal(btcutil.Amount(80000-60000-200-30), s.Change)
+	assert.Equal(testFeePerTxOut+testFeePerTxIn, s.Waste)
+
+	_, err = LargestFirst{}.Select(
+		testUtxos, 110000, testFeePerTxIn, testFeePerTxOut)
+	assert.Equal(ErrNotEnoughUtxos, err)
+}
+
+func TestOldestFirst(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := OldestFirst{}.Select(
+		testUtxos, 40000, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"c", "a"}, txids(s.Utxos))
+	assert.Equal(btcutil.Amount(50000-40000-200-30), s.Change)
+}
+
+func TestPrivacyPreferring(t *testing.T) {
+	assert := assert.New(t)
+
+	// addr1 and addr2 can afford it alone, and addr1 wastes less
+	s, err := PrivacyPreferring{}.Select(
+		testUtxos, 29800, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, txids(s.Utxos))
+	assert.Equal(btcutil.Amount(100), s.Waste, "excess goes to fee")
+
+	// no address can afford it alone
+	s, err = PrivacyPreferring{}.Select(
+		testUtxos, 70000, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"a", "c", "b"}, txids(s.Utxos),
+		"addresses should be spent entirely")
+}
+
+func TestBranchAndBound(t *testing.T) {
+	assert := assert.New(t)
+
+	// c + d pays the target without change
+	s, err := BranchAndBound{}.Select(
+		testUtxos, 29750, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"c", "d"}, txids(s.Utxos))
+	assert.Equal(btcutil.Amount(0), s.Change)
+	assert.Equal(btcutil.Amount(50), s.Waste)
+
+	// prefers an exact match
+	s, err = BranchAndBound{}.Select(
+		testUtxos, 29900, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, txids(s.Utxos))
+	assert.Equal(btcutil.Amount(0), s.Waste)
+
+	_, err = BranchAndBound{}.Select(
+		testUtxos, 35000, testFeePerTxIn, testFeePerTxOut)
+	assert.Equal(ErrNoChangelessSelection, err)
+}
+
+func TestSelectCoinsDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	// changeless if possible
+	s, err := SelectCoins(
+		nil, testUtxos, 29900, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, txids(s.Utxos))
+
+	// falls back to largest-first
+	s, err = SelectCoins(
+		nil, testUtxos, 35000, testFeePerTxIn, testFeePerTxOut)
+	assert.NoError(err)
+	assert.Equal([]string{"b"}, txids(s.Utxos))
+	assert.True(s.Change > 0)
+}
+
+func TestSelectCoinsInvalidAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	invalid := []Utxo{
+		newTestUtxo("e", 40000, 1, "addr4"),
+		{TxID: "f", Amount: math.NaN(), Address: "addr4"},
+	}
+	utxos := append(append([]Utxo{}, testUtxos...), invalid...)
+
+	selectors := []CoinSelector{
+		nil, LargestFirst{}, OldestFirst{}, PrivacyPreferring{},
+		BranchAndBound{}}
+	for _, selector := range selectors {
+		_, err := SelectCoins(
+			selector, utxos, 1000, testFeePerTxIn, testFeePerTxOut)
+		assert.Error(err)
+	}
+
+	utxos[len(utxos)-1].Amount = -0.1
+	_, err := SelectCoins(
+		nil, utxos, 1000, testFeePerTxIn, testFeePerTxOut)
+	assert.Error(err)
+}
@@ -0,0 +1,24 @@
+package wallet
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// VirtualSize returns virtual size of a tx,
+// which is used for feerate calculation
+func VirtualSize(tx *wire.MsgTx) int64 {
+	// weight = base size * 3 + total size
+	weight := tx.SerializeSizeStripped()*3 + tx.SerializeSize()
+	return int64((weight + 3) / 4)
+}
+
+// ChildFee returns fee that a child tx pays
+// so that the parent and the child are mined at a target feerate (CPFP).
+// It's 0 or less if the parent pays enough fee by itself.
+func ChildFee(
+	parentVSize, childVSize int64, parentFee, feerate btcutil.Amount,
+) btcutil.Amount {
+	pkgFee := feerate.MulF64(float64(parentVSize + childVSize))
+	return pkgFee - parentFee
+}
@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"time"
+
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -17,6 +19,12 @@ type Wallet interface {
 	// NewAddress creates a new address
 	NewAddress() (btcutil.Address, error)
 
+	// NewChangePubkey returns a new pubkey of the internal (change) branch
+	NewChangePubkey() (*btcec.PublicKey, error)
+
+	// NewChangeAddress creates a new address of the internal (change) branch
+	NewChangeAddress() (btcutil.Address, error)
+
 	// WitnessSignature returns witness signature for a given txin and pubkey
 	WitnessSignature(
 		tx *wire.MsgTx, idx int, amt btcutil.Amount, sc []byte, pub *btcec.PublicKey,
@@ -33,10 +41,18 @@ type Wallet interface {
 	WitnessSignTxByIdxs(tx *wire.MsgTx, idxs []int) ([]wire.TxWitness, error)
 
 	// SelectUtxos selects utxos for requested amount
-	// by considering additional fee per txin and txout
+	// by considering additional fee per txin and txout.
+	// DefaultCoinSelector is used if selector is nil.
 	SelectUnspent(
-		amt, feePerTxIn, feePerTxOut btcutil.Amount,
+		selector CoinSelector, amt, feePerTxIn, feePerTxOut btcutil.Amount,
 	) (utxos []Utxo, change btcutil.Amount, err error)
+
+	// LockUnspent reserves utxos until expiry so that SelectUnspent skips them
+	LockUnspent(ops []wire.OutPoint, expiry time.Time) error
+
+	// UnlockUnspent releases reserved utxos
+	UnlockUnspent(ops []wire.OutPoint) error
+
 	// Unlock unlocks address manager
 	Unlock(privPass []byte) error
 
@@ -48,6 +64,10 @@ type Wallet interface {
 	ListUnspent() (utxos []Utxo, err error)
 	SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error)
 
+	// GetTxOut returns a txout if it's unspent including the mempool,
+	// or nil if it's spent or unknown
+	GetTxOut(op wire.OutPoint) (*btcjson.GetTxOutResult, error)
+
 	Close() error
 }
 
@@ -3,6 +3,7 @@ package integration
 import (
 	"math"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
@@ -15,15 +16,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// lotteryStream is a name of oracle's event stream for lottery results
+const lotteryStream = "lottery"
+
 // Senario: There's a `lottery` oracle who publishes a random digit number everyday,
 // and 2 parties bet on tomorrow's numbers randomly
 func TestContractorMakeAndExecuteDLC(t *testing.T) {
 	// Given an oracle "Olivia"
 	nDigit := 2
-	olivia, _ := newOracle("Olivia", nDigit)
+	olivia, _ := newOracle("Olivia", lotteryStream)
 
 	// And next announcement time
 	fixingTime := nextLotteryAnnouncement()
+	oracleAnnouncesEvent(t, olivia, lotteryStream, fixingTime, nDigit)
 
 	// And a contractor "Alice"
 	alice, _ := newContractor("Alice")
@@ -42,11 +47,11 @@ func TestContractorMakeAndExecuteDLC(t *testing.T) {
 		t, alice, bob, nDigit, fixingTime, blockHeightAfterDays(2))
 
 	// And Alice offers a DLC to Bob
-	contractorGetCommitmentsFromOracle(t, alice, olivia)
+	contractorGetCommitmentsFromOracle(t, alice, olivia, lotteryStream)
 	contractorOfferCounterparty(t, alice, bob)
 
 	// And Bob accepts the offer
-	contractorGetCommitmentsFromOracle(t, bob, olivia)
+	contractorGetCommitmentsFromOracle(t, bob, olivia, lotteryStream)
 	contractorAcceptOffer(t, bob, alice)
 
 	// And Alice signs all txs and send the signs to Bob
@@ -80,10 +85,11 @@ func TestContractorMakeAndExecuteDLC(t *testing.T) {
 func TestContractorRefundDLC(t *testing.T) {
 	// Given an oracle "Olivia"
 	nDigit := 2
-	olivia, _ := newOracle("Olivia", nDigit)
+	olivia, _ := newOracle("Olivia", lotteryStream)
 
 	// And next announcement time
 	fixingTime := nextLotteryAnnouncement()
+	oracleAnnouncesEvent(t, olivia, lotteryStream, fixingTime, nDigit)
 
 	// And a contractor "Alice"
 	alice, _ := newContractor("Alice")
@@ -102,11 +108,11 @@ func TestContractorRefundDLC(t *testing.T) {
 	contractorsBetOnLottery(t, alice, bob, nDigit, fixingTime, refundUnlockAt)
 
 	// And Alice offers a DLC to Bob
-	contractorGetCommitmentsFromOracle(t, alice, olivia)
+	contractorGetCommitmentsFromOracle(t, alice, olivia, lotteryStream)
 	contractorOfferCounterparty(t, alice, bob)
 
 	// And Bob accepts the offer
-	contractorGetCommitmentsFromOracle(t, bob, olivia)
+	contractorGetCommitmentsFromOracle(t, bob, olivia, lotteryStream)
 	contractorAcceptOffer(t, bob, alice)
 
 	// And Alice signs all txs and send the signs to Bob
@@ -194,22 +200,22 @@ func randomDealAmts(famt int) (btcutil.Amount, btcutil.Amount) {
 	return utils.ItoAmt(a), utils.ItoAmt(b)
 }
 
-// convert a n-digit number to byte
-// e.g. 123 -> [][]byte{{1}, {2}, {3}}
+// convert a n-digit number to messages
+// e.g. 123 -> OutcomeMsgs("3", "2", "1")
 func nDigitToBytes(d int, n int) [][]byte {
-	b := make([][]byte, n)
+	digits := make([]string, n)
 	for i := 0; i < n; i++ {
-		b[i] = []byte{byte(d % 10)}
+		digits[i] = strconv.Itoa(d % 10)
 		d = d / 10
 	}
-	return b
+	return oracle.OutcomeMsgs(digits...)
 }
 
 func oracleFixLottery(
 	t *testing.T, o *oracle.Oracle, n int, ftime time.Time) {
 	msgs := nDigitToBytes(randomNumber(n), n)
 
-	err := o.FixMsgs(ftime, msgs)
+	err := o.FixMsgs(oracle.TimeEventID(lotteryStream, ftime), msgs)
 	assert.NoError(t, err)
 }
 
@@ -234,7 +240,7 @@ func contractorFixLotteryDeal(
 		idxs = append(idxs, i)
 	}
 
-	contractorFixDeal(t, c, o, idxs)
+	contractorFixDeal(t, c, o, lotteryStream, idxs)
 }
 
 func contractorCannotFixLotteryDeal(
@@ -246,7 +252,7 @@ func contractorCannotFixLotteryDeal(
 		idxs = append(idxs, i)
 	}
 
-	contractorCannotFixDeal(t, c, o, idxs)
+	contractorCannotFixDeal(t, c, o, lotteryStream, idxs)
 }
 
 func oracleFixInvalidLottery(
@@ -254,14 +260,14 @@ func oracleFixInvalidLottery(
 
 	msgs := randomStringMsgs(n)
 
-	err := o.FixMsgs(ftime, msgs)
+	err := o.FixMsgs(oracle.TimeEventID(lotteryStream, ftime), msgs)
 	assert.NoError(t, err)
 }
 
 func randomStringMsgs(n int) [][]byte {
 	msgs := make([][]byte, n)
 	for i := 0; i < n; i++ {
-		msgs[i] = []byte(string(randomChar()))
+		msgs[i] = oracle.OutcomeMsgs(string(randomChar()))[0]
 	}
 	return msgs
 }
@@ -5,14 +5,15 @@ import (
 	"github.com/dgarage/dlc/internal/oracle"
 )
 
-// NewOracle creates an oracle for integration tests
-func newOracle(name string, nPoints int) (*oracle.Oracle, error) {
+// NewOracle creates an oracle that publishes events in a given stream for integration tests
+func newOracle(name string, stream string) (*oracle.Oracle, error) {
 	params := chaincfg.RegressionNetParams
 
-	o, err := oracle.New(name, params, nPoints)
+	o, err := oracle.New(name, params)
 	if err != nil {
 		return nil, err
 	}
 	o.InitDB()
-	return o, nil
+	err = o.AddStream(stream)
+	return o, err
 }
@@ -6,15 +6,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/dgarage/dlc/internal/oracle"
 	"github.com/dgarage/dlc/pkg/dlc"
 	"github.com/stretchr/testify/assert"
 )
 
+// weatherStream is a name of oracle's event stream for weather information
+const weatherStream = "weather/tokyo"
+
 func TestOracleCommitAndSign(t *testing.T) {
 	// Given an oracle "Olivia" who provides weather information
 	//   weather info contains "weather" "temperature" "windspeed"
-	olivia, _ := newOracle("Olivia", 3)
+	olivia, _ := newOracle("Olivia", weatherStream)
 
 	// And a contractor "Alice"
 	alice, _ := newContractor("Alice")
@@ -22,6 +26,9 @@ func TestOracleCommitAndSign(t *testing.T) {
 	// And Alice bet on "weather" and "temprature" at a future time
 	fixingTime := contractorBetOnWeatherAndTemperature(t, alice)
 
+	// And Olivia announces weather info at the time
+	oracleAnnouncesEvent(t, olivia, weatherStream, fixingTime, 3)
+
 	// Alice asks Olivia to fix weather info at the fixing time
 	contractorAsksOracleToCommit(t, alice, olivia)
 
@@ -36,21 +43,22 @@ func TestOracleCommitAndSign(t *testing.T) {
 }
 
 func newWeather(weather string, temp int, windSpeed int) [][]byte {
-	return [][]byte{
-		[]byte(weather),
-		[]byte(strconv.Itoa(temp)),
-		[]byte(strconv.Itoa(windSpeed)),
-	}
+	return oracle.OutcomeMsgs(
+		weather,
+		strconv.Itoa(temp),
+		strconv.Itoa(windSpeed),
+	)
 }
 
 func contractorBetOnWeatherAndTemperature(t *testing.T, c *Contractor) time.Time {
-	deal1 := dlc.NewDeal(2, 0, newWeather("fine", 20, 0)[:2])
-	deal2 := dlc.NewDeal(1, 1, newWeather("fine", 10, 0)[:2])
-	deal3 := dlc.NewDeal(1, 1, newWeather("rain", 20, 0)[:2])
-	deal4 := dlc.NewDeal(0, 2, newWeather("rain", 10, 0)[:2])
+	var onebtc btcutil.Amount = 1 * btcutil.SatoshiPerBitcoin
+	deal1 := dlc.NewDeal(2*onebtc, 0, newWeather("fine", 20, 0)[:2])
+	deal2 := dlc.NewDeal(onebtc, onebtc, newWeather("fine", 10, 0)[:2])
+	deal3 := dlc.NewDeal(onebtc, onebtc, newWeather("rain", 20, 0)[:2])
+	deal4 := dlc.NewDeal(0, 2*onebtc, newWeather("rain", 10, 0)[:2])
 	deals := []*dlc.Deal{deal1, deal2, deal3, deal4}
 	fixingTime := time.Now().AddDate(0, 0, 1)
-	conds, err := dlc.NewConditions(fixingTime, 1, 1, 1, 1, 1, deals)
+	conds, err := dlc.NewConditions(fixingTime, onebtc, onebtc, 1, 1, 1, deals)
 	assert.NoError(t, err)
 	c.createDLCBuilder(conds, dlc.FirstParty)
 	return fixingTime
@@ -60,10 +68,14 @@ func contractorAsksOracleToCommit(
 	t *testing.T, c *Contractor, o *oracle.Oracle) {
 	ftime := c.DLCBuilder.DLC().Conds.FixingTime
 
-	pubkeySet, err := o.PubkeySet(ftime)
+	pubkeySet, err := o.PubkeySet(oracle.TimeEventID(weatherStream, ftime))
 	assert.NoError(t, err)
 
-	c.DLCBuilder.SetOraclePubkeySet(&pubkeySet)
+	identity, err := o.Pubkey()
+	assert.NoError(t, err)
+	c.DLCBuilder.SetOracleIdentity(identity)
+	err = c.DLCBuilder.SetOraclePubkeySet(&pubkeySet)
+	assert.NoError(t, err)
 }
 
 func oracleFixesWeather(
@@ -81,7 +93,7 @@ func oracleFixesWeather(
 
 	fixingMsg := randomMsg(msgs)
 
-	err := o.FixMsgs(ftime, fixingMsg)
+	err := o.FixMsgs(oracle.TimeEventID(weatherStream, ftime), fixingMsg)
 	assert.NoError(t, err)
 
 	return fixingMsg
@@ -96,7 +108,7 @@ func randomMsg(msgs [][][]byte) [][]byte {
 
 func contractorFixesWeatherDeal(t *testing.T, c *Contractor, o *oracle.Oracle) {
 	idxs := []int{0, 1} // use only weather and temperature
-	contractorFixDeal(t, c, o, idxs)
+	contractorFixDeal(t, c, o, weatherStream, idxs)
 }
 
 func shouldFixedDealSameWithFixedWeather(t *testing.T, c *Contractor, fixedWeather [][]byte) {
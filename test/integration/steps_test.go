@@ -2,6 +2,7 @@ package integration
 
 import (
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -18,30 +19,46 @@ func contratorHasBalance(t *testing.T, c *Contractor, balance btcutil.Amount) {
 	assert.NoError(t, err)
 }
 
-func contractorGetCommitmentsFromOracle(t *testing.T, c *Contractor, o *oracle.Oracle) {
+// oracleAnnouncesEvent adds an event fixed at a given time to the stream
+func oracleAnnouncesEvent(
+	t *testing.T, o *oracle.Oracle, stream string, ftime time.Time, nRpoints int) {
+	id := oracle.TimeEventID(stream, ftime)
+	err := o.AddEvent(oracle.Event{ID: id, NRpoints: nRpoints})
+	assert.NoError(t, err)
+}
+
+func contractorGetCommitmentsFromOracle(
+	t *testing.T, c *Contractor, o *oracle.Oracle, stream string) {
 	// fixing time of the contract
 	fixingTime := c.DLCBuilder.DLC().Conds.FixingTime
 
-	// oracle provides pubkey set for the given time
-	pubkeySet, err := o.PubkeySet(fixingTime)
+	// oracle provides pubkey set for the event at the given time
+	pubkeySet, err := o.PubkeySet(oracle.TimeEventID(stream, fixingTime))
+	assert.NoError(t, err)
+
+	// contractor trusts oracle's identity pubkey
+	identity, err := o.Pubkey()
 	assert.NoError(t, err)
+	c.DLCBuilder.SetOracleIdentity(identity)
 
 	// contractor sets and prepare commitents on each deal
-	c.DLCBuilder.SetOraclePubkeySet(&pubkeySet)
+	err = c.DLCBuilder.SetOraclePubkeySet(&pubkeySet)
+	assert.NoError(t, err)
 }
 
 // A contractor sends pubkey and fund txins to the counterparty
 func contractorOfferCounterparty(t *testing.T, c1, c2 *Contractor) {
 	// first party prepare pubkey and fund txins/txouts
 	c1.DLCBuilder.PreparePubkey()
-	err := c1.DLCBuilder.PrepareFundTxIns()
+	err := c1.DLCBuilder.PrepareFundTxIns(nil)
 	assert.NoError(t, err)
 
 	// send prepared data to second party
 	dlc1 := *c1.DLCBuilder.DLC()
 
 	// second party accepts it
-	c2.DLCBuilder.CopyReqsFromCounterparty(&dlc1)
+	err = c2.DLCBuilder.CopyReqsFromCounterparty(&dlc1)
+	assert.NoError(t, err)
 }
 
 // A contractor sends pubkey, fund txins and
@@ -49,7 +66,7 @@ func contractorOfferCounterparty(t *testing.T, c1, c2 *Contractor) {
 func contractorAcceptOffer(t *testing.T, c1, c2 *Contractor) {
 	// Second party prepares pubkey and fund txins/txouts
 	c1.DLCBuilder.PreparePubkey()
-	err := c1.DLCBuilder.PrepareFundTxIns()
+	err := c1.DLCBuilder.PrepareFundTxIns(nil)
 	assert.NoError(t, err)
 
 	// signs CE txs and refund tx
@@ -58,7 +75,8 @@ func contractorAcceptOffer(t *testing.T, c1, c2 *Contractor) {
 
 	// Sends pubkey and fund txins and sign to the counterparty
 	dlc1 := *c1.DLCBuilder.DLC()
-	c2.DLCBuilder.CopyReqsFromCounterparty(&dlc1)
+	err = c2.DLCBuilder.CopyReqsFromCounterparty(&dlc1)
+	assert.NoError(t, err)
 
 	// send signs
 	err = c2.DLCBuilder.AcceptCETxSigns(ceSigns)
@@ -141,11 +159,11 @@ func contractorShouldHaveBalanceAfterFunding(
 }
 
 func contractorFixDeal(
-	t *testing.T, c *Contractor, o *oracle.Oracle, idxs []int) {
+	t *testing.T, c *Contractor, o *oracle.Oracle, stream string, idxs []int) {
 	ftime := c.DLCBuilder.DLC().Conds.FixingTime
 
 	// receive signset
-	signSet, err := o.SignSet(ftime)
+	signSet, err := o.SignSet(oracle.TimeEventID(stream, ftime))
 	assert.NoError(t, err)
 
 	// fix deal with the signset
@@ -154,11 +172,11 @@ func contractorFixDeal(
 }
 
 func contractorCannotFixDeal(
-	t *testing.T, c *Contractor, o *oracle.Oracle, idxs []int) {
+	t *testing.T, c *Contractor, o *oracle.Oracle, stream string, idxs []int) {
 	ftime := c.DLCBuilder.DLC().Conds.FixingTime
 
 	// receive signset
-	signSet, err := o.SignSet(ftime)
+	signSet, err := o.SignSet(oracle.TimeEventID(stream, ftime))
 	assert.NoError(t, err)
 
 	// fail to fix deal with the signset